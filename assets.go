@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const assetHashLength = 8
+
+// AssetManifest maps asset file names to fingerprinted names containing hash of the file content
+// (`js/app.js` -> `js/app.3f2a1c9b.js`). Fingerprinted URLs change whenever the content changes, so they can be
+// cached by clients forever.
+type AssetManifest struct {
+	// ImmutableCacheControl is `Cache-Control` header value for requests to fingerprinted names.
+	ImmutableCacheControl string
+	// CacheControl is `Cache-Control` header value for requests to original (not fingerprinted) names.
+	CacheControl string
+
+	filesystem fs.FS
+	prefix     string
+	hashed     map[string]string // original name -> fingerprinted name
+	original   map[string]string // fingerprinted name -> original name
+}
+
+// NewAssetManifest hashes every file in the file system and creates manifest for assets served under URL path prefix.
+func NewAssetManifest(pathPrefix string, filesystem fs.FS) (*AssetManifest, error) {
+	m := &AssetManifest{
+		ImmutableCacheControl: "public, max-age=31536000, immutable",
+		CacheControl:          "public, max-age=60",
+		filesystem:            filesystem,
+		prefix:                strings.TrimSuffix(pathPrefix, "/"),
+		hashed:                map[string]string{},
+		original:              map[string]string{},
+	}
+	err := fs.WalkDir(filesystem, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := hashAsset(filesystem, name)
+		if err != nil {
+			return err
+		}
+		fingerprinted := fingerprintName(name, sum)
+		m.hashed[name] = fingerprinted
+		m.original[fingerprinted] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset manifest: %w", err)
+	}
+	return m, nil
+}
+
+func hashAsset(filesystem fs.FS, name string) (string, error) {
+	f, err := filesystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:assetHashLength], nil
+}
+
+// fingerprintName inserts hash before the file extension: `css/site.css` -> `css/site.<hash>.css`
+func fingerprintName(name, sum string) string {
+	dir, file := path.Split(name)
+	ext := path.Ext(file)
+	if ext == file { // dot files like `.htaccess`
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(file, ext) + "." + sum + ext
+}
+
+// Lookup returns fingerprinted name of the asset. Returns false when asset does not exist in manifest.
+func (m *AssetManifest) Lookup(name string) (string, bool) {
+	hashed, ok := m.hashed[strings.TrimPrefix(name, "/")]
+	return hashed, ok
+}
+
+// Path returns URL path of the asset with fingerprinted file name. For unknown assets URL path with the given name is
+// returned.
+func (m *AssetManifest) Path(name string) string {
+	if hashed, ok := m.Lookup(name); ok {
+		name = hashed
+	}
+	return m.prefix + "/" + strings.TrimPrefix(name, "/")
+}
+
+// FuncMap returns template functions for `html/template` and `text/template` packages. Function `asset` resolves
+// URL path of the asset.
+//
+//	t := template.New("").Funcs(manifest.FuncMap())
+//	// {{ asset "js/app.js" }} renders `/assets/js/app.3f2a1c9b.js`
+func (m *AssetManifest) FuncMap() map[string]any {
+	return map[string]any{"asset": m.Path}
+}
+
+// Handler returns handler function serving assets by fingerprinted and original names. Route for the handler must
+// end with `*` wildcard.
+func (m *AssetManifest) Handler() HandlerFunc {
+	static := StaticDirectoryHandler(m.filesystem, false)
+	return func(c Context) error {
+		p, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return fmt.Errorf("failed to unescape path variable: %w", err)
+		}
+		name := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(p, "/")))
+
+		header := c.Response().Header()
+		if original, ok := m.original[name]; ok {
+			header.Set(HeaderCacheControl, m.ImmutableCacheControl)
+			return fsFile(c, original, m.filesystem)
+		}
+
+		header.Set(HeaderCacheControl, m.CacheControl)
+		if err := static(c); err != nil {
+			header.Del(HeaderCacheControl)
+			return err
+		}
+		return nil
+	}
+}
+
+// StaticAssets registers a new route with path prefix to serve fingerprinted static files from the provided file
+// system. All files are hashed when route is registered. Use `Echo#Asset` or `AssetManifest#FuncMap` to create URLs
+// to assets. Requests to fingerprinted names are served with `Cache-Control: public, max-age=31536000, immutable` and
+// requests to original names with short caching.
+//
+// Panics when file system can not be read.
+//
+//	manifest := e.StaticAssets("/assets", echo.MustSubFS(assets, "dist"))
+//	e.Renderer = &echo.TemplateRenderer{
+//		Template: template.Must(template.New("").Funcs(manifest.FuncMap()).ParseGlob("templates/*.html")),
+//	}
+func (e *Echo) StaticAssets(pathPrefix string, filesystem fs.FS) *AssetManifest {
+	manifest := mustAssetManifest(pathPrefix, filesystem)
+	e.assets = append(e.assets, manifest)
+	e.Add(http.MethodGet, pathPrefix+"*", manifest.Handler())
+	return manifest
+}
+
+// StaticAssets implements `Echo#StaticAssets()` for sub-routes within the Group.
+func (g *Group) StaticAssets(pathPrefix string, filesystem fs.FS) *AssetManifest {
+	manifest := mustAssetManifest(g.prefix+pathPrefix, filesystem)
+	g.echo.assets = append(g.echo.assets, manifest)
+	g.Add(http.MethodGet, pathPrefix+"*", manifest.Handler())
+	return manifest
+}
+
+func mustAssetManifest(pathPrefix string, filesystem fs.FS) *AssetManifest {
+	manifest, err := NewAssetManifest(pathPrefix, filesystem)
+	if err != nil {
+		panic(err)
+	}
+	return manifest
+}
+
+// Asset returns URL path of the asset with fingerprinted file name from manifests registered with
+// `Echo#StaticAssets`. Name is returned as is when asset is not found.
+func (e *Echo) Asset(name string) string {
+	for _, m := range e.assets {
+		if _, ok := m.Lookup(name); ok {
+			return m.Path(name)
+		}
+	}
+	return name
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAssetsFS() fstest.MapFS {
+	return fstest.MapFS{
+		"js/app.js":    {Data: []byte("console.log('app')")},
+		"css/site.css": {Data: []byte("body{}")},
+		".htaccess":    {Data: []byte("deny")},
+	}
+}
+
+func TestNewAssetManifest(t *testing.T) {
+	m, err := NewAssetManifest("/assets/", testAssetsFS())
+	assert.NoError(t, err)
+
+	hashed, ok := m.Lookup("js/app.js")
+	assert.True(t, ok)
+	assert.Regexp(t, `^js/app\.[0-9a-f]{8}\.js$`, hashed)
+	assert.Equal(t, "/assets/"+hashed, m.Path("/js/app.js"))
+
+	hashed, ok = m.Lookup(".htaccess")
+	assert.True(t, ok)
+	assert.Regexp(t, `^\.htaccess\.[0-9a-f]{8}$`, hashed)
+
+	_, ok = m.Lookup("missing.js")
+	assert.False(t, ok)
+	assert.Equal(t, "/assets/missing.js", m.Path("missing.js"))
+}
+
+func TestNewAssetManifest_hashChangesWithContent(t *testing.T) {
+	fsys := testAssetsFS()
+	m1, err := NewAssetManifest("/assets", fsys)
+	assert.NoError(t, err)
+
+	fsys["js/app.js"] = &fstest.MapFile{Data: []byte("console.log('changed')")}
+	m2, err := NewAssetManifest("/assets", fsys)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, m1.Path("js/app.js"), m2.Path("js/app.js"))
+	assert.Equal(t, m1.Path("css/site.css"), m2.Path("css/site.css"))
+}
+
+func TestEcho_StaticAssets(t *testing.T) {
+	e := New()
+	m := e.StaticAssets("/assets", testAssetsFS())
+	hashedURL := e.Asset("js/app.js")
+	assert.Equal(t, m.Path("js/app.js"), hashedURL)
+	assert.Equal(t, "unknown.js", e.Asset("unknown.js"))
+
+	var testCases = []struct {
+		name               string
+		whenURL            string
+		expectStatus       int
+		expectCacheControl string
+		expectBody         string
+	}{
+		{
+			name:               "ok, fingerprinted name is cached forever",
+			whenURL:            hashedURL,
+			expectStatus:       http.StatusOK,
+			expectCacheControl: "public, max-age=31536000, immutable",
+			expectBody:         "console.log('app')",
+		},
+		{
+			name:               "ok, original name has short caching",
+			whenURL:            "/assets/js/app.js",
+			expectStatus:       http.StatusOK,
+			expectCacheControl: "public, max-age=60",
+			expectBody:         "console.log('app')",
+		},
+		{
+			name:               "nok, unknown file",
+			whenURL:            "/assets/js/app.00000000.js",
+			expectStatus:       http.StatusNotFound,
+			expectCacheControl: "",
+			expectBody:         "{\"message\":\"Not Found\"}\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.whenURL, nil)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tc.expectCacheControl, rec.Header().Get(HeaderCacheControl))
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+		})
+	}
+}
+
+func TestGroup_StaticAssets(t *testing.T) {
+	e := New()
+	g := e.Group("/static")
+	g.StaticAssets("/assets", testAssetsFS())
+
+	hashedURL := e.Asset("css/site.css")
+	assert.Regexp(t, `^/static/assets/css/site\.[0-9a-f]{8}\.css$`, hashedURL)
+
+	req := httptest.NewRequest(http.MethodGet, hashedURL, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "body{}", rec.Body.String())
+}
+
+func TestAssetManifest_FuncMap(t *testing.T) {
+	m, err := NewAssetManifest("/assets", testAssetsFS())
+	assert.NoError(t, err)
+
+	tmpl := template.Must(template.New("page").Funcs(m.FuncMap()).Parse(`<script src="{{ asset "js/app.js" }}"></script>`))
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, nil))
+	assert.Equal(t, `<script src="`+m.Path("js/app.js")+`"></script>`, buf.String())
+}
+
+func TestEcho_StaticAssetsPanic(t *testing.T) {
+	e := New()
+	assert.Panics(t, func() {
+		e.StaticAssets("/assets", failingFS{})
+	})
+}
+
+type failingFS struct{}
+
+func (failingFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Binder is the interface that wraps the Bind method.
@@ -21,7 +22,69 @@ type Binder interface {
 }
 
 // DefaultBinder is the default implementation of the Binder interface.
-type DefaultBinder struct{}
+//
+// Query and form values with keys in bracket or dot notation are bound to nested structs, slices and maps:
+// `filter[status]=open&items[0][qty]=2&items[0].id=3&tags[]=a&tags[]=b`. Errors for nested fields are returned as
+// `BindingError` with field path like `items[0].qty`.
+//
+// Fields bound from query, path, header and form can have `default:"..."` tag, used when value is absent or empty, and
+// `required:"true"` tag, which results `BindingError` when value is absent or empty. Default is applied by each binding
+// step the field has source tag for (`query:"page" default:"1"`) and only when field has no value yet. Single source
+// binding methods check `required` tag right away, `Bind` checks it once after all sources have been bound so that
+// field with multiple source tags (`param:"id" query:"id" required:"true"`) can be filled by any of them.
+//
+// Cookies (`cookie` tag) are bound by `BindCookies`, `Bind` binds them only when IncludeCookies is enabled. Cookies are
+// sent with every request to the domain, including cookies set by other applications on the same domain and cookies
+// planted by a sibling subdomain, so values from them should not fill request structs unless the caller asks for it.
+type DefaultBinder struct {
+	// MaxNestedDepth is maximum number of segments in nested query/form key (`items[0][qty]` has 3 segments).
+	// Optional. Default value 32.
+	MaxNestedDepth int
+
+	// MaxSliceIndex is maximum slice index in nested query/form key (`items[1000]`). Limits size of slices allocated
+	// by binding.
+	// Optional. Default value 1000.
+	MaxSliceIndex int
+
+	// MaxSliceElements is maximum total number of slice elements allocated by binding of nested query/form keys during
+	// single bind call. Unlike MaxSliceIndex it limits also many slices (`a[0][999]&a[1][999]`) allocated from small
+	// request.
+	// Optional. Default value 10000.
+	MaxSliceElements int
+
+	// AggregateErrors enables collecting errors of all fields that failed to bind instead of returning the first
+	// error. Collected errors are returned as `BindingErrors`.
+	// Optional. Default value false.
+	AggregateErrors bool
+
+	// SplitHeaderValues enables splitting comma separated header values (`X-Ids: 1, 2`) into separate elements when
+	// headers are bound to slice fields. Commas inside quoted strings do not separate values. Leave disabled for
+	// headers which values contain commas, i.e. HTTP dates.
+	// Optional. Default value false.
+	SplitHeaderValues bool
+
+	// IncludeCookies enables binding request cookies (`cookie` tag) by `Bind` for struct destinations. Cookies are
+	// bound after path params and before query params. Disabled by default as cookies are not request specific input
+	// (see above). Cookies can always be bound with `BindCookies`.
+	// Optional. Default value false.
+	IncludeCookies bool
+
+	// fieldErrors collects field errors during single Bind call when AggregateErrors is enabled
+	fieldErrors *[]FieldError
+	// missingRequired collects required fields without value during single Bind call, these are checked again after
+	// all sources have been bound
+	missingRequired *[]missingRequiredField
+	// sliceElements counts slice elements allocated during single bind call, shared by all sources of Bind
+	sliceElements *int
+}
+
+type missingRequiredField struct {
+	field  reflect.Value
+	source string
+	path   string
+	values []string
+	err    error
+}
 
 // BindUnmarshaler is the interface used to wrap the UnmarshalParam method.
 // Types that don't implement this, but do implement encoding.TextUnmarshaler
@@ -46,18 +109,22 @@ func (b *DefaultBinder) BindPathParams(c Context, i interface{}) error {
 	for i, name := range names {
 		params[name] = []string{values[i]}
 	}
-	if err := b.bindData(i, params, "param", nil); err != nil {
-		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
-	}
-	return nil
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.bindData(i, params, "param", nil); err != nil {
+			return newBindDataError(err)
+		}
+		return nil
+	})
 }
 
 // BindQueryParams binds query params to bindable object
 func (b *DefaultBinder) BindQueryParams(c Context, i interface{}) error {
-	if err := b.bindData(i, c.QueryParams(), "query", nil); err != nil {
-		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
-	}
-	return nil
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.bindData(i, c.QueryParams(), "query", nil); err != nil {
+			return newBindDataError(err)
+		}
+		return nil
+	})
 }
 
 // BindBody binds request body contents to bindable object
@@ -76,7 +143,7 @@ func (b *DefaultBinder) BindBody(c Context, i interface{}) (err error) {
 	mediatype := strings.TrimSpace(base)
 
 	switch mediatype {
-	case MIMEApplicationJSON:
+	case MIMEApplicationJSON, MIMEApplicationJSONPatch, MIMEApplicationMergePatch:
 		if err = c.Echo().JSONSerializer.Deserialize(c, i); err != nil {
 			switch err.(type) {
 			case *HTTPError:
@@ -97,61 +164,151 @@ func (b *DefaultBinder) BindBody(c Context, i interface{}) (err error) {
 	case MIMEApplicationForm:
 		params, err := c.FormParams()
 		if err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
-		}
-		if err = b.bindData(i, params, "form", nil); err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+			return newFormParseError(err)
 		}
+		return b.aggregate(func(b *DefaultBinder) error {
+			if err := b.bindData(i, params, "form", nil); err != nil {
+				return newBindDataError(err)
+			}
+			return nil
+		})
 	case MIMEMultipartForm:
 		params, err := c.MultipartForm()
 		if err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
-		}
-		if err = b.bindData(i, params.Value, "form", params.File); err != nil {
-			return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+			return newFormParseError(err)
 		}
+		return b.aggregate(func(b *DefaultBinder) error {
+			if err := b.bindData(i, params.Value, "form", params.File); err != nil {
+				return newBindDataError(err)
+			}
+			return nil
+		})
 	default:
 		return ErrUnsupportedMediaType
 	}
 	return nil
 }
 
+// BindMultipartStream binds non-file fields read so far from multipart stream to bindable object.
+// Fields sent after file parts are available only after the stream has been iterated past those file parts.
+func (b *DefaultBinder) BindMultipartStream(s *MultipartStream, i interface{}) error {
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.bindData(i, s.Values(), "form", nil); err != nil {
+			return newBindDataError(err)
+		}
+		return nil
+	})
+}
+
 // BindHeaders binds HTTP headers to a bindable object
 func (b *DefaultBinder) BindHeaders(c Context, i interface{}) error {
-	if err := b.bindData(i, c.Request().Header, "header", nil); err != nil {
-		return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.bindData(i, c.Request().Header, "header", nil); err != nil {
+			return newBindDataError(err)
+		}
+		return nil
+	})
+}
+
+// BindCookies binds request cookies to bindable object. Fields are bound using `cookie` tag.
+func (b *DefaultBinder) BindCookies(c Context, i interface{}) error {
+	cookies := c.Cookies()
+	data := make(map[string][]string, len(cookies))
+	for _, cookie := range cookies {
+		data[cookie.Name] = append(data[cookie.Name], cookie.Value)
 	}
-	return nil
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.bindData(i, data, "cookie", nil); err != nil {
+			return newBindDataError(err)
+		}
+		return nil
+	})
 }
 
 // Bind implements the `Binder#Bind` function.
 // Binding is done in following order: 1) path params; 2) query params; 3) request body. Each step COULD override previous
-// step binded values. For single source binding use their own methods BindBody, BindQueryParams, BindPathParams.
+// step binded values. For single source binding use their own methods BindBody, BindQueryParams, BindPathParams,
+// BindHeaders, BindCookies.
+// Fields with `cookie` tag are bound by Bind only when IncludeCookies is enabled, then cookies are bound after path
+// params. See DefaultBinder for reasons.
+// When AggregateErrors is enabled field errors from all steps are returned together.
 func (b *DefaultBinder) Bind(i interface{}, c Context) (err error) {
-	if err := b.BindPathParams(c, i); err != nil {
-		return err
-	}
-	// Only bind query parameters for GET/DELETE/HEAD to avoid unexpected behavior with destination struct binding from body.
-	// For example a request URL `&id=1&lang=en` with body `{"id":100,"lang":"de"}` would lead to precedence issues.
-	// The HTTP method check restores pre-v4.1.11 behavior to avoid these problems (see issue #1670)
-	method := c.Request().Method
-	if method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead {
-		if err = b.BindQueryParams(c, i); err != nil {
+	return b.aggregate(func(b *DefaultBinder) error {
+		rb := *b
+		rb.missingRequired = &[]missingRequiredField{}
+		rb.sliceElements = new(int)
+		if err := rb.bind(i, c); err != nil {
+			return err
+		}
+		return b.checkMissingRequired(*rb.missingRequired)
+	})
+}
+
+func (b *DefaultBinder) bind(i interface{}, c Context) error {
+	return b.aggregate(func(b *DefaultBinder) error {
+		if err := b.BindPathParams(c, i); err != nil {
 			return err
 		}
+		// cookies are not bound to maps as that would copy all (session etc.) cookies to the destination
+		if typ := reflect.TypeOf(i); b.IncludeCookies && typ != nil && typ.Kind() == reflect.Pointer && typ.Elem().Kind() == reflect.Struct {
+			if err := b.BindCookies(c, i); err != nil {
+				return err
+			}
+		}
+		// Only bind query parameters for GET/DELETE/HEAD to avoid unexpected behavior with destination struct binding from body.
+		// For example a request URL `&id=1&lang=en` with body `{"id":100,"lang":"de"}` would lead to precedence issues.
+		// The HTTP method check restores pre-v4.1.11 behavior to avoid these problems (see issue #1670)
+		method := c.Request().Method
+		if method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead {
+			if err := b.BindQueryParams(c, i); err != nil {
+				return err
+			}
+		}
+		return b.BindBody(c, i)
+	})
+}
+
+// newBindDataError converts error from bindData to HTTP error. BindingError already is HTTP error with field path.
+func newBindDataError(err error) error {
+	var be *BindingError
+	if errors.As(err, &be) {
+		return be
+	}
+	return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+}
+
+// newFormParseError returns error for failed form parsing. 413 errors returned by request body readers (i.e. BodyLimit
+// and Decompress middlewares) are returned as they are so that the status is not changed to 400.
+func newFormParseError(err error) error {
+	var he *HTTPError
+	if errors.As(err, &he) && he.Code == http.StatusRequestEntityTooLarge {
+		return he
 	}
-	return b.BindBody(c, i)
+	return NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
 }
 
 // bindData will bind data ONLY fields in destination struct that have EXPLICIT tag
 func (b *DefaultBinder) bindData(destination interface{}, data map[string][]string, tag string, dataFiles map[string][]*multipart.FileHeader) error {
-	if destination == nil || (len(data) == 0 && len(dataFiles) == 0) {
+	if destination == nil {
 		return nil
 	}
+	if b.sliceElements == nil {
+		sb := *b
+		sb.sliceElements = new(int)
+		b = &sb
+	}
 	hasFiles := len(dataFiles) > 0
 	typ := reflect.TypeOf(destination).Elem()
 	val := reflect.ValueOf(destination).Elem()
 
+	if len(data) == 0 && !hasFiles {
+		// struct fields could still have `default` or `required` tags
+		if typ.Kind() != reflect.Struct || !hasDefaultOrRequiredTags(typ, tag) {
+			return nil
+		}
+		data = map[string][]string{}
+	}
+
 	// Support binding to limited Map destinations:
 	// - map[string][]string,
 	// - map[string]string <-- (binds first value from data slice)
@@ -164,7 +321,7 @@ func (b *DefaultBinder) bindData(destination interface{}, data map[string][]stri
 
 	// !struct
 	if typ.Kind() != reflect.Struct {
-		if tag == "param" || tag == "query" || tag == "header" {
+		if tag == "param" || tag == "query" || tag == "header" || tag == "cookie" {
 			// incompatible type, data is probably to be found in the body
 			return nil
 		}
@@ -255,6 +412,28 @@ func handleStructBinding(b *DefaultBinder, typ reflect.Type, val reflect.Value,
 			}
 		}
 
+		if !exists && (tag == "query" || tag == "form") {
+			if bound, err := b.bindNestedField(structField, inputFieldName, data, tag); err != nil {
+				return err
+			} else if bound {
+				continue
+			}
+		}
+
+		if !exists || isEmptyInput(inputValue) {
+			defaultValue, hasDefault, err := bindDefaultValue(typeField, structField, inputFieldName, inputValue)
+			if err != nil {
+				if err := b.requiredError(structField, tag, inputFieldName, inputValue, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if hasDefault {
+				inputValue = defaultValue
+				exists = true
+			}
+		}
+
 		if !exists {
 			continue
 		}
@@ -264,14 +443,14 @@ func handleStructBinding(b *DefaultBinder, typ reflect.Type, val reflect.Value,
 
 		// try unmarshalling first, in case we're dealing with an alias to an array type
 		if ok, err := unmarshalInputsToField(typeField.Type.Kind(), inputValue, structField); ok {
-			if err != nil {
+			if err := b.fieldError(tag, inputFieldName, inputValue, err); err != nil {
 				return err
 			}
 			continue
 		}
 
 		if ok, err := unmarshalInputToField(typeField.Type.Kind(), inputValue[0], structField); ok {
-			if err != nil {
+			if err := b.fieldError(tag, inputFieldName, inputValue, err); err != nil {
 				return err
 			}
 			continue
@@ -285,25 +464,184 @@ func handleStructBinding(b *DefaultBinder, typ reflect.Type, val reflect.Value,
 		}
 
 		if structFieldKind == reflect.Slice {
+			if tag == "header" && b.SplitHeaderValues {
+				inputValue = splitHeaderValues(inputValue)
+			}
 			sliceOf := structField.Type().Elem().Kind()
 			numElems := len(inputValue)
 			slice := reflect.MakeSlice(structField.Type(), numElems, numElems)
-			for j := 0; j < numElems; j++ {
-				if err := setWithProperType(sliceOf, inputValue[j], slice.Index(j)); err != nil {
+			var err error
+			for j := 0; j < numElems && err == nil; j++ {
+				err = setWithProperType(sliceOf, inputValue[j], slice.Index(j))
+			}
+			if err != nil {
+				if err := b.fieldError(tag, inputFieldName, inputValue, err); err != nil {
 					return err
 				}
+				continue
 			}
 			structField.Set(slice)
 			continue
 		}
 
 		if err := setWithProperType(structFieldKind, inputValue[0], structField); err != nil {
+			if err := b.fieldError(tag, inputFieldName, inputValue, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldError records error of the field and returns nil when binder aggregates errors. Otherwise, err is returned as is.
+func (b *DefaultBinder) fieldError(source string, field string, values []string, err error) error {
+	if b.fieldErrors == nil || err == nil {
+		return err
+	}
+	fe := FieldError{Source: source, Field: field, Values: values, Reason: err.Error()}
+	var be *BindingError
+	if errors.As(err, &be) {
+		fe.Field = be.Field
+		fe.Values = be.Values
+		fe.Reason = fmt.Sprint(be.Message)
+		if be.Internal != nil {
+			fe.Reason += ": " + be.Internal.Error()
+		}
+	}
+	*b.fieldErrors = append(*b.fieldErrors, fe)
+	return nil
+}
+
+// requiredError records required field without value to be checked after all sources have been bound when called
+// during `Bind`. Otherwise, error is handled as field error.
+func (b *DefaultBinder) requiredError(field reflect.Value, source string, path string, values []string, err error) error {
+	if b.missingRequired == nil {
+		return b.fieldError(source, path, values, err)
+	}
+	*b.missingRequired = append(*b.missingRequired, missingRequiredField{
+		field:  field,
+		source: source,
+		path:   path,
+		values: values,
+		err:    err,
+	})
+	return nil
+}
+
+// checkMissingRequired returns errors for required fields that did not get value from any source.
+func (b *DefaultBinder) checkMissingRequired(missing []missingRequiredField) error {
+	checked := make(map[uintptr]struct{}, len(missing))
+	for _, m := range missing {
+		addr := m.field.Addr().Pointer()
+		if _, ok := checked[addr]; ok {
+			continue
+		}
+		checked[addr] = struct{}{}
+		if !m.field.IsZero() {
+			continue
+		}
+		if err := b.fieldError(m.source, m.path, m.values, m.err); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// aggregate runs bind function with binder collecting field errors when AggregateErrors is enabled. Collected errors
+// are returned as BindingErrors. Nested calls (`Bind` calling `BindQueryParams`) share the same collection.
+func (b *DefaultBinder) aggregate(bind func(b *DefaultBinder) error) error {
+	if !b.AggregateErrors || b.fieldErrors != nil {
+		return bind(b)
+	}
+	ab := *b
+	ab.fieldErrors = &[]FieldError{}
+	if err := bind(&ab); err != nil {
+		return err
+	}
+	if len(*ab.fieldErrors) > 0 {
+		return NewBindingErrors(*ab.fieldErrors)
+	}
+	return nil
+}
+
+// bindDefaultValue returns value of `default` tag for field that has no input value. Returns error when field has
+// `required:"true"` tag and neither input value nor default value exists. Fields that already have value (set by
+// previous binding step) are not defaulted.
+//
+// For slice fields default value is comma separated list of values: `default:"a,b"`.
+func bindDefaultValue(typeField reflect.StructField, field reflect.Value, name string, inputValue []string) ([]string, bool, error) {
+	if !field.IsZero() {
+		return nil, false, nil
+	}
+	defaultValue, ok := typeField.Tag.Lookup("default")
+	if !ok {
+		if typeField.Tag.Get("required") == "true" {
+			return nil, false, NewBindingError(name, inputValue, "required field value is empty", nil)
+		}
+		return nil, false, nil
+	}
+	typ := typeField.Type
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice && !isUnmarshalerType(typ) {
+		return strings.Split(defaultValue, ","), true, nil
+	}
+	return []string{defaultValue}, true, nil
+}
+
+type bindTagsKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// bindTagsCache caches results of hasDefaultOrRequiredTags by struct type and source tag
+var bindTagsCache sync.Map
+
+// hasDefaultOrRequiredTags checks if struct has fields (including fields of nested structs) with given source tag and
+// `default` or `required` tag. Such structs have to be bound even when source has no data.
+func hasDefaultOrRequiredTags(typ reflect.Type, tag string) bool {
+	key := bindTagsKey{typ: typ, tag: tag}
+	if v, ok := bindTagsCache.Load(key); ok {
+		return v.(bool)
+	}
+	result := scanDefaultOrRequiredTags(typ, tag, map[reflect.Type]bool{})
+	bindTagsCache.Store(key, result)
+	return result
+}
+
+func scanDefaultOrRequiredTags(typ reflect.Type, tag string, visited map[reflect.Type]bool) bool {
+	if visited[typ] {
+		return false
+	}
+	visited[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		if _, ok := typeField.Tag.Lookup(tag); ok {
+			if _, ok := typeField.Tag.Lookup("default"); ok || typeField.Tag.Get("required") == "true" {
+				return true
+			}
+		}
+		ft := typeField.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && scanDefaultOrRequiredTags(ft, tag, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyInput(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
 func setWithProperType(valueKind reflect.Kind, val string, structField reflect.Value) error {
 	// But also call it here, in case we're dealing with an array of BindUnmarshalers
 	if ok, err := unmarshalInputToField(valueKind, val, structField); ok {
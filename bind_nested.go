@@ -0,0 +1,387 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBindMaxDepth      = 32
+	defaultBindMaxSliceIndex = 1000
+	defaultBindMaxSliceElems = 10000
+)
+
+var (
+	bindUnmarshalerType         = reflect.TypeOf((*BindUnmarshaler)(nil)).Elem()
+	bindMultipleUnmarshalerType = reflect.TypeOf((*bindMultipleUnmarshaler)(nil)).Elem()
+	textUnmarshalerType         = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// bindNode is a tree of values created from nested keys. i.e. `items[0][qty]=2` creates path `items` -> `0` -> `qty`.
+type bindNode struct {
+	values   []string
+	children map[string]*bindNode
+}
+
+func (n *bindNode) insert(segments []string, values []string) {
+	if len(segments) == 0 {
+		n.values = append(n.values, values...)
+		return
+	}
+	if n.children == nil {
+		n.children = map[string]*bindNode{}
+	}
+	child, ok := n.children[segments[0]]
+	if !ok {
+		child = &bindNode{}
+		n.children[segments[0]] = child
+	}
+	child.insert(segments[1:], values)
+}
+
+func (n *bindNode) child(name string) *bindNode {
+	if child, ok := n.children[name]; ok {
+		return child
+	}
+	// same as flat binding, keys are matched case-insensitively when exact match does not exist
+	for k, child := range n.children {
+		if strings.EqualFold(k, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *bindNode) sortedKeys() []string {
+	keys := make([]string, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseNestedKey splits key using bracket and dot notation into path segments. i.e. `items[0].qty` and
+// `items[0][qty]` both result `["items", "0", "qty"]`. Returns false for keys that are not nested or are malformed.
+func parseNestedKey(key string) ([]string, bool) {
+	i := strings.IndexAny(key, "[.")
+	if i <= 0 {
+		return nil, false
+	}
+	segments := []string{key[:i]}
+	rest := key[i:]
+	for rest != "" {
+		switch rest[0] {
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false
+			}
+			segments = append(segments, rest[1:end])
+			rest = rest[end+1:]
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, "[.")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, false
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		default:
+			return nil, false // `a[b]c`
+		}
+	}
+	return segments, true
+}
+
+func (b *DefaultBinder) maxDepth() int {
+	if b.MaxNestedDepth > 0 {
+		return b.MaxNestedDepth
+	}
+	return defaultBindMaxDepth
+}
+
+func (b *DefaultBinder) maxSliceIndex() int {
+	if b.MaxSliceIndex > 0 {
+		return b.MaxSliceIndex
+	}
+	return defaultBindMaxSliceIndex
+}
+
+// allocSliceElements reserves n slice elements from the element budget of the bind call. Returns false when the budget
+// is exceeded.
+func (b *DefaultBinder) allocSliceElements(n int) bool {
+	limit := defaultBindMaxSliceElems
+	if b.MaxSliceElements > 0 {
+		limit = b.MaxSliceElements
+	}
+	if *b.sliceElements+n > limit {
+		return false
+	}
+	*b.sliceElements += n
+	return true
+}
+
+// isNestedBindable checks if type can be bound from nested keys.
+func isNestedBindable(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		return !isUnmarshalerType(typ)
+	case reflect.Map:
+		return typ.Key().Kind() == reflect.String
+	case reflect.Slice:
+		return !isUnmarshalerType(typ)
+	}
+	return false
+}
+
+func isUnmarshalerType(typ reflect.Type) bool {
+	ptr := reflect.PointerTo(typ)
+	return ptr.Implements(bindUnmarshalerType) ||
+		ptr.Implements(textUnmarshalerType) ||
+		ptr.Implements(bindMultipleUnmarshalerType)
+}
+
+// bindNestedField binds keys using bracket or dot notation (`items[0][qty]`, `filter.status`) starting with given name
+// to the field. Returns false when there are no such keys.
+func (b *DefaultBinder) bindNestedField(field reflect.Value, name string, data map[string][]string, tag string) (bool, error) {
+	if !isNestedBindable(field.Type()) {
+		return false, nil
+	}
+	root := &bindNode{}
+	found := false
+	for key, values := range data {
+		segments, ok := parseNestedKey(key)
+		if !ok || !strings.EqualFold(segments[0], name) {
+			continue
+		}
+		if len(segments) > b.maxDepth() {
+			if err := b.fieldError(tag, key, values, NewBindingError(key, values, "nested key depth limit exceeded", nil)); err != nil {
+				return true, err
+			}
+			continue
+		}
+		root.insert(segments[1:], values)
+		found = true
+	}
+	if !found {
+		if field.Kind() == reflect.Struct {
+			// fields of absent struct could still have `default` or `required` tags
+			return false, b.bindNodeStruct(field, root, name, tag)
+		}
+		return false, nil
+	}
+	return true, b.bindNode(field, root, name, tag)
+}
+
+func (b *DefaultBinder) bindNode(field reflect.Value, node *bindNode, path string, tag string) error {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	if len(node.children) == 0 {
+		return b.fieldError(tag, path, node.values, bindNodeValues(field, node.values, path))
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		if !isUnmarshalerType(field.Type()) {
+			return b.bindNodeStruct(field, node, path, tag)
+		}
+	case reflect.Slice:
+		if !isUnmarshalerType(field.Type()) {
+			return b.bindNodeSlice(field, node, path, tag)
+		}
+	case reflect.Map:
+		if field.Type().Key().Kind() == reflect.String {
+			return b.bindNodeMap(field, node, path, tag)
+		}
+	}
+	key := node.sortedKeys()[0]
+	err := NewBindingError(path+"["+key+"]", node.children[key].values, "nested key is not supported by field type", nil)
+	return b.fieldError(tag, path, nil, err)
+}
+
+func (b *DefaultBinder) bindNodeStruct(field reflect.Value, node *bindNode, path string, tag string) error {
+	typ := field.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		structField := field.Field(i)
+		if typeField.Anonymous && structField.Kind() == reflect.Pointer {
+			if structField.IsNil() {
+				continue
+			}
+			structField = structField.Elem()
+		}
+		if !structField.CanSet() {
+			continue
+		}
+
+		name := typeField.Tag.Get(tag)
+		if name == "" {
+			if structField.Kind() == reflect.Struct && !isUnmarshalerType(structField.Type()) {
+				if err := b.bindNodeStruct(structField, node, path, tag); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		fieldPath := path + "." + name
+		child := node.child(name)
+		if child == nil || (len(child.children) == 0 && isEmptyInput(child.values)) {
+			var values []string
+			if child != nil {
+				values = child.values
+			}
+			defaultValue, hasDefault, err := bindDefaultValue(typeField, structField, fieldPath, values)
+			if err != nil {
+				if err := b.requiredError(structField, tag, fieldPath, values, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if hasDefault {
+				child = &bindNode{values: defaultValue}
+			} else if child == nil {
+				continue
+			}
+		}
+		if err := b.bindNode(structField, child, fieldPath, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *DefaultBinder) bindNodeSlice(field reflect.Value, node *bindNode, path string, tag string) error {
+	indexes := make([]int, 0, len(node.children))
+	for _, key := range node.sortedKeys() {
+		if key == "" {
+			continue // `tags[]=a&tags[]=b` values are appended below
+		}
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			err = NewBindingError(path+"["+key+"]", node.children[key].values, "invalid slice index", err)
+		} else if index > b.maxSliceIndex() {
+			err = NewBindingError(path+"["+key+"]", node.children[key].values, "slice index limit exceeded", nil)
+		}
+		if err != nil {
+			if err := b.fieldError(tag, path, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	if len(indexes) > 0 {
+		if length := indexes[len(indexes)-1] + 1; length > field.Len() {
+			if !b.allocSliceElements(length - field.Len()) {
+				key := strconv.Itoa(indexes[len(indexes)-1])
+				err := NewBindingError(path+"["+key+"]", node.children[key].values, "slice element limit exceeded", nil)
+				return b.fieldError(tag, path, nil, err)
+			}
+			slice := reflect.MakeSlice(field.Type(), length, length)
+			reflect.Copy(slice, field)
+			field.Set(slice)
+		}
+		for _, index := range indexes {
+			elemPath := path + "[" + strconv.Itoa(index) + "]"
+			if err := b.bindNode(field.Index(index), node.children[strconv.Itoa(index)], elemPath, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	if appended, ok := node.children[""]; ok {
+		for _, value := range appended.values {
+			elemPath := path + "[" + strconv.Itoa(field.Len()) + "]"
+			if field.Len() > b.maxSliceIndex() {
+				return b.fieldError(tag, elemPath, nil, NewBindingError(elemPath, []string{value}, "slice index limit exceeded", nil))
+			}
+			if !b.allocSliceElements(1) {
+				return b.fieldError(tag, elemPath, nil, NewBindingError(elemPath, []string{value}, "slice element limit exceeded", nil))
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := bindNodeValues(elem, []string{value}, elemPath); err != nil {
+				if err := b.fieldError(tag, elemPath, nil, err); err != nil {
+					return err
+				}
+				continue
+			}
+			field.Set(reflect.Append(field, elem))
+		}
+	}
+	return nil
+}
+
+func (b *DefaultBinder) bindNodeMap(field reflect.Value, node *bindNode, path string, tag string) error {
+	typ := field.Type()
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(typ))
+	}
+	for _, key := range node.sortedKeys() {
+		mapKey := reflect.ValueOf(key).Convert(typ.Key())
+		elem := reflect.New(typ.Elem()).Elem()
+		if existing := field.MapIndex(mapKey); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := b.bindNode(elem, node.children[key], path+"["+key+"]", tag); err != nil {
+			return err
+		}
+		field.SetMapIndex(mapKey, elem)
+	}
+	return nil
+}
+
+// bindNodeValues binds values of the leaf node to the field.
+func bindNodeValues(field reflect.Value, values []string, path string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	kind := field.Kind()
+	if ok, err := unmarshalInputsToField(kind, values, field); ok {
+		return wrapNodeError(err, field, values, path)
+	}
+	if ok, err := unmarshalInputToField(kind, values[0], field); ok {
+		return wrapNodeError(err, field, values, path)
+	}
+	if kind == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setWithProperType(field.Type().Elem().Kind(), v, slice.Index(i)); err != nil {
+				return wrapNodeError(err, field, values, path)
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	if kind == reflect.Interface && field.NumMethod() == 0 {
+		field.Set(reflect.ValueOf(values[0]))
+		return nil
+	}
+	return wrapNodeError(setWithProperType(kind, values[0], field), field, values, path)
+}
+
+func wrapNodeError(err error, field reflect.Value, values []string, path string) error {
+	if err == nil {
+		return nil
+	}
+	return NewBindingError(path, values, fmt.Sprintf("failed to bind field value to %v", field.Type()), err)
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nestedItem struct {
+	ID  int `query:"id" form:"id"`
+	Qty int `query:"qty" form:"qty"`
+}
+
+type nestedOrder struct {
+	Filter struct {
+		Status string `query:"status" form:"status"`
+		Owner  *struct {
+			Name string `query:"name" form:"name"`
+		} `query:"owner" form:"owner"`
+	} `query:"filter" form:"filter"`
+	Items  []nestedItem           `query:"items" form:"items"`
+	Lookup map[string]nestedItem  `query:"lookup" form:"lookup"`
+	Labels map[string]string      `query:"labels" form:"labels"`
+	Tags   []string               `query:"tags" form:"tags"`
+	Refs   []*nestedItem          `query:"refs" form:"refs"`
+	Extra  map[string]interface{} `query:"extra" form:"extra"`
+	Page   int                    `query:"page" form:"page"`
+}
+
+func TestParseNestedKey(t *testing.T) {
+	var testCases = []struct {
+		whenKey        string
+		expectSegments []string
+		expectOk       bool
+	}{
+		{whenKey: "items[0][qty]", expectSegments: []string{"items", "0", "qty"}, expectOk: true},
+		{whenKey: "items[0].qty", expectSegments: []string{"items", "0", "qty"}, expectOk: true},
+		{whenKey: "filter.owner.name", expectSegments: []string{"filter", "owner", "name"}, expectOk: true},
+		{whenKey: "tags[]", expectSegments: []string{"tags", ""}, expectOk: true},
+		{whenKey: "page", expectOk: false},
+		{whenKey: "[0]", expectOk: false},
+		{whenKey: "items[0", expectOk: false},
+		{whenKey: "items[0]qty", expectOk: false},
+		{whenKey: "items..qty", expectOk: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.whenKey, func(t *testing.T) {
+			segments, ok := parseNestedKey(tc.whenKey)
+			assert.Equal(t, tc.expectOk, ok)
+			assert.Equal(t, tc.expectSegments, segments)
+		})
+	}
+}
+
+func TestDefaultBinder_BindQueryParams_Nested(t *testing.T) {
+	q := "filter[status]=open&filter.owner.name=jon&page=2" +
+		"&items[0][id]=3&items[0][qty]=2&items[1].id=4&items[1].qty=1" +
+		"&lookup[a][id]=7&labels[env]=prod&tags[]=x&tags[]=y&refs[1][id]=9&extra[k]=v"
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?"+q, nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var o nestedOrder
+	err := c.Bind(&o)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "open", o.Filter.Status)
+	if assert.NotNil(t, o.Filter.Owner) {
+		assert.Equal(t, "jon", o.Filter.Owner.Name)
+	}
+	assert.Equal(t, []nestedItem{{ID: 3, Qty: 2}, {ID: 4, Qty: 1}}, o.Items)
+	assert.Equal(t, map[string]nestedItem{"a": {ID: 7}}, o.Lookup)
+	assert.Equal(t, map[string]string{"env": "prod"}, o.Labels)
+	assert.Equal(t, []string{"x", "y"}, o.Tags)
+	assert.Equal(t, []*nestedItem{nil, {ID: 9}}, o.Refs)
+	assert.Equal(t, map[string]interface{}{"k": "v"}, o.Extra)
+	assert.Equal(t, 2, o.Page)
+}
+
+func TestDefaultBinder_BindBody_NestedForm(t *testing.T) {
+	e := New()
+	body := "items[0][id]=1&items[0][qty]=5&filter[status]=closed"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var o nestedOrder
+	err := c.Bind(&o)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []nestedItem{{ID: 1, Qty: 5}}, o.Items)
+	assert.Equal(t, "closed", o.Filter.Status)
+}
+
+func TestDefaultBinder_BindQueryParams_NestedErrors(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		givenBinder   *DefaultBinder
+		whenQuery     string
+		expectField   string
+		expectMessage string
+	}{
+		{
+			name:          "nok, invalid value has precise path",
+			givenBinder:   &DefaultBinder{},
+			whenQuery:     "items[0][id]=1&items[0][qty]=many",
+			expectField:   "items[0].qty",
+			expectMessage: "failed to bind field value to int",
+		},
+		{
+			name:          "nok, invalid slice index",
+			givenBinder:   &DefaultBinder{},
+			whenQuery:     "items[x][qty]=1",
+			expectField:   "items[x]",
+			expectMessage: "invalid slice index",
+		},
+		{
+			name:          "nok, slice index limit",
+			givenBinder:   &DefaultBinder{MaxSliceIndex: 10},
+			whenQuery:     "items[11][qty]=1",
+			expectField:   "items[11]",
+			expectMessage: "slice index limit exceeded",
+		},
+		{
+			name:          "nok, default slice index limit",
+			givenBinder:   &DefaultBinder{},
+			whenQuery:     "items[100000000][qty]=1",
+			expectField:   "items[100000000]",
+			expectMessage: "slice index limit exceeded",
+		},
+		{
+			name:          "nok, slice element limit across slices",
+			givenBinder:   &DefaultBinder{MaxSliceElements: 1500},
+			whenQuery:     "items[999][qty]=1&refs[999][qty]=1",
+			expectField:   "refs[999]",
+			expectMessage: "slice element limit exceeded",
+		},
+		{
+			name:          "nok, depth limit",
+			givenBinder:   &DefaultBinder{MaxNestedDepth: 2},
+			whenQuery:     "items[0][qty]=1",
+			expectField:   "items[0][qty]",
+			expectMessage: "nested key depth limit exceeded",
+		},
+		{
+			name:          "nok, nested key for scalar field",
+			givenBinder:   &DefaultBinder{},
+			whenQuery:     "items[0][qty][x]=1",
+			expectField:   "items[0].qty[x]",
+			expectMessage: "nested key is not supported by field type",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodGet, "/?"+tc.whenQuery, nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var o nestedOrder
+			err := tc.givenBinder.BindQueryParams(c, &o)
+
+			var be *BindingError
+			if assert.True(t, errors.As(err, &be)) {
+				assert.Equal(t, http.StatusBadRequest, be.Code)
+				assert.Equal(t, tc.expectField, be.Field)
+				assert.Equal(t, tc.expectMessage, be.Message)
+			}
+		})
+	}
+}
+
+func TestDefaultBinder_BindQueryParams_NestedSliceElementLimit(t *testing.T) {
+	query := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		query = append(query, "matrix["+strconv.Itoa(i)+"][999]=1")
+	}
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?"+strings.Join(query, "&"), nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var result struct {
+		Matrix [][]int `query:"matrix"`
+	}
+	err := (&DefaultBinder{}).BindQueryParams(c, &result)
+
+	var be *BindingError
+	if assert.True(t, errors.As(err, &be)) {
+		assert.Equal(t, "slice element limit exceeded", be.Message)
+	}
+	total := len(result.Matrix)
+	for _, row := range result.Matrix {
+		total += len(row)
+	}
+	assert.LessOrEqual(t, total, defaultBindMaxSliceElems)
+}
@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -1619,3 +1620,363 @@ func assertMultipartFileHeader(t *testing.T, fh *multipart.FileHeader, file test
 	err = fl.Close()
 	assert.NoError(t, err)
 }
+
+func TestDefaultBinder_DefaultAndRequiredTags(t *testing.T) {
+	type filter struct {
+		Status string `query:"status" default:"open"`
+	}
+	type request struct {
+		ID     int      `param:"id" required:"true"`
+		Page   int      `query:"page" default:"1"`
+		Size   *int     `query:"size" default:"20"`
+		Sort   []string `query:"sort" default:"name,id"`
+		Search string   `query:"q" required:"true"`
+		Filter filter   `query:"filter"`
+		Token  string   `header:"X-Token" default:"anonymous"`
+	}
+	var testCases = []struct {
+		name          string
+		whenURL       string
+		whenParams    []string
+		expect        request
+		expectField   string
+		expectMessage string
+	}{
+		{
+			name:       "ok, defaults are applied to absent and empty values",
+			whenURL:    "/?q=echo&page=",
+			whenParams: []string{"1"},
+			expect: request{
+				ID:     1,
+				Page:   1,
+				Size:   func() *int { v := 20; return &v }(),
+				Sort:   []string{"name", "id"},
+				Search: "echo",
+				Filter: filter{Status: "open"},
+				Token:  "anonymous",
+			},
+		},
+		{
+			name:       "ok, values from request take precedence",
+			whenURL:    "/?q=echo&page=3&size=5&sort=id&filter[status]=closed",
+			whenParams: []string{"2"},
+			expect: request{
+				ID:     2,
+				Page:   3,
+				Size:   func() *int { v := 5; return &v }(),
+				Sort:   []string{"id"},
+				Search: "echo",
+				Filter: filter{Status: "closed"},
+				Token:  "anonymous",
+			},
+		},
+		{
+			name:          "nok, required query value is missing",
+			whenURL:       "/?page=2",
+			whenParams:    []string{"1"},
+			expectField:   "q",
+			expectMessage: "required field value is empty",
+		},
+		{
+			name:          "nok, required query value is empty",
+			whenURL:       "/?q=",
+			whenParams:    []string{"1"},
+			expectField:   "q",
+			expectMessage: "required field value is empty",
+		},
+		{
+			name:          "nok, required path param is missing",
+			whenURL:       "/?q=echo",
+			whenParams:    []string{""},
+			expectField:   "id",
+			expectMessage: "required field value is empty",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodGet, tc.whenURL, nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues(tc.whenParams...)
+
+			var result request
+			err := c.Bind(&result)
+			if err == nil {
+				err = (&DefaultBinder{}).BindHeaders(c, &result)
+			}
+
+			if tc.expectField != "" {
+				var be *BindingError
+				if assert.True(t, errors.As(err, &be)) {
+					assert.Equal(t, http.StatusBadRequest, be.Code)
+					assert.Equal(t, tc.expectField, be.Field)
+					assert.Equal(t, tc.expectMessage, be.Message)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestDefaultBinder_RequiredMultipleSources(t *testing.T) {
+	type request struct {
+		ID int `param:"id" query:"id" required:"true"`
+	}
+	var testCases = []struct {
+		name        string
+		whenURL     string
+		whenParam   string
+		expectID    int
+		expectError string
+	}{
+		{
+			name:      "ok, value from path param",
+			whenURL:   "/",
+			whenParam: "1",
+			expectID:  1,
+		},
+		{
+			name:     "ok, value from query param",
+			whenURL:  "/?id=2",
+			expectID: 2,
+		},
+		{
+			name:        "nok, value is missing in all sources",
+			whenURL:     "/",
+			expectError: "code=400, message=required field value is empty, field=id",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, tc.whenURL, nil), httptest.NewRecorder())
+			if tc.whenParam != "" {
+				c.SetParamNames("id")
+				c.SetParamValues(tc.whenParam)
+			}
+
+			var result request
+			err := c.Bind(&result)
+
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectID, result.ID)
+		})
+	}
+}
+
+func TestHasDefaultOrRequiredTags(t *testing.T) {
+	type nested struct {
+		Page int `query:"page" default:"1"`
+	}
+	type request struct {
+		Name   string `query:"name"`
+		Nested *nested
+	}
+	assert.True(t, hasDefaultOrRequiredTags(reflect.TypeOf(request{}), "query"))
+	assert.False(t, hasDefaultOrRequiredTags(reflect.TypeOf(request{}), "form"))
+	assert.False(t, hasDefaultOrRequiredTags(reflect.TypeOf(struct {
+		Name string `query:"name"`
+	}{}), "query"))
+}
+
+func TestDefaultBinder_RequiredFormField(t *testing.T) {
+	type request struct {
+		Name  string `form:"name" required:"true"`
+		Email string `form:"email" required:"true"`
+	}
+	e := New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=jon"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err := c.Bind(&request{})
+
+	var be *BindingError
+	if assert.True(t, errors.As(err, &be)) {
+		assert.Equal(t, "email", be.Field)
+	}
+}
+
+func TestDefaultBinder_AggregateErrors(t *testing.T) {
+	type request struct {
+		ID    int          `param:"id"`
+		Page  int          `query:"page"`
+		Sort  []int        `query:"sort"`
+		Q     string       `query:"q" required:"true"`
+		Items []nestedItem `query:"items"`
+		Valid string       `query:"valid"`
+	}
+	e := New()
+	e.Binder = &DefaultBinder{AggregateErrors: true}
+	req := httptest.NewRequest(http.MethodGet, "/?page=x&sort=1&sort=y&items[0][qty]=z&items[a][id]=1&valid=ok", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("nan")
+
+	var result request
+	err := c.Bind(&result)
+
+	var be *BindingErrors
+	if !assert.True(t, errors.As(err, &be)) {
+		return
+	}
+	assert.Equal(t, http.StatusBadRequest, be.Code)
+	assert.Equal(t, "ok", result.Valid) // binding continues after failures
+
+	fields := map[string]FieldError{}
+	for _, fe := range be.Errors {
+		fields[fe.Field] = fe
+	}
+	assert.Len(t, be.Errors, 6)
+	assert.Equal(t, FieldError{
+		Source: "param",
+		Field:  "id",
+		Values: []string{"nan"},
+		Reason: `strconv.ParseInt: parsing "nan": invalid syntax`,
+	}, fields["id"])
+	assert.Equal(t, "query", fields["page"].Source)
+	assert.Equal(t, []string{"1", "y"}, fields["sort"].Values)
+	assert.Equal(t, "required field value is empty", fields["q"].Reason)
+	assert.Equal(t, []string{"z"}, fields["items[0].qty"].Values)
+	assert.Contains(t, fields["items[0].qty"].Reason, "failed to bind field value to int: ")
+	assert.Equal(t, "invalid slice index: strconv.Atoi: parsing \"a\": invalid syntax", fields["items[a]"].Reason)
+
+	e.HTTPErrorHandler(err, c)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"message":"failed to bind request"`)
+	assert.Contains(t, rec.Body.String(), `{"source":"query","field":"q","reason":"required field value is empty"}`)
+}
+
+func TestDefaultBinder_AggregateErrors_noErrors(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?page=2", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var result struct {
+		Page int `query:"page"`
+	}
+	err := (&DefaultBinder{AggregateErrors: true}).Bind(&result, c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Page)
+}
+
+func TestDefaultBinder_BindCookiesAndHeaders(t *testing.T) {
+	type request struct {
+		Theme   string   `cookie:"theme"`
+		Seen    []int    `cookie:"seen"`
+		Session string   `cookie:"session" required:"true"`
+		Page    int      `query:"page"`
+		Accept  []string `header:"Accept"`
+	}
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/?page=3", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	req.AddCookie(&http.Cookie{Name: "seen", Value: "1"})
+	req.AddCookie(&http.Cookie{Name: "seen", Value: "2"})
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.Header.Add(HeaderAccept, "text/html, application/json;q=0.9")
+	req.Header.Add(HeaderAccept, "*/*;q=0.1")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var result request
+	b := &DefaultBinder{IncludeCookies: true, SplitHeaderValues: true}
+	err := b.Bind(&result, c)
+	assert.NoError(t, err)
+	err = b.BindHeaders(c, &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, request{
+		Theme:   "dark",
+		Seen:    []int{1, 2},
+		Session: "abc",
+		Page:    3,
+		Accept:  []string{"text/html", "application/json;q=0.9", "*/*;q=0.1"},
+	}, result)
+
+	// cookies are not bound to map destinations by Bind
+	m := map[string]string{}
+	assert.NoError(t, b.Bind(&m, c))
+	assert.Equal(t, map[string]string{"page": "3"}, m)
+
+	// cookies are not bound and header values are not split by default
+	type defaultRequest struct {
+		Theme  string   `cookie:"theme"`
+		Accept []string `header:"Accept"`
+	}
+	var def defaultRequest
+	assert.NoError(t, c.Bind(&def))
+	assert.NoError(t, (&DefaultBinder{}).BindHeaders(c, &def))
+	assert.Equal(t, defaultRequest{Accept: []string{"text/html, application/json;q=0.9", "*/*;q=0.1"}}, def)
+}
+
+func TestDefaultBinder_BindCookies_required(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var result struct {
+		Session string `cookie:"session" required:"true"`
+	}
+	err := (&DefaultBinder{}).BindCookies(c, &result)
+
+	var be *BindingError
+	if assert.True(t, errors.As(err, &be)) {
+		assert.Equal(t, "session", be.Field)
+	}
+}
+
+func TestDefaultBinder_BindBody_formBodyTooLarge(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		whenContentType string
+		whenErr         error
+		expectCode      int
+	}{
+		{
+			name:            "ok, form body limit error keeps 413",
+			whenContentType: MIMEApplicationForm,
+			whenErr:         ErrStatusRequestEntityTooLarge,
+			expectCode:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:            "ok, multipart body limit error keeps 413",
+			whenContentType: MIMEMultipartForm + "; boundary=xxx",
+			whenErr:         ErrStatusRequestEntityTooLarge,
+			expectCode:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:            "ok, other read error is 400",
+			whenContentType: MIMEApplicationForm,
+			whenErr:         errors.New("read error"),
+			expectCode:      http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodPost, "/", iotest.ErrReader(tc.whenErr))
+			req.ContentLength = -1
+			req.Header.Set(HeaderContentType, tc.whenContentType)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var result struct {
+				Name string `form:"name"`
+			}
+			err := (&DefaultBinder{}).BindBody(c, &result)
+
+			var he *HTTPError
+			if assert.ErrorAs(t, err, &he) {
+				assert.Equal(t, tc.expectCode, he.Code)
+			}
+		})
+	}
+}
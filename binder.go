@@ -18,6 +18,9 @@ import (
     * QueryParamsBinder(c) - binds query parameters (source URL)
     * PathParamsBinder(c) - binds path parameters (source URL)
     * FormFieldBinder(c) - binds form fields (source URL + body)
+    * HeadersBinder(c) - binds request headers
+    * SplitHeadersBinder(c) - binds request headers, comma separated values are bound as separate values
+    * CookiesBinder(c) - binds request cookies
 
 	Example:
   ```go
@@ -92,6 +95,45 @@ func (be *BindingError) Error() string {
 	return fmt.Sprintf("%s, field=%s", be.HTTPError.Error(), be.Field)
 }
 
+// FieldError describes a single field that failed to bind.
+type FieldError struct {
+	// Source is binding source of the field: `param`, `query`, `header` or `form`.
+	Source string `json:"source"`
+	// Field is name or path (`items[0].qty`) of the field.
+	Field string `json:"field"`
+	// Values are raw values of the field from request.
+	Values []string `json:"values,omitempty"`
+	// Reason describes why binding failed.
+	Reason string `json:"reason"`
+}
+
+// BindingErrors is returned by DefaultBinder with `AggregateErrors` enabled when one or more fields failed to bind.
+type BindingErrors struct {
+	*HTTPError
+	// Errors are field errors in order of occurrence.
+	Errors []FieldError `json:"errors"`
+}
+
+// NewBindingErrors creates new instance of aggregated binding errors
+func NewBindingErrors(errors []FieldError) error {
+	return &BindingErrors{
+		Errors: errors,
+		HTTPError: &HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "failed to bind request",
+		},
+	}
+}
+
+// Error returns error message
+func (be *BindingErrors) Error() string {
+	fields := make([]string, len(be.Errors))
+	for i, fe := range be.Errors {
+		fields[i] = fe.Field
+	}
+	return fmt.Sprintf("%s, fields=%s", be.HTTPError.Error(), strings.Join(fields, ","))
+}
+
 // ValueBinder provides utility methods for binding query or path parameter to various Go built-in types
 type ValueBinder struct {
 	// ValueFunc is used to get single parameter (first) value from request
@@ -171,6 +213,96 @@ func FormFieldBinder(c Context) *ValueBinder {
 	return vb
 }
 
+// HeadersBinder creates request header value binder. Header names are case-insensitive. For slice types values of
+// repeated headers are bound as separate values. Values are not split on commas as some headers (i.e. HTTP dates)
+// contain commas, use SplitHeadersBinder for list headers.
+func HeadersBinder(c Context) *ValueBinder {
+	return &ValueBinder{
+		failFast: true,
+		ValueFunc: func(sourceParam string) string {
+			return c.Request().Header.Get(sourceParam)
+		},
+		ValuesFunc: func(sourceParam string) []string {
+			return c.Request().Header.Values(sourceParam)
+		},
+		ErrorFunc: NewBindingError,
+	}
+}
+
+// SplitHeadersBinder creates request header value binder for list headers. For slice types values of repeated
+// headers and comma separated values (`X-Ids: 1, 2` and `X-Ids: 3` result `["1", "2", "3"]`) are bound as separate
+// values. Commas inside quoted strings do not separate values.
+func SplitHeadersBinder(c Context) *ValueBinder {
+	vb := HeadersBinder(c)
+	vb.ValuesFunc = func(sourceParam string) []string {
+		return splitHeaderValues(c.Request().Header.Values(sourceParam))
+	}
+	return vb
+}
+
+// CookiesBinder creates request cookie value binder. For slice types values of all cookies with the same name are
+// bound.
+func CookiesBinder(c Context) *ValueBinder {
+	return &ValueBinder{
+		failFast: true,
+		ValueFunc: func(sourceParam string) string {
+			cookie, err := c.Cookie(sourceParam)
+			if err != nil {
+				return ""
+			}
+			return cookie.Value
+		},
+		ValuesFunc: func(sourceParam string) []string {
+			var values []string
+			for _, cookie := range c.Cookies() {
+				if cookie.Name == sourceParam {
+					values = append(values, cookie.Value)
+				}
+			}
+			return values
+		},
+		ErrorFunc: NewBindingError,
+	}
+}
+
+// splitHeaderValues splits comma separated header values (RFC 9110 section 5.6.1) and trims whitespace around them.
+// Commas inside quoted strings are not treated as separators. Empty elements are omitted.
+func splitHeaderValues(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		start := 0
+		quoted := false
+		for i := 0; i <= len(v); i++ {
+			if i < len(v) {
+				switch v[i] {
+				case '"':
+					quoted = !quoted
+					continue
+				case '\\':
+					if quoted {
+						i++
+					}
+					continue
+				case ',':
+					if quoted {
+						continue
+					}
+				default:
+					continue
+				}
+			}
+			if element := strings.TrimSpace(v[start:i]); element != "" {
+				result = append(result, element)
+			}
+			start = i + 1
+		}
+	}
+	return result
+}
+
 // FailFast set internal flag to indicate if binding methods will return early (without binding) when previous bind failed
 // NB: call this method before any other binding methods as it modifies binding methods behaviour
 func (b *ValueBinder) FailFast(value bool) *ValueBinder {
@@ -164,6 +164,97 @@ func TestFormFieldBinder(t *testing.T) {
 	assert.Equal(t, []int64{}, notExisting)
 }
 
+func TestHeadersBinder(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Add("X-Ids", "1, 2")
+	req.Header.Add("X-Ids", "3")
+	req.Header.Set("X-Tags", `a, "b,c", d`)
+	req.Header.Set("X-Limit", "nope")
+	req.Header.Set("X-Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var rawIDs []string
+	var dates []string
+	err := HeadersBinder(c).
+		Strings("X-Ids", &rawIDs).
+		Strings("X-Date", &dates).
+		BindError()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1, 2", "3"}, rawIDs)
+	assert.Equal(t, []string{"Mon, 02 Jan 2006 15:04:05 GMT"}, dates)
+
+	var requestID string
+	var ids []int64
+	var tags []string
+	var missing []string
+	err = SplitHeadersBinder(c).
+		String("x-request-id", &requestID).
+		Int64s("X-Ids", &ids).
+		Strings("X-Tags", &tags).
+		Strings("X-Missing", &missing).
+		BindError()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", requestID)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+	assert.Equal(t, []string{"a", `"b,c"`, "d"}, tags)
+	assert.Nil(t, missing)
+
+	var limit int
+	err = HeadersBinder(c).MustInt("X-Limit", &limit).BindError()
+	assert.EqualError(t, err, `code=400, message=failed to bind field value to int, internal=strconv.ParseInt: parsing "nope": invalid syntax, field=X-Limit`)
+}
+
+func TestCookiesBinder(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	req.AddCookie(&http.Cookie{Name: "page", Value: "2"})
+	req.AddCookie(&http.Cookie{Name: "seen", Value: "1"})
+	req.AddCookie(&http.Cookie{Name: "seen", Value: "5"})
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var theme string
+	var page int
+	var seen []int
+	err := CookiesBinder(c).
+		String("theme", &theme).
+		Int("page", &page).
+		Ints("seen", &seen).
+		BindError()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", theme)
+	assert.Equal(t, 2, page)
+	assert.Equal(t, []int{1, 5}, seen)
+
+	var session string
+	err = CookiesBinder(c).MustString("session", &session).BindError()
+	assert.EqualError(t, err, "code=400, message=required field value is empty, field=session")
+}
+
+func TestSplitHeaderValues(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		when   []string
+		expect []string
+	}{
+		{name: "nil", when: nil, expect: nil},
+		{name: "single value", when: []string{"a"}, expect: []string{"a"}},
+		{name: "comma separated and repeated", when: []string{"a, b", "c"}, expect: []string{"a", "b", "c"}},
+		{name: "empty elements are omitted", when: []string{" , a,,b ,"}, expect: []string{"a", "b"}},
+		{name: "quoted commas", when: []string{`"a,b", c`}, expect: []string{`"a,b"`, "c"}},
+		{name: "escaped quote inside quoted string", when: []string{`"a\",b", c`}, expect: []string{`"a\",b"`, "c"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, splitHeaderValues(tc.when))
+		})
+	}
+}
+
 func TestValueBinder_errorStopsBinding(t *testing.T) {
 	// this test documents "feature" that binding multiple params can change destination if it was binded before
 	// failing parameter binding
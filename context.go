@@ -8,12 +8,15 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 )
 
 // Context represents the context of the current HTTP request. It holds request and
@@ -87,6 +90,10 @@ type Context interface {
 	// MultipartForm returns the multipart form.
 	MultipartForm() (*multipart.Form, error)
 
+	// MultipartStream returns stream to iterate over parts of multipart form request body without buffering
+	// file contents into memory or temporary files. Limits are enforced while parts are being read.
+	MultipartStream(config MultipartStreamConfig) (*MultipartStream, error)
+
 	// Cookie returns the named cookie provided in the request.
 	Cookie(name string) (*http.Cookie, error)
 
@@ -96,6 +103,13 @@ type Context interface {
 	// Cookies returns the HTTP cookies sent with the request.
 	Cookies() []*http.Cookie
 
+	// SetSecureCookie adds a `Set-Cookie` header with value signed and optionally encrypted using `Echo.CookieKeys`.
+	SetSecureCookie(name string, value string, opts SecureCookieOptions) error
+
+	// SecureCookie returns the named cookie provided in the request with verified and decrypted value. Returns
+	// `*InvalidCookieError` when cookie value has been tampered with and `ErrSecureCookieExpired` when it has expired.
+	SecureCookie(name string) (*http.Cookie, error)
+
 	// Get retrieves data from the context.
 	Get(key string) interface{}
 
@@ -165,12 +179,30 @@ type Context interface {
 	// Inline sends a response as inline, opening the file in the browser.
 	Inline(file string, name string) error
 
+	// AttachmentFS sends a response as attachment from the provided file system, prompting client to save the file.
+	// Range requests are supported.
+	AttachmentFS(file string, name string, filesystem fs.FS) error
+
+	// InlineFS sends a response as inline from the provided file system, opening the file in the browser.
+	// Range requests are supported.
+	InlineFS(file string, name string, filesystem fs.FS) error
+
+	// ServeContent sends the content of given io.ReadSeeker as the response. Single and multi range requests,
+	// `If-Range` and conditional requests are handled using `modTime`. Content type is detected from the name
+	// extension or by sniffing the content unless `Content-Type` header is already set.
+	ServeContent(name string, modTime time.Time, content io.ReadSeeker) error
+
 	// NoContent sends a response with no body and a status code.
 	NoContent(code int) error
 
 	// Redirect redirects the request to a provided URL with status code.
 	Redirect(code int, url string) error
 
+	// CheckPreconditions sets `ETag` and `Last-Modified` response headers and evaluates conditional request headers
+	// against them. Returns true when "304 Not Modified" response has been sent and `ErrPreconditionFailed` when
+	// request preconditions (`If-Match`, `If-Unmodified-Since`) failed.
+	CheckPreconditions(etag string, modTime time.Time) (bool, error)
+
 	// Error invokes the registered global HTTP error handler. Generally used by middleware.
 	// A side-effect of calling global error handler is that now Response has been committed (sent to the client) and
 	// middlewares up in chain can not change Response status code or Response body anymore.
@@ -598,10 +630,54 @@ func (c *context) Inline(file, name string) error {
 var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
 
 func (c *context) contentDisposition(file, name, dispositionType string) error {
-	c.response.Header().Set(HeaderContentDisposition, fmt.Sprintf(`%s; filename="%s"`, dispositionType, quoteEscaper.Replace(name)))
+	c.response.Header().Set(HeaderContentDisposition, contentDispositionValue(dispositionType, name))
 	return c.File(file)
 }
 
+// contentDispositionValue creates `Content-Disposition` header value. Names containing non-ASCII characters are
+// additionally sent as RFC 5987 encoded `filename*` parameter and `filename` contains ASCII only fallback.
+// See RFC 6266 section 4.3: https://datatracker.ietf.org/doc/html/rfc6266#section-4.3
+func contentDispositionValue(dispositionType, name string) string {
+	if isASCII(name) {
+		return fmt.Sprintf(`%s; filename="%s"`, dispositionType, quoteEscaper.Replace(name))
+	}
+	fallback := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, dispositionType, quoteEscaper.Replace(fallback), encodeRFC5987(name))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeRFC5987 percent-encodes all bytes of value that are not `attr-char` characters.
+// See RFC 5987 section 3.2.1: https://datatracker.ietf.org/doc/html/rfc5987#section-3.2.1
+func encodeRFC5987(value string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') ||
+			strings.IndexByte("!#$&+-.^_`|~", ch) != -1 {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0F])
+	}
+	return b.String()
+}
+
 func (c *context) NoContent(code int) error {
 	c.response.WriteHeader(code)
 	return nil
@@ -9,6 +9,7 @@ import (
 	"io/fs"
 	"net/http"
 	"path/filepath"
+	"time"
 )
 
 func (c *context) File(file string) error {
@@ -24,6 +25,21 @@ func (c *context) FileFS(file string, filesystem fs.FS) error {
 	return fsFile(c, file, filesystem)
 }
 
+func (c *context) AttachmentFS(file, name string, filesystem fs.FS) error {
+	c.response.Header().Set(HeaderContentDisposition, contentDispositionValue("attachment", name))
+	return fsFile(c, file, filesystem)
+}
+
+func (c *context) InlineFS(file, name string, filesystem fs.FS) error {
+	c.response.Header().Set(HeaderContentDisposition, contentDispositionValue("inline", name))
+	return fsFile(c, file, filesystem)
+}
+
+func (c *context) ServeContent(name string, modTime time.Time, content io.ReadSeeker) error {
+	http.ServeContent(c.Response(), c.Request(), name, modTime, content)
+	return nil
+}
+
 func fsFile(c Context, file string, filesystem fs.FS) error {
 	f, err := filesystem.Open(file)
 	if err != nil {
@@ -43,6 +59,11 @@ func fsFile(c Context, file string, filesystem fs.FS) error {
 			return err
 		}
 	}
+	if pfs, ok := filesystem.(*precompressedFS); ok {
+		if ServePrecompressed(c, pfs.FS.Open, file, fi, pfs.encodings...) {
+			return nil
+		}
+	}
 	ff, ok := f.(io.ReadSeeker)
 	if !ok {
 		return errors.New("file does not implement io.ReadSeeker")
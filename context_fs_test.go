@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestContext_File(t *testing.T) {
@@ -133,3 +135,141 @@ func TestContext_FileFS(t *testing.T) {
 		})
 	}
 }
+
+func TestContext_AttachmentFS(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenInline   bool
+		whenRange    string
+		expectStatus int
+		expectHeader string
+		expectLength int
+	}{
+		{
+			name:         "ok, attachment",
+			expectStatus: http.StatusOK,
+			expectHeader: `attachment; filename="walle.png"`,
+			expectLength: 219885,
+		},
+		{
+			name:         "ok, inline",
+			whenInline:   true,
+			expectStatus: http.StatusOK,
+			expectHeader: `inline; filename="walle.png"`,
+			expectLength: 219885,
+		},
+		{
+			name:         "ok, attachment with range",
+			whenRange:    "bytes=0-9",
+			expectStatus: http.StatusPartialContent,
+			expectHeader: `attachment; filename="walle.png"`,
+			expectLength: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.whenRange != "" {
+				req.Header.Set(HeaderRange, tc.whenRange)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var err error
+			if tc.whenInline {
+				err = c.InlineFS("walle.png", "walle.png", os.DirFS("_fixture/images"))
+			} else {
+				err = c.AttachmentFS("walle.png", "walle.png", os.DirFS("_fixture/images"))
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tc.expectHeader, rec.Header().Get(HeaderContentDisposition))
+			assert.Equal(t, tc.expectLength, rec.Body.Len())
+		})
+	}
+}
+
+func TestContext_ServeContent(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	content := "0123456789abcdefghij"
+
+	var testCases = []struct {
+		name              string
+		whenHeaders       map[string]string
+		expectStatus      int
+		expectBody        string
+		expectContains    []string
+		expectContentType string
+	}{
+		{
+			name:              "ok, full content",
+			expectStatus:      http.StatusOK,
+			expectBody:        content,
+			expectContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:         "ok, single range",
+			whenHeaders:  map[string]string{HeaderRange: "bytes=10-14"},
+			expectStatus: http.StatusPartialContent,
+			expectBody:   "abcde",
+		},
+		{
+			name:           "ok, multi range",
+			whenHeaders:    map[string]string{HeaderRange: "bytes=0-1,18-"},
+			expectStatus:   http.StatusPartialContent,
+			expectContains: []string{"Content-Range: bytes 0-1/20\r\n", "\r\n\r\n01\r\n", "Content-Range: bytes 18-19/20\r\n", "\r\n\r\nij\r\n"},
+		},
+		{
+			name: "ok, If-Range matches",
+			whenHeaders: map[string]string{
+				HeaderRange:   "bytes=0-1",
+				HeaderIfRange: modTime.Format(http.TimeFormat),
+			},
+			expectStatus: http.StatusPartialContent,
+			expectBody:   "01",
+		},
+		{
+			name: "ok, If-Range does not match sends full content",
+			whenHeaders: map[string]string{
+				HeaderRange:   "bytes=0-1",
+				HeaderIfRange: modTime.Add(-time.Hour).Format(http.TimeFormat),
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   content,
+		},
+		{
+			name:         "nok, range not satisfiable",
+			whenHeaders:  map[string]string{HeaderRange: "bytes=100-"},
+			expectStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.whenHeaders {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := c.ServeContent("export.txt", modTime, strings.NewReader(content))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody, rec.Body.String())
+			}
+			for _, part := range tc.expectContains {
+				assert.Contains(t, rec.Body.String(), part)
+			}
+			if tc.expectContentType != "" {
+				assert.Equal(t, tc.expectContentType, rec.Header().Get(HeaderContentType))
+			}
+		})
+	}
+}
@@ -430,6 +430,11 @@ func TestContextAttachment(t *testing.T) {
 			whenName:     `malicious.sh"; \"; dummy=.txt`,
 			expectHeader: `attachment; filename="malicious.sh\"; \\\"; dummy=.txt"`,
 		},
+		{
+			name:         "ok, non-ASCII filename is RFC 5987 encoded",
+			whenName:     "€ rates ß.png",
+			expectHeader: `attachment; filename="_ rates _.png"; filename*=UTF-8''%E2%82%AC%20rates%20%C3%9F.png`,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -83,6 +83,7 @@ type Echo struct {
 	router        *Router
 	routers       map[string]*Router
 	pool          sync.Pool
+	assets        []*AssetManifest
 
 	StdLogger        *stdLog.Logger
 	Server           *http.Server
@@ -99,6 +100,9 @@ type Echo struct {
 	IPExtractor      IPExtractor
 	ListenerNetwork  string
 
+	// CookieKeys is key ring used by `Context#SetSecureCookie` and `Context#SecureCookie` to sign and encrypt cookies.
+	CookieKeys *CookieKeyRing
+
 	// OnAddRouteHandler is called when Echo adds new route to specific host router.
 	OnAddRouteHandler func(host string, route Route, handler HandlerFunc, middleware []MiddlewareFunc)
 	DisableHTTP2      bool
@@ -186,6 +190,10 @@ const (
 	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + charsetUTF8
 	MIMEMultipartForm                    = "multipart/form-data"
 	MIMEOctetStream                      = "application/octet-stream"
+	// MIMEApplicationJSONPatch JSON Patch document https://www.rfc-editor.org/rfc/rfc6902
+	MIMEApplicationJSONPatch = "application/json-patch+json"
+	// MIMEApplicationMergePatch JSON Merge Patch document https://www.rfc-editor.org/rfc/rfc7386
+	MIMEApplicationMergePatch = "application/merge-patch+json"
 )
 
 const (
@@ -215,7 +223,15 @@ const (
 	HeaderCookie              = "Cookie"
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderIfModifiedSince     = "If-Modified-Since"
+	HeaderIfUnmodifiedSince   = "If-Unmodified-Since"
+	HeaderIfMatch             = "If-Match"
+	HeaderIfNoneMatch         = "If-None-Match"
+	HeaderETag                = "ETag"
 	HeaderLastModified        = "Last-Modified"
+	HeaderRange               = "Range"
+	HeaderIfRange             = "If-Range"
+	HeaderContentRange        = "Content-Range"
+	HeaderAcceptRanges        = "Accept-Ranges"
 	HeaderLocation            = "Location"
 	HeaderRetryAfter          = "Retry-After"
 	HeaderUpgrade             = "Upgrade"
@@ -235,6 +251,9 @@ const (
 	HeaderOrigin              = "Origin"
 	HeaderCacheControl        = "Cache-Control"
 	HeaderConnection          = "Connection"
+	HeaderAge                 = "Age"
+	HeaderExpires             = "Expires"
+	HeaderPragma              = "Pragma"
 
 	// Access control
 	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
@@ -421,6 +440,9 @@ func (e *Echo) DefaultHTTPErrorHandler(err error, c Context) {
 		return
 	}
 
+	var be *BindingError
+	var bes *BindingErrors
+	var ve *ValidationErrors
 	he, ok := err.(*HTTPError)
 	if ok {
 		if he.Internal != nil {
@@ -428,6 +450,22 @@ func (e *Echo) DefaultHTTPErrorHandler(err error, c Context) {
 				he = herr
 			}
 		}
+	} else if errors.As(err, &be) {
+		he = &HTTPError{
+			Code:     be.Code,
+			Message:  Map{"message": be.Message, "field": be.Field},
+			Internal: be.Internal,
+		}
+	} else if errors.As(err, &bes) {
+		he = &HTTPError{
+			Code:    bes.Code,
+			Message: Map{"message": bes.Message, "errors": bes.Errors},
+		}
+	} else if errors.As(err, &ve) {
+		he = &HTTPError{
+			Code:    ve.Code,
+			Message: Map{"message": ve.Message, "errors": ve.Errors},
+		}
 	} else {
 		he = &HTTPError{
 			Code:    http.StatusInternalServerError,
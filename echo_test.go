@@ -1366,6 +1366,24 @@ func TestDefaultHTTPErrorHandler(t *testing.T) {
 			expectCode: http.StatusBadRequest,
 			expectBody: "{\"message\":\"error in httperror\"}\n",
 		},
+		{
+			name:       "with Debug=false binding error contains field",
+			whenPath:   "/binding-error",
+			expectCode: http.StatusBadRequest,
+			expectBody: "{\"field\":\"page\",\"message\":\"required field value is empty\"}\n",
+		},
+		{
+			name:       "with Debug=false wrapped binding error contains field",
+			whenPath:   "/wrapped-binding-error",
+			expectCode: http.StatusBadRequest,
+			expectBody: "{\"field\":\"page\",\"message\":\"required field value is empty\"}\n",
+		},
+		{
+			name:       "with Debug=false wrapped binding errors contain field errors",
+			whenPath:   "/wrapped-binding-errors",
+			expectCode: http.StatusBadRequest,
+			expectBody: "{\"errors\":[{\"source\":\"query\",\"field\":\"page\",\"reason\":\"invalid\"}],\"message\":\"failed to bind request\"}\n",
+		},
 		{
 			name:       "with Debug=false when httpError contains an error",
 			whenPath:   "/customerror-in-httperror",
@@ -1413,6 +1431,18 @@ func TestDefaultHTTPErrorHandler(t *testing.T) {
 				return NewHTTPError(http.StatusBadRequest, errors.New("error in httperror"))
 			})
 
+			e.GET("/binding-error", func(c Context) error {
+				return NewBindingError("page", nil, "required field value is empty", nil)
+			})
+
+			e.GET("/wrapped-binding-error", func(c Context) error {
+				return fmt.Errorf("bind: %w", NewBindingError("page", nil, "required field value is empty", nil))
+			})
+
+			e.GET("/wrapped-binding-errors", func(c Context) error {
+				return fmt.Errorf("bind: %w", NewBindingErrors([]FieldError{{Source: "query", Field: "page", Reason: "invalid"}}))
+			})
+
 			e.GET("/customerror-in-httperror", func(c Context) error {
 				return NewHTTPError(http.StatusBadRequest, &customError{s: "custom error msg"})
 			})
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GenerateETag returns an entity tag for given content. Tag is quoted and can be used as is for `ETag` header value.
+// When weak is true the tag is prefixed with `W/` which marks it as weak validator (semantically equivalent content)
+// instead of strong validator (byte-for-byte identical content).
+// See RFC 9110 section 8.8.3: https://www.rfc-editor.org/rfc/rfc9110#section-8.8.3
+func GenerateETag(content []byte, weak bool) string {
+	sum := sha256.Sum256(content)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// EvaluatePreconditions evaluates conditional request headers (`If-Match`, `If-Unmodified-Since`, `If-None-Match`,
+// `If-Modified-Since`) against the current entity tag and modification time of the selected representation.
+// Empty etag or zero modTime mean that the respective validator is not available.
+//
+// Returns 0 when request should be processed normally, http.StatusNotModified when a GET/HEAD request can be answered
+// with 304 and http.StatusPreconditionFailed when request must not be processed.
+// Evaluation order follows RFC 9110 section 13.2.2: https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2
+func EvaluatePreconditions(r *http.Request, etag string, modTime time.Time) int {
+	isGetOrHead := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if ifMatch := r.Header.Get(HeaderIfMatch); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get(HeaderIfUnmodifiedSince); ius != "" && !isZeroTime(modTime) {
+		if t, err := http.ParseTime(ius); err == nil && modTime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := r.Header.Get(HeaderIfNoneMatch); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			if isGetOrHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := r.Header.Get(HeaderIfModifiedSince); ims != "" && isGetOrHead && !isZeroTime(modTime) {
+		if t, err := http.ParseTime(ims); err == nil && !modTime.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// CheckPreconditions sets `ETag` and `Last-Modified` response headers and evaluates request conditional headers
+// against them. It is meant to be called by handler before doing expensive work or modifying the resource.
+//
+// When request can be answered with "304 Not Modified" the response is sent and `true` is returned. When precondition
+// fails `ErrPreconditionFailed` is returned. Otherwise handler should continue processing the request.
+//
+// Example:
+//
+//	if done, err := c.CheckPreconditions(doc.ETag, doc.UpdatedAt); done || err != nil {
+//		return err
+//	}
+func (c *context) CheckPreconditions(etag string, modTime time.Time) (bool, error) {
+	header := c.response.Header()
+	if etag != "" {
+		header.Set(HeaderETag, etag)
+	}
+	if !isZeroTime(modTime) {
+		header.Set(HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+	}
+
+	switch EvaluatePreconditions(c.request, etag, modTime) {
+	case http.StatusNotModified:
+		WriteNotModified(c.response)
+		return true, nil
+	case http.StatusPreconditionFailed:
+		return false, ErrPreconditionFailed
+	}
+	return false, nil
+}
+
+// WriteNotModified removes representation headers that must not be sent with 304 response and writes
+// "304 Not Modified" status to the response.
+func WriteNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del(HeaderContentType)
+	h.Del(HeaderContentLength)
+	h.Del(HeaderContentEncoding)
+	if h.Get(HeaderETag) != "" {
+		h.Del(HeaderLastModified)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// matchETag reports whether any entity tag in comma separated list (or `*`) matches given etag. With weak comparison
+// the `W/` prefix is ignored on both sides, with strong comparison weak tags never match.
+func matchETag(list string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	list = strings.TrimSpace(list)
+	if list == "*" {
+		return true
+	}
+	for list != "" {
+		var candidate string
+		candidate, list = scanETag(list)
+		if candidate == "" {
+			return false // malformed list
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if candidate == etag && !strings.HasPrefix(candidate, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scanETag returns first entity tag from comma separated list and the remainder of that list.
+func scanETag(s string) (etag string, remain string) {
+	s = strings.TrimLeft(s, " \t,")
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s[start:]) < 2 || s[start] != '"' {
+		return "", ""
+	}
+	end := strings.IndexByte(s[start+1:], '"')
+	if end == -1 {
+		return "", ""
+	}
+	end += start + 2
+	return s[:end], strings.TrimLeft(s[end:], " \t,")
+}
+
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateETag(t *testing.T) {
+	strong := GenerateETag([]byte("hello"), false)
+	assert.Equal(t, `"2cf24dba5fb0a30e26e83b2ac5b9e29e"`, strong)
+	assert.Equal(t, "W/"+strong, GenerateETag([]byte("hello"), true))
+	assert.NotEqual(t, strong, GenerateETag([]byte("hello!"), false))
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var testCases = []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		etag     string
+		modTime  time.Time
+		expected int
+	}{
+		{
+			name:     "ok, no conditional headers",
+			method:   http.MethodGet,
+			etag:     `"abc"`,
+			expected: 0,
+		},
+		{
+			name:     "ok, If-None-Match matches on GET",
+			method:   http.MethodGet,
+			headers:  map[string]string{HeaderIfNoneMatch: `"xyz", "abc"`},
+			etag:     `"abc"`,
+			expected: http.StatusNotModified,
+		},
+		{
+			name:     "ok, If-None-Match uses weak comparison",
+			method:   http.MethodGet,
+			headers:  map[string]string{HeaderIfNoneMatch: `W/"abc"`},
+			etag:     `"abc"`,
+			expected: http.StatusNotModified,
+		},
+		{
+			name:     "ok, If-None-Match does not match",
+			method:   http.MethodGet,
+			headers:  map[string]string{HeaderIfNoneMatch: `"xyz"`},
+			etag:     `"abc"`,
+			expected: 0,
+		},
+		{
+			name:     "ok, If-None-Match * on PUT fails",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfNoneMatch: `*`},
+			etag:     `"abc"`,
+			expected: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "ok, If-Match matches",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfMatch: `"abc"`},
+			etag:     `"abc"`,
+			expected: 0,
+		},
+		{
+			name:     "ok, If-Match uses strong comparison",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfMatch: `W/"abc"`},
+			etag:     `W/"abc"`,
+			expected: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "ok, If-Match does not match",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfMatch: `"xyz"`},
+			etag:     `"abc"`,
+			expected: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "ok, If-Match malformed",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfMatch: `abc`},
+			etag:     `"abc"`,
+			expected: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "ok, If-Modified-Since not modified",
+			method:   http.MethodGet,
+			headers:  map[string]string{HeaderIfModifiedSince: modTime.Format(http.TimeFormat)},
+			modTime:  modTime.Add(500 * time.Millisecond),
+			expected: http.StatusNotModified,
+		},
+		{
+			name:     "ok, If-Modified-Since modified",
+			method:   http.MethodGet,
+			headers:  map[string]string{HeaderIfModifiedSince: modTime.Format(http.TimeFormat)},
+			modTime:  modTime.Add(time.Hour),
+			expected: 0,
+		},
+		{
+			name:   "ok, If-Modified-Since ignored when If-None-Match present",
+			method: http.MethodGet,
+			headers: map[string]string{
+				HeaderIfNoneMatch:     `"xyz"`,
+				HeaderIfModifiedSince: modTime.Format(http.TimeFormat),
+			},
+			etag:     `"abc"`,
+			modTime:  modTime,
+			expected: 0,
+		},
+		{
+			name:     "ok, If-Modified-Since ignored for POST",
+			method:   http.MethodPost,
+			headers:  map[string]string{HeaderIfModifiedSince: modTime.Format(http.TimeFormat)},
+			modTime:  modTime,
+			expected: 0,
+		},
+		{
+			name:     "ok, If-Unmodified-Since fails when modified",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfUnmodifiedSince: modTime.Format(http.TimeFormat)},
+			modTime:  modTime.Add(time.Hour),
+			expected: http.StatusPreconditionFailed,
+		},
+		{
+			name:     "ok, If-Unmodified-Since passes",
+			method:   http.MethodPut,
+			headers:  map[string]string{HeaderIfUnmodifiedSince: modTime.Format(http.TimeFormat)},
+			modTime:  modTime,
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tc.expected, EvaluatePreconditions(req, tc.etag, tc.modTime))
+		})
+	}
+}
+
+func TestContext_CheckPreconditions(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("ok, not modified", func(t *testing.T) {
+		e := New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderIfNoneMatch, `"v1"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		done, err := c.CheckPreconditions(`"v1"`, modTime)
+
+		assert.NoError(t, err)
+		assert.True(t, done)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Equal(t, `"v1"`, rec.Header().Get(HeaderETag))
+		assert.Equal(t, "", rec.Header().Get(HeaderLastModified))
+	})
+
+	t.Run("ok, continue", func(t *testing.T) {
+		e := New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		done, err := c.CheckPreconditions(`"v1"`, modTime)
+
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.False(t, c.Response().Committed)
+		assert.Equal(t, `"v1"`, rec.Header().Get(HeaderETag))
+		assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", rec.Header().Get(HeaderLastModified))
+	})
+
+	t.Run("nok, lost update", func(t *testing.T) {
+		e := New()
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		req.Header.Set(HeaderIfMatch, `"v1"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		done, err := c.CheckPreconditions(`"v2"`, time.Time{})
+
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+		assert.False(t, done)
+		assert.False(t, c.Response().Committed)
+	})
+}
+
+func TestWriteNotModified(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(HeaderContentType, MIMETextPlain)
+	rec.Header().Set(HeaderContentLength, "5")
+	rec.Header().Set(HeaderContentEncoding, "gzip")
+	rec.Header().Set(HeaderETag, `"abc"`)
+	rec.Header().Set(HeaderLastModified, "Mon, 02 Jan 2006 15:04:05 GMT")
+
+	WriteNotModified(rec)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Len(t, rec.Header(), 1)
+	assert.Equal(t, `"abc"`, rec.Header().Get(HeaderETag))
+}
@@ -4,13 +4,66 @@
 package echo
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 )
 
 // DefaultJSONSerializer implements JSON encoding using encoding/json.
-type DefaultJSONSerializer struct{}
+//
+// Zero value decodes request bodies without any restrictions. For public APIs stricter decoding can be configured:
+//
+//	e.JSONSerializer = &echo.DefaultJSONSerializer{
+//		DisallowUnknownFields: true,
+//		MaxBodySize:           1 << 20,
+//		MaxDepth:              32,
+//		DisallowDuplicateKeys: true,
+//		DisallowTrailingData:  true,
+//	}
+//
+// All decoding errors are returned as HTTPError with status 400 (413 when body is larger than MaxBodySize) and
+// message containing the byte offset of the problem where it is known.
+type DefaultJSONSerializer struct {
+	// DisallowUnknownFields causes an error when the destination is a struct and the input contains object keys which
+	// do not match any non-ignored, exported fields in the destination. Keys are matched to fields like encoding/json
+	// does, exact match first and then case-insensitive.
+	DisallowUnknownFields bool
+
+	// UseNumber causes numbers decoded into an interface{} to be json.Number instead of float64.
+	UseNumber bool
+
+	// MaxBodySize is maximum number of bytes read from the request body. Zero or negative means no limit.
+	MaxBodySize int64
+
+	// MaxDepth is maximum nesting depth of objects and arrays. i.e. `{"a":[1]}` has depth of 2.
+	// Zero or negative means no limit (encoding/json still applies its own internal limit).
+	MaxDepth int
+
+	// DisallowDuplicateKeys causes an error when an object contains the same key more than once. Keys of objects
+	// decoded into structs are duplicates when they match the same field, i.e. `{"id":1,"ID":2}`. By default the last
+	// value wins.
+	DisallowDuplicateKeys bool
+
+	// DisallowTrailingData causes an error when the body contains anything besides whitespace after the first JSON value.
+	DisallowTrailingData bool
+}
+
+// jsonDecodeError is returned by the token scan done for MaxDepth, DisallowUnknownFields and DisallowDuplicateKeys
+// checks.
+type jsonDecodeError struct {
+	message string
+	offset  int64
+}
+
+func (e *jsonDecodeError) Error() string {
+	return fmt.Sprintf("%v: offset=%v", e.message, e.offset)
+}
 
 // Serialize converts an interface into a json and writes it to the response.
 // You can optionally use the indent parameter to produce pretty JSONs.
@@ -24,11 +77,280 @@ func (d DefaultJSONSerializer) Serialize(c Context, i interface{}, indent string
 
 // Deserialize reads a JSON from a request body and converts it into an interface.
 func (d DefaultJSONSerializer) Deserialize(c Context, i interface{}) error {
-	err := json.NewDecoder(c.Request().Body).Decode(i)
-	if ute, ok := err.(*json.UnmarshalTypeError); ok {
-		return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, field=%v, offset=%v", ute.Type, ute.Value, ute.Field, ute.Offset)).SetInternal(err)
-	} else if se, ok := err.(*json.SyntaxError); ok {
-		return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error())).SetInternal(err)
+	var body io.Reader = c.Request().Body
+	if d.MaxBodySize > 0 {
+		body = http.MaxBytesReader(c.Response(), c.Request().Body, d.MaxBodySize)
+	}
+
+	if d.MaxDepth > 0 || d.DisallowDuplicateKeys || d.DisallowUnknownFields {
+		// structural checks need a separate pass over the tokens so the body is buffered before decoding
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return d.wrapError(err)
+		}
+		if err := d.scan(b, reflect.TypeOf(i)); err != nil {
+			return d.wrapError(err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	dec := json.NewDecoder(body)
+	if d.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(i); err != nil {
+		return d.wrapError(err)
+	}
+	if d.DisallowTrailingData {
+		offset := dec.InputOffset()
+		if _, err := dec.Token(); err != io.EOF {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				return d.wrapError(err)
+			}
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Trailing data error: offset=%v", offset)).SetInternal(err)
+		}
+	}
+	return nil
+}
+
+func (d DefaultJSONSerializer) wrapError(err error) error {
+	var (
+		ute  *json.UnmarshalTypeError
+		se   *json.SyntaxError
+		mbe  *http.MaxBytesError
+		jde  *jsonDecodeError
+		code = http.StatusBadRequest
+	)
+	switch {
+	case errors.As(err, &ute):
+		return NewHTTPError(code, fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, field=%v, offset=%v", ute.Type, ute.Value, ute.Field, ute.Offset)).SetInternal(err)
+	case errors.As(err, &se):
+		return NewHTTPError(code, fmt.Sprintf("Syntax error: offset=%v, error=%v", se.Offset, se.Error())).SetInternal(err)
+	case errors.As(err, &mbe):
+		return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large: limit=%v bytes", mbe.Limit)).SetInternal(err)
+	case errors.As(err, &jde):
+		return NewHTTPError(code, jde.Error()).SetInternal(err)
 	}
 	return err
 }
+
+// scan walks over the tokens of the first JSON value in b and checks nesting depth, unknown fields and duplicate object
+// keys. typ is the destination type, object keys are matched to struct fields of it.
+func (d DefaultJSONSerializer) scan(b []byte, typ reflect.Type) error {
+	type frame struct {
+		object    bool
+		expectKey bool
+		// typ is struct, map, slice or array type the value is decoded into. nil when not known.
+		typ reflect.Type
+		// valueType is type of the next value in the object or array
+		valueType reflect.Type
+		keys      map[string]struct{}
+	}
+	var stack []*frame
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // avoid float parsing of numbers, values are not used
+	valueType := typ
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			// syntax errors are left for the json.Decoder.Decode which reports them more precisely than Token does
+			return nil
+		}
+
+		var parent *frame
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			valueType = parent.valueType
+		}
+		if parent != nil && parent.object && parent.expectKey {
+			if key, ok := tok.(string); ok {
+				name := key
+				parent.valueType = nil
+				if parent.typ != nil && parent.typ.Kind() == reflect.Struct {
+					field, ok := lookupJSONField(cachedJSONFields(parent.typ), key)
+					if !ok && d.DisallowUnknownFields {
+						return &jsonDecodeError{
+							message: fmt.Sprintf("Unknown field error: field=%v", key),
+							offset:  keyOffset(b, offset),
+						}
+					}
+					name = field.name
+					parent.valueType = field.typ
+				} else if parent.typ != nil && parent.typ.Kind() == reflect.Map {
+					parent.valueType = parent.typ.Elem()
+				}
+				if d.DisallowDuplicateKeys && name != "" {
+					if _, exists := parent.keys[name]; exists {
+						return &jsonDecodeError{
+							message: fmt.Sprintf("Duplicate key error: key=%v", key),
+							offset:  keyOffset(b, offset),
+						}
+					}
+					parent.keys[name] = struct{}{}
+				}
+				parent.expectKey = false
+				continue
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if d.MaxDepth > 0 && len(stack) >= d.MaxDepth {
+				return &jsonDecodeError{
+					message: fmt.Sprintf("Nesting depth error: max depth=%v", d.MaxDepth),
+					offset:  dec.InputOffset() - 1,
+				}
+			}
+			f := &frame{object: tok == json.Delim('{'), expectKey: true}
+			if t := jsonDecodedType(valueType); t != nil {
+				switch t.Kind() {
+				case reflect.Struct, reflect.Map:
+					if f.object {
+						f.typ = t
+					}
+				case reflect.Slice, reflect.Array:
+					if !f.object {
+						f.typ = t
+						f.valueType = t.Elem()
+					}
+				}
+			}
+			if f.object && d.DisallowDuplicateKeys {
+				f.keys = map[string]struct{}{}
+			}
+			stack = append(stack, f)
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			return nil // only the first value is checked, trailing data is handled by DisallowTrailingData
+		}
+		stack[len(stack)-1].expectKey = true
+	}
+}
+
+// jsonField is struct field as seen by encoding/json.
+type jsonField struct {
+	name  string
+	typ   reflect.Type
+	depth int
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	jsonFieldsCache     sync.Map // reflect.Type -> []jsonField
+)
+
+// jsonDecodedType returns type the value is decoded into by encoding/json with pointers dereferenced. Returns nil when
+// the type is not known or the type decodes itself with custom unmarshaler.
+func jsonDecodedType(t reflect.Type) reflect.Type {
+	for t != nil {
+		if t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType) {
+			return nil
+		}
+		if t.Kind() != reflect.Pointer {
+			if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+				return nil
+			}
+			if t.Kind() == reflect.Interface {
+				return nil
+			}
+			return t
+		}
+		t = t.Elem()
+	}
+	return nil
+}
+
+// lookupJSONField finds field for the object key. Exact match is preferred over case-insensitive match like
+// encoding/json does.
+func lookupJSONField(fields []jsonField, key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func cachedJSONFields(typ reflect.Type) []jsonField {
+	if cached, ok := jsonFieldsCache.Load(typ); ok {
+		return cached.([]jsonField)
+	}
+	fields := appendJSONFields(nil, typ, 0, map[reflect.Type]bool{})
+
+	// fields hidden by fields with the same name at shallower depth are not decoded by encoding/json, neither are
+	// fields with the same name at the same depth
+	depths := make(map[string]int, len(fields))
+	counts := make(map[string]int, len(fields))
+	for _, f := range fields {
+		if d, ok := depths[f.name]; !ok || f.depth < d {
+			depths[f.name] = f.depth
+			counts[f.name] = 1
+		} else if f.depth == d {
+			counts[f.name]++
+		}
+	}
+	result := make([]jsonField, 0, len(fields))
+	for _, f := range fields {
+		if f.depth == depths[f.name] && counts[f.name] == 1 {
+			result = append(result, f)
+		}
+	}
+	jsonFieldsCache.Store(typ, result)
+	return result
+}
+
+// appendJSONFields adds fields of the struct type. Fields of embedded structs without JSON name are added as fields of
+// the parent struct.
+func appendJSONFields(fields []jsonField, typ reflect.Type, depth int, visited map[reflect.Type]bool) []jsonField {
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			t := sf.Type
+			if t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			if t.Kind() == reflect.Struct {
+				if !visited[t] {
+					fields = appendJSONFields(fields, t, depth+1, visited)
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: sf.Type, depth: depth})
+	}
+	return fields
+}
+
+// keyOffset returns offset of the opening quote of the object key which is read by the decoder starting at given offset.
+// Decoder offset points after the previous token so it could be followed by whitespace and a comma.
+func keyOffset(b []byte, offset int64) int64 {
+	for offset < int64(len(b)) && b[offset] != '"' {
+		offset++
+	}
+	return offset
+}
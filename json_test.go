@@ -4,6 +4,7 @@
 package echo
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -98,3 +99,170 @@ func TestDefaultJSONCodec_Decode(t *testing.T) {
 	assert.EqualError(t, err, "code=400, message=Unmarshal type error: expected=string, got=number, field=id, offset=7, internal=json: cannot unmarshal number into Go struct field .id of type string")
 
 }
+
+func TestDefaultJSONSerializer_DeserializeOptions(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		given       DefaultJSONSerializer
+		whenBody    string
+		expect      interface{}
+		expectCode  int
+		expectError string
+	}{
+		{
+			name:     "ok, all options with valid body",
+			given:    DefaultJSONSerializer{DisallowUnknownFields: true, MaxBodySize: 100, MaxDepth: 2, DisallowDuplicateKeys: true, DisallowTrailingData: true},
+			whenBody: ` {"id": 1, "name": "Jon Snow"} ` + "\n",
+			expect:   map[string]interface{}{"id": float64(1), "name": "Jon Snow"},
+		},
+		{
+			name:     "ok, duplicate keys in different objects",
+			given:    DefaultJSONSerializer{DisallowDuplicateKeys: true},
+			whenBody: `{"a": {"a": 1}, "b": [{"a": 1}, {"a": 2}]}`,
+			expect: map[string]interface{}{
+				"a": map[string]interface{}{"a": float64(1)},
+				"b": []interface{}{map[string]interface{}{"a": float64(1)}, map[string]interface{}{"a": float64(2)}},
+			},
+		},
+		{
+			name:     "ok, use number",
+			given:    DefaultJSONSerializer{UseNumber: true},
+			whenBody: `{"id": 12345678901234567890}`,
+			expect:   map[string]interface{}{"id": json.Number("12345678901234567890")},
+		},
+		{
+			name:     "ok, trailing data is allowed by default",
+			whenBody: `{"id": 1} {"id": 2}`,
+			expect:   map[string]interface{}{"id": float64(1)},
+		},
+		{
+			name:        "nok, unknown field",
+			given:       DefaultJSONSerializer{DisallowUnknownFields: true},
+			whenBody:    `{"id": 1, "email": "jon@example.com"}`,
+			expect:      &user{},
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Unknown field error: field=email: offset=10, internal=Unknown field error: field=email: offset=10",
+		},
+		{
+			name:        "nok, unknown field in nested struct",
+			given:       DefaultJSONSerializer{DisallowUnknownFields: true},
+			whenBody:    `{"users": [{"id": 1}, {"ID": 2, "email": "jon@example.com"}]}`,
+			expect:      &struct{ Users []*user }{},
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Unknown field error: field=email: offset=32, internal=Unknown field error: field=email: offset=32",
+		},
+		{
+			name:        "nok, duplicate key matching the same field case-insensitively",
+			given:       DefaultJSONSerializer{DisallowDuplicateKeys: true},
+			whenBody:    `{"id": 1, "ID": 2}`,
+			expect:      &user{},
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Duplicate key error: key=ID: offset=10, internal=Duplicate key error: key=ID: offset=10",
+		},
+		{
+			name:        "nok, body too large",
+			given:       DefaultJSONSerializer{MaxBodySize: 10},
+			whenBody:    `{"id": 1, "name": "Jon Snow"}`,
+			expectCode:  http.StatusRequestEntityTooLarge,
+			expectError: "code=413, message=Request body too large: limit=10 bytes, internal=http: request body too large",
+		},
+		{
+			name:        "nok, body too large with buffered checks",
+			given:       DefaultJSONSerializer{MaxBodySize: 10, MaxDepth: 10},
+			whenBody:    `{"id": 1, "name": "Jon Snow"}`,
+			expectCode:  http.StatusRequestEntityTooLarge,
+			expectError: "code=413, message=Request body too large: limit=10 bytes, internal=http: request body too large",
+		},
+		{
+			name:        "nok, max depth",
+			given:       DefaultJSONSerializer{MaxDepth: 2},
+			whenBody:    `{"a": [1, {"b": 2}]}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Nesting depth error: max depth=2: offset=10, internal=Nesting depth error: max depth=2: offset=10",
+		},
+		{
+			name:        "nok, duplicate key",
+			given:       DefaultJSONSerializer{DisallowDuplicateKeys: true},
+			whenBody:    `{"id": 1, "nested": {"x": 1}, "id": 2}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Duplicate key error: key=id: offset=30, internal=Duplicate key error: key=id: offset=30",
+		},
+		{
+			name:        "nok, syntax error found by structural checks",
+			given:       DefaultJSONSerializer{DisallowDuplicateKeys: true},
+			whenBody:    `{"id": 1,}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Syntax error: offset=10, error=invalid character '}' looking for beginning of object key string, internal=invalid character '}' looking for beginning of object key string",
+		},
+		{
+			name:        "nok, trailing data",
+			given:       DefaultJSONSerializer{DisallowTrailingData: true},
+			whenBody:    `{"id": 1} {"id": 2}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Trailing data error: offset=9",
+		},
+		{
+			name:        "nok, trailing garbage",
+			given:       DefaultJSONSerializer{DisallowTrailingData: true},
+			whenBody:    `{"id": 1}}`,
+			expectCode:  http.StatusBadRequest,
+			expectError: "code=400, message=Trailing data error: offset=9, internal=invalid character '}' looking for beginning of value",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.whenBody))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var result interface{}
+			dest := interface{}(&result)
+			if tc.expect != nil && tc.expectError != "" {
+				dest = tc.expect
+			}
+			err := tc.given.Deserialize(c, dest)
+
+			if tc.expectError != "" {
+				var he *HTTPError
+				if assert.ErrorAs(t, err, &he) {
+					assert.Equal(t, tc.expectCode, he.Code)
+				}
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
+
+func TestDefaultJSONSerializer_DeserializeStructKeys(t *testing.T) {
+	type audit struct {
+		CreatedBy string `json:"created_by"`
+	}
+	type request struct {
+		audit
+		ID     int               `json:"id"`
+		Labels map[string]string `json:"labels"`
+		Raw    json.RawMessage   `json:"raw"`
+		Any    interface{}       `json:"any"`
+	}
+	body := `{"ID": 1, "created_by": "jon", "labels": {"a": "1", "A": "2"}, "raw": {"x": 1, "x": 2}, "any": {"y": 1}}`
+
+	e := New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), httptest.NewRecorder())
+	var result request
+	err := DefaultJSONSerializer{DisallowUnknownFields: true, DisallowDuplicateKeys: true}.Deserialize(c, &result)
+
+	assert.EqualError(t, err, "code=400, message=Duplicate key error: key=x: offset=79, internal=Duplicate key error: key=x: offset=79")
+
+	c = e.NewContext(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Replace(body, `"x": 2`, `"z": 2`, 1))), httptest.NewRecorder())
+	err = DefaultJSONSerializer{DisallowUnknownFields: true, DisallowDuplicateKeys: true}.Deserialize(c, &result)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ID)
+	assert.Equal(t, "jon", result.CreatedBy)
+	assert.Equal(t, map[string]string{"a": "1", "A": "2"}, result.Labels)
+	assert.Equal(t, map[string]interface{}{"y": float64(1)}, result.Any)
+}
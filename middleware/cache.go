@@ -0,0 +1,612 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CacheConfig defines the config for Cache middleware.
+type CacheConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Store persists cached responses.
+	// Optional. Default value memory store with 64MB size limit.
+	Store CacheStore
+
+	// KeyFunc returns the cache key of the request. Requests with the same key share cached responses (different
+	// variants are still selected by the `Vary` response header).
+	// Optional. Default value is request host and URI. Route path alone is not used as default key because requests
+	// to the same route with different path parameters, query or virtual host have different responses. Responses of
+	// a route can be invalidated with `CacheInvalidateRoute`.
+	KeyFunc func(c echo.Context) string
+
+	// DefaultMaxAge is freshness lifetime of responses that do not have explicit lifetime (`Cache-Control` max-age,
+	// s-maxage or `Expires` header).
+	// Optional. Default value 0, which means that such responses are not stored.
+	DefaultMaxAge time.Duration
+
+	// StaleWhileRevalidate is time after the response has become stale when it is still served while being
+	// revalidated in the background. `stale-while-revalidate` directive of the response takes precedence.
+	// Optional. Default value 0.
+	StaleWhileRevalidate time.Duration
+
+	// MaxEntrySize is maximum size of the response body (in bytes) that is stored.
+	// Optional. Default value 1MB.
+	MaxEntrySize int
+}
+
+// DefaultCacheConfig is the default Cache middleware config.
+var DefaultCacheConfig = CacheConfig{
+	Skipper:      DefaultSkipper,
+	KeyFunc:      defaultCacheKey,
+	MaxEntrySize: 1024 * 1024,
+}
+
+// cacheContextKey is key of the cache state in the context used by CacheTags and CacheInvalidate functions.
+const cacheContextKey = "_cache"
+
+// cacheableStatuses are response status codes that are cacheable by default.
+// See RFC 9110 section 15.1: https://www.rfc-editor.org/rfc/rfc9110#section-15.1
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Cache returns a middleware which stores responses of GET requests and serves them for GET and HEAD requests as a
+// shared cache would (RFC 9111).
+//
+// Responses are stored only when they have explicit freshness lifetime (`s-maxage`, `max-age` or `Expires`) or
+// DefaultMaxAge is configured and they are not marked `no-store`, `no-cache` or `private`, do not set cookies and do
+// not vary on all headers (`Vary: *`). Responses to requests with `Authorization` header are stored only when response
+// explicitly allows it (`public`, `s-maxage` or `must-revalidate`). Requests with `Cache-Control: no-store` bypass the
+// cache and `no-cache` or `max-age=0` requests are served by the handler and refresh the cache.
+//
+// Concurrent requests for the same cold entry are coalesced: only one of them executes the handler while others wait
+// for the result. Stale entries within the stale-while-revalidate window are served immediately and refreshed in the
+// background. Background revalidation runs the handler (and middlewares registered after Cache) with a copy of the
+// request and without values set into the context by the middlewares registered before Cache.
+//
+// Successful unsafe requests (POST, PUT, PATCH, DELETE) invalidate responses stored for the same key. Handlers can tag
+// responses with `CacheTags` and invalidate them with `CacheInvalidateTags` or `CacheInvalidateRoute`.
+//
+// Example:
+//
+//	e.Use(middleware.Cache())
+//	e.GET("/products/:id", func(c echo.Context) error {
+//		middleware.CacheTags(c, "products")
+//		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=60, stale-while-revalidate=30")
+//		return c.JSON(http.StatusOK, product)
+//	}).Name = "product"
+//	e.PUT("/catalog", func(c echo.Context) error {
+//		// ...
+//		return middleware.CacheInvalidateTags(c, "products")
+//	})
+func Cache() echo.MiddlewareFunc {
+	return CacheWithConfig(DefaultCacheConfig)
+}
+
+// CacheWithConfig returns a Cache middleware with config.
+// See: `Cache()`.
+func CacheWithConfig(config CacheConfig) echo.MiddlewareFunc {
+	return newResponseCache(config).middleware
+}
+
+type responseCache struct {
+	config CacheConfig
+	bpool  sync.Pool
+
+	mutex   sync.Mutex
+	flights map[string]*cacheFlight
+
+	timeNow func() time.Time
+}
+
+// cacheFlight is request filling or revalidating the cache entry. Other requests with the same key wait for it.
+type cacheFlight struct {
+	done chan struct{}
+}
+
+// cacheState is stored into the context for CacheTags and CacheInvalidate functions.
+type cacheState struct {
+	cache *responseCache
+	tags  []string
+}
+
+func newResponseCache(config CacheConfig) *responseCache {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCacheConfig.Skipper
+	}
+	if config.Store == nil {
+		config.Store = NewCacheMemoryStore(64 * 1024 * 1024)
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = DefaultCacheConfig.KeyFunc
+	}
+	if config.MaxEntrySize <= 0 {
+		config.MaxEntrySize = DefaultCacheConfig.MaxEntrySize
+	}
+	return &responseCache{
+		config:  config,
+		bpool:   bufferPool(),
+		flights: map[string]*cacheFlight{},
+		timeNow: time.Now,
+	}
+}
+
+func defaultCacheKey(c echo.Context) string {
+	return c.Request().Host + c.Request().URL.RequestURI()
+}
+
+// CacheTags adds tags to the response stored by Cache middleware. Tagged responses can be invalidated with
+// `CacheInvalidateTags`.
+func CacheTags(c echo.Context, tags ...string) {
+	if state, ok := c.Get(cacheContextKey).(*cacheState); ok {
+		state.tags = append(state.tags, tags...)
+	}
+}
+
+// CacheInvalidateTags removes responses stored by Cache middleware with any of the given tags.
+func CacheInvalidateTags(c echo.Context, tags ...string) error {
+	state, ok := c.Get(cacheContextKey).(*cacheState)
+	if !ok {
+		return errors.New("echo: cache middleware is not used for the request")
+	}
+	return state.cache.config.Store.Invalidate(tags...)
+}
+
+// CacheInvalidateRoute removes all responses stored by Cache middleware for the route with given name.
+func CacheInvalidateRoute(c echo.Context, name string) error {
+	var tags []string
+	for _, r := range c.Echo().Routes() {
+		if r.Name == name {
+			tags = append(tags, cacheRouteTag(r.Path))
+		}
+	}
+	if len(tags) == 0 {
+		return errors.New("echo: route not found: " + name)
+	}
+	return CacheInvalidateTags(c, tags...)
+}
+
+func cacheRouteTag(path string) string {
+	return "route:" + path
+}
+
+func cacheKeyTag(key string) string {
+	return "key:" + key
+}
+
+func (rc *responseCache) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if rc.config.Skipper(c) {
+			return next(c)
+		}
+		state := &cacheState{cache: rc}
+		c.Set(cacheContextKey, state)
+
+		req := c.Request()
+		key := rc.config.KeyFunc(c)
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			err := next(c)
+			if err == nil && req.Method != http.MethodOptions && req.Method != http.MethodTrace && c.Response().Status < http.StatusBadRequest {
+				// RFC 9111 section 4.4: unsafe request invalidates responses stored for the target URI
+				if iErr := rc.config.Store.Invalidate(cacheKeyTag(key)); iErr != nil {
+					c.Logger().Error(iErr)
+				}
+			}
+			return err
+		}
+
+		directives := parseCacheControl(req.Header.Values(echo.HeaderCacheControl))
+		if _, ok := directives["no-store"]; ok {
+			return next(c)
+		}
+		maxAge := -1
+		if v, ok := directives["max-age"]; ok {
+			maxAge = parseCacheSeconds(v)
+		}
+		_, noCache := directives["no-cache"]
+		if len(directives) == 0 && strings.Contains(strings.ToLower(req.Header.Get(echo.HeaderPragma)), "no-cache") {
+			noCache = true
+		}
+		if noCache || maxAge == 0 {
+			// client requires response from the origin, it is stored for the next requests
+			return rc.fill(c, next, key, state)
+		}
+
+		served, err := rc.serveStored(c, next, key, maxAge)
+		if served || err != nil {
+			return err
+		}
+		if req.Method == http.MethodHead {
+			return next(c) // HEAD responses have no body and can not be used for GET requests
+		}
+
+		// coalesce concurrent requests for the same cold entry
+		flight, leader := rc.join(key)
+		if !leader {
+			select {
+			case <-flight.done:
+			case <-req.Context().Done():
+				return req.Context().Err()
+			}
+			if served, err := rc.serveStored(c, next, key, maxAge); served || err != nil {
+				return err
+			}
+			return rc.fill(c, next, key, state)
+		}
+		defer rc.leave(key, flight)
+		// entry could have been stored by another request between lookup and join
+		if served, err := rc.serveStored(c, next, key, maxAge); served || err != nil {
+			return err
+		}
+		return rc.fill(c, next, key, state)
+	}
+}
+
+// serveStored writes stored response for the request. Returns false when there is no suitable stored response.
+func (rc *responseCache) serveStored(c echo.Context, next echo.HandlerFunc, key string, maxAge int) (bool, error) {
+	entry, err := rc.lookup(key, c.Request())
+	if err != nil {
+		c.Logger().Error(err)
+		return false, nil
+	}
+	if entry == nil {
+		return false, nil
+	}
+	now := rc.timeNow()
+	age := now.Sub(entry.StoredAt)
+	if maxAge >= 0 && age > time.Duration(maxAge)*time.Second {
+		return false, nil
+	}
+	if !now.Before(entry.ExpiresAt) {
+		if !now.Before(entry.StaleUntil) {
+			return false, nil
+		}
+		rc.revalidate(c, next, key)
+	}
+	return true, rc.serve(c, entry, age)
+}
+
+// lookup returns stored response for the request or nil when there is none.
+func (rc *responseCache) lookup(key string, req *http.Request) (*CacheEntry, error) {
+	entry, err := rc.config.Store.Get(key)
+	if err == nil && entry.Vary != nil {
+		entry, err = rc.config.Store.Get(cacheVariantKey(key, entry.Vary, req))
+	}
+	if errors.Is(err, ErrCacheMiss) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+func (rc *responseCache) serve(c echo.Context, entry *CacheEntry, age time.Duration) error {
+	req := c.Request()
+	res := c.Response()
+	header := res.Header()
+	for k, v := range entry.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(echo.HeaderAge, strconv.FormatInt(int64(age/time.Second), 10))
+
+	if entry.Status == http.StatusOK {
+		etag := header.Get(echo.HeaderETag)
+		var modTime time.Time
+		if lm := header.Get(echo.HeaderLastModified); lm != "" {
+			modTime, _ = http.ParseTime(lm)
+		}
+		switch echo.EvaluatePreconditions(req, etag, modTime) {
+		case http.StatusNotModified:
+			echo.WriteNotModified(res)
+			return nil
+		case http.StatusPreconditionFailed:
+			header.Del(echo.HeaderContentType)
+			header.Del(echo.HeaderContentLength)
+			res.WriteHeader(http.StatusPreconditionFailed)
+			return nil
+		}
+	}
+
+	res.WriteHeader(entry.Status)
+	if req.Method == http.MethodHead {
+		return nil
+	}
+	_, err := res.Write(entry.Body)
+	return err
+}
+
+func (rc *responseCache) join(key string) (*cacheFlight, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if flight, ok := rc.flights[key]; ok {
+		return flight, false
+	}
+	flight := &cacheFlight{done: make(chan struct{})}
+	rc.flights[key] = flight
+	return flight, true
+}
+
+func (rc *responseCache) leave(key string, flight *cacheFlight) {
+	rc.mutex.Lock()
+	delete(rc.flights, key)
+	rc.mutex.Unlock()
+	close(flight.done)
+}
+
+// fill executes the handler, sends the response to the client and stores it when it is cacheable.
+func (rc *responseCache) fill(c echo.Context, next echo.HandlerFunc, key string, state *cacheState) error {
+	res := c.Response()
+	buf := rc.bpool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer rc.bpool.Put(buf)
+
+	rw := res.Writer
+	crw := &cacheResponseWriter{ResponseWriter: rw, buffer: buf, maxSize: rc.config.MaxEntrySize}
+	res.Writer = crw
+	err := next(c)
+	res.Writer = rw
+	if err != nil || crw.tooLarge || crw.hijacked || !res.Committed {
+		return err
+	}
+	if c.Request().Method == http.MethodGet {
+		rc.store(c, key, res.Status, res.Header(), buf.Bytes(), state.tags)
+	}
+	return nil
+}
+
+// revalidate refreshes the stale entry in the background. Only one revalidation per key is run at a time.
+func (rc *responseCache) revalidate(c echo.Context, next echo.HandlerFunc, key string) {
+	flight, leader := rc.join(key)
+	if !leader {
+		return
+	}
+
+	req := c.Request().Clone(context.Background())
+	for _, h := range []string{echo.HeaderIfNoneMatch, echo.HeaderIfModifiedSince, echo.HeaderIfMatch, echo.HeaderIfUnmodifiedSince, echo.HeaderIfRange, echo.HeaderRange} {
+		req.Header.Del(h)
+	}
+	recorder := &cacheRecorder{header: http.Header{}}
+	bc := c.Echo().NewContext(req, recorder)
+	bc.SetPath(c.Path())
+	bc.SetParamNames(append([]string(nil), c.ParamNames()...)...)
+	bc.SetParamValues(append([]string(nil), c.ParamValues()...)...)
+	state := &cacheState{cache: rc}
+	bc.Set(cacheContextKey, state)
+
+	go func() {
+		defer rc.leave(key, flight)
+		// there is no Recover middleware up the stack of this goroutine, panic would crash the whole server
+		defer func() {
+			if r := recover(); r != nil {
+				bc.Logger().Errorf("[PANIC RECOVER] cache revalidation of %s: %v %s", key, r, debug.Stack())
+			}
+		}()
+		if err := next(bc); err != nil {
+			bc.Logger().Error(err)
+			return
+		}
+		if !bc.Response().Committed || recorder.body.Len() > rc.config.MaxEntrySize {
+			return
+		}
+		rc.store(bc, key, bc.Response().Status, recorder.header, recorder.body.Bytes(), state.tags)
+	}()
+}
+
+// store saves the response when it is cacheable.
+func (rc *responseCache) store(c echo.Context, key string, status int, header http.Header, body []byte, tags []string) {
+	now := rc.timeNow()
+	entry := rc.newEntry(c.Request(), status, header, body, now)
+	if entry == nil {
+		return
+	}
+	entry.Tags = append(append([]string(nil), tags...), cacheKeyTag(key), cacheRouteTag(c.Path()))
+
+	var err error
+	vary := cacheVaryHeaders(header)
+	if len(vary) == 0 {
+		err = rc.config.Store.Set(key, entry)
+	} else {
+		marker := &CacheEntry{StoredAt: now, ExpiresAt: entry.ExpiresAt, StaleUntil: entry.StaleUntil, Vary: vary, Tags: entry.Tags}
+		if err = rc.config.Store.Set(key, marker); err == nil {
+			err = rc.config.Store.Set(cacheVariantKey(key, vary, c.Request()), entry)
+		}
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// newEntry returns cache entry for the response or nil when response must not be stored.
+// See RFC 9111 section 3: https://www.rfc-editor.org/rfc/rfc9111#section-3
+func (rc *responseCache) newEntry(req *http.Request, status int, header http.Header, body []byte, now time.Time) *CacheEntry {
+	if !cacheableStatuses[status] || header.Get(echo.HeaderSetCookie) != "" {
+		return nil
+	}
+	directives := parseCacheControl(header.Values(echo.HeaderCacheControl))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return nil
+		}
+	}
+	for _, v := range cacheVaryHeaders(header) {
+		if v == "*" {
+			return nil
+		}
+	}
+	_, public := directives["public"]
+	_, mustRevalidate := directives["must-revalidate"]
+	_, proxyRevalidate := directives["proxy-revalidate"]
+	sMaxAge, hasSMaxAge := directives["s-maxage"]
+	if req.Header.Get(echo.HeaderAuthorization) != "" && !public && !hasSMaxAge && !mustRevalidate {
+		return nil
+	}
+
+	var lifetime time.Duration
+	if maxAge, ok := directives["max-age"]; hasSMaxAge || ok {
+		if hasSMaxAge {
+			maxAge = sMaxAge
+		}
+		lifetime = time.Duration(parseCacheSeconds(maxAge)) * time.Second
+	} else if expires := header.Get(echo.HeaderExpires); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return nil // invalid date means already expired
+		}
+		lifetime = t.Sub(now)
+	} else {
+		lifetime = rc.config.DefaultMaxAge
+	}
+	if lifetime <= 0 {
+		return nil
+	}
+
+	stale := rc.config.StaleWhileRevalidate
+	if v, ok := directives["stale-while-revalidate"]; ok {
+		stale = time.Duration(parseCacheSeconds(v)) * time.Second
+	}
+	if mustRevalidate || proxyRevalidate {
+		stale = 0
+	}
+
+	h := header.Clone()
+	h.Del(echo.HeaderAge)
+	return &CacheEntry{
+		Status:     status,
+		Header:     h,
+		Body:       append([]byte(nil), body...),
+		StoredAt:   now,
+		ExpiresAt:  now.Add(lifetime),
+		StaleUntil: now.Add(lifetime + stale),
+	}
+}
+
+// parseCacheControl parses `Cache-Control` header values into map of lower cased directives and their values.
+func parseCacheControl(values []string) map[string]string {
+	directives := map[string]string{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			if k == "" {
+				continue
+			}
+			directives[k] = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return directives
+}
+
+// parseCacheSeconds parses delta-seconds value. Invalid values are treated as 0.
+func parseCacheSeconds(v string) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// cacheVaryHeaders returns sorted canonical header names from `Vary` response header.
+func cacheVaryHeaders(header http.Header) []string {
+	var names []string
+	for _, v := range header.Values(echo.HeaderVary) {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cacheVariantKey returns key of the response variant selected by the request header values.
+func cacheVariantKey(key string, vary []string, req *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(key)
+	for _, name := range vary {
+		sb.WriteString("\x00")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(strings.Join(req.Header.Values(name), ","))
+	}
+	return sb.String()
+}
+
+// cacheResponseWriter sends the response to the client and keeps copy of the body for storing.
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	buffer   *bytes.Buffer
+	maxSize  int
+	tooLarge bool
+	hijacked bool
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if !w.tooLarge {
+		if w.buffer.Len()+n > w.maxSize {
+			w.tooLarge = true
+			w.buffer.Reset()
+		} else {
+			w.buffer.Write(b[:n])
+		}
+	}
+	return n, err
+}
+
+func (w *cacheResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *cacheResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *cacheResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cacheRecorder is response writer for background revalidation.
+type cacheRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *cacheRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *cacheRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+// WriteHeader does nothing as the status code is recorded by echo.Response.
+func (r *cacheRecorder) WriteHeader(_ int) {
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CacheStore is the interface to be implemented by response cache stores.
+type CacheStore interface {
+	// Get returns entry stored with the key. Returns ErrCacheMiss when entry does not exist or can not be served
+	// anymore (current time is after StaleUntil).
+	Get(key string) (*CacheEntry, error)
+	// Set stores the entry with the key replacing existing entry. Entry can be removed from the store after StaleUntil.
+	Set(key string, entry *CacheEntry) error
+	// Invalidate removes all entries that have any of the given tags.
+	Invalidate(tags ...string) error
+}
+
+// CacheEntry is response stored by Cache middleware. Stores must not modify entries as they are shared between
+// requests.
+type CacheEntry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// StoredAt is time when the response was stored. It is used to calculate `Age` header value.
+	StoredAt time.Time
+	// ExpiresAt is time until the entry is fresh and can be served without revalidation.
+	ExpiresAt time.Time
+	// StaleUntil is time until the stale entry can be served while it is revalidated in the background.
+	StaleUntil time.Time
+
+	// Vary is set for entries that only record request headers the response varies on. Actual responses are stored
+	// with keys derived from the values of these headers.
+	Vary []string
+	// Tags are used to invalidate entries. Entries are always tagged with their route and cache key.
+	Tags []string
+}
+
+// ErrCacheMiss is returned by CacheStore when entry does not exist.
+var ErrCacheMiss = errors.New("cache miss")
+
+// CacheMemoryStore is CacheStore keeping entries in the server memory. When total size of stored entries exceeds the
+// size limit the least recently used entries are removed.
+type CacheMemoryStore struct {
+	mutex   sync.Mutex
+	maxSize int64
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{}
+
+	timeNow func() time.Time
+}
+
+type cacheMemoryItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// NewCacheMemoryStore returns new CacheMemoryStore which keeps at most maxSize bytes of responses in memory.
+func NewCacheMemoryStore(maxSize int64) *CacheMemoryStore {
+	if maxSize <= 0 {
+		panic("echo: cache memory store size must be greater than zero")
+	}
+	return &CacheMemoryStore{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		tags:    map[string]map[string]struct{}{},
+		timeNow: time.Now,
+	}
+}
+
+// Get implements CacheStore.Get
+func (s *CacheMemoryStore) Get(key string) (*CacheEntry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	item := elem.Value.(*cacheMemoryItem)
+	if !s.timeNow().Before(item.entry.StaleUntil) {
+		s.remove(elem)
+		return nil, ErrCacheMiss
+	}
+	s.lru.MoveToFront(elem)
+	return item.entry, nil
+}
+
+// Set implements CacheStore.Set
+func (s *CacheMemoryStore) Set(key string, entry *CacheEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	item := &cacheMemoryItem{key: key, entry: entry, size: cacheEntrySize(key, entry)}
+	if item.size > s.maxSize {
+		return nil // entry would evict everything else and still not fit
+	}
+	s.entries[key] = s.lru.PushFront(item)
+	s.size += item.size
+	for _, tag := range entry.Tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for s.size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Invalidate implements CacheStore.Invalidate
+func (s *CacheMemoryStore) Invalidate(tags ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if elem, ok := s.entries[key]; ok {
+				s.remove(elem)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *CacheMemoryStore) remove(elem *list.Element) {
+	item := s.lru.Remove(elem).(*cacheMemoryItem)
+	delete(s.entries, item.key)
+	s.size -= item.size
+	for _, tag := range item.entry.Tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
+
+// cacheEntrySize returns approximate memory usage of the entry.
+func cacheEntrySize(key string, entry *CacheEntry) int64 {
+	size := int64(len(key) + len(entry.Body))
+	for k, values := range entry.Header {
+		size += int64(len(k))
+		for _, v := range values {
+			size += int64(len(v))
+		}
+	}
+	for _, v := range entry.Vary {
+		size += int64(len(v))
+	}
+	for _, tag := range entry.Tags {
+		size += int64(len(tag))
+	}
+	return size
+}
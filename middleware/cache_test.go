@@ -0,0 +1,451 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheTestServer struct {
+	e     *echo.Echo
+	cache *responseCache
+	now   time.Time
+	calls int32
+}
+
+// newCacheTestServer creates echo instance with Cache middleware and route `/` responding with number of handler calls
+// and headers from query params `cc` (Cache-Control), `vary` and `cookie`.
+func newCacheTestServer(config CacheConfig) *cacheTestServer {
+	s := &cacheTestServer{e: echo.New(), now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s.cache = newResponseCache(config)
+	s.cache.timeNow = func() time.Time { return s.now }
+	if store, ok := s.cache.config.Store.(*CacheMemoryStore); ok {
+		store.timeNow = s.cache.timeNow
+	}
+	s.e.Use(s.cache.middleware)
+	s.e.Match([]string{http.MethodGet, http.MethodHead}, "/", func(c echo.Context) error {
+		n := atomic.AddInt32(&s.calls, 1)
+		h := c.Response().Header()
+		if cc := c.QueryParam("cc"); cc != "" {
+			h.Set(echo.HeaderCacheControl, cc)
+		}
+		if vary := c.QueryParam("vary"); vary != "" {
+			h.Set(echo.HeaderVary, vary)
+		}
+		if c.QueryParam("cookie") != "" {
+			h.Set(echo.HeaderSetCookie, "a=b")
+		}
+		if expires := c.QueryParam("expires"); expires != "" {
+			h.Set(echo.HeaderExpires, expires)
+		}
+		h.Set(echo.HeaderETag, `"v`+strconv.Itoa(int(n))+`"`)
+		return c.String(http.StatusOK, "call "+strconv.Itoa(int(n))+" "+c.Request().Header.Get("Accept-Language"))
+	})
+	return s
+}
+
+func (s *cacheTestServer) do(method string, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCache(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		givenConfig    CacheConfig
+		givenTarget    string
+		givenHeader    http.Header
+		givenElapsed   time.Duration
+		expectCalls    int32
+		expectAgeOnHit string
+	}{
+		{
+			name:           "ok, max-age response is cached",
+			givenTarget:    "/?cc=max-age=60",
+			givenElapsed:   10 * time.Second,
+			expectCalls:    1,
+			expectAgeOnHit: "10",
+		},
+		{
+			name:         "ok, expired response is not served",
+			givenTarget:  "/?cc=max-age=60",
+			givenElapsed: 60 * time.Second,
+			expectCalls:  2,
+		},
+		{
+			name:           "ok, s-maxage takes precedence over max-age",
+			givenTarget:    "/?cc=max-age=1,s-maxage=60",
+			givenElapsed:   30 * time.Second,
+			expectCalls:    1,
+			expectAgeOnHit: "30",
+		},
+		{
+			name:           "ok, expires header",
+			givenTarget:    "/?expires=Wed,%2001%20May%202024%2013:00:00%20GMT",
+			givenElapsed:   time.Minute,
+			expectCalls:    1,
+			expectAgeOnHit: "60",
+		},
+		{
+			name:        "ok, response without freshness is not stored",
+			givenTarget: "/",
+			expectCalls: 2,
+		},
+		{
+			name:           "ok, default max age",
+			givenConfig:    CacheConfig{DefaultMaxAge: time.Minute},
+			givenTarget:    "/",
+			expectCalls:    1,
+			expectAgeOnHit: "0",
+		},
+		{
+			name:        "ok, no-store response",
+			givenTarget: "/?cc=no-store,max-age=60",
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, private response",
+			givenTarget: "/?cc=private,max-age=60",
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, no-cache response",
+			givenTarget: "/?cc=no-cache,max-age=60",
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, response setting cookie",
+			givenTarget: "/?cc=max-age=60&cookie=1",
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, vary on all headers",
+			givenTarget: "/?cc=max-age=60&vary=*",
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, request with authorization",
+			givenTarget: "/?cc=max-age=60",
+			givenHeader: http.Header{echo.HeaderAuthorization: {"Basic YTpi"}},
+			expectCalls: 2,
+		},
+		{
+			name:           "ok, public response to request with authorization",
+			givenTarget:    "/?cc=public,max-age=60",
+			givenHeader:    http.Header{echo.HeaderAuthorization: {"Basic YTpi"}},
+			expectCalls:    1,
+			expectAgeOnHit: "0",
+		},
+		{
+			name:        "ok, request no-store bypasses cache",
+			givenTarget: "/?cc=max-age=60",
+			givenHeader: http.Header{echo.HeaderCacheControl: {"no-store"}},
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, request no-cache is served by handler",
+			givenTarget: "/?cc=max-age=60",
+			givenHeader: http.Header{echo.HeaderCacheControl: {"no-cache"}},
+			expectCalls: 2,
+		},
+		{
+			name:        "ok, pragma no-cache",
+			givenTarget: "/?cc=max-age=60",
+			givenHeader: http.Header{echo.HeaderPragma: {"no-cache"}},
+			expectCalls: 2,
+		},
+		{
+			name:         "ok, request max-age older than stored response",
+			givenTarget:  "/?cc=max-age=60",
+			givenHeader:  http.Header{echo.HeaderCacheControl: {"max-age=5"}},
+			givenElapsed: 10 * time.Second,
+			expectCalls:  2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newCacheTestServer(tc.givenConfig)
+
+			first := s.do(http.MethodGet, tc.givenTarget, tc.givenHeader)
+			s.now = s.now.Add(tc.givenElapsed)
+			second := s.do(http.MethodGet, tc.givenTarget, tc.givenHeader)
+
+			assert.Equal(t, http.StatusOK, second.Code)
+			assert.Equal(t, tc.expectCalls, atomic.LoadInt32(&s.calls))
+			if tc.expectAgeOnHit != "" {
+				assert.Equal(t, first.Body.String(), second.Body.String())
+				assert.Equal(t, tc.expectAgeOnHit, second.Header().Get(echo.HeaderAge))
+				assert.Equal(t, first.Header().Get(echo.HeaderETag), second.Header().Get(echo.HeaderETag))
+			} else {
+				assert.Empty(t, second.Header().Get(echo.HeaderAge))
+			}
+		})
+	}
+}
+
+func TestCache_vary(t *testing.T) {
+	s := newCacheTestServer(CacheConfig{})
+	target := "/?cc=max-age=60&vary=accept-language"
+	en := http.Header{"Accept-Language": {"en"}}
+	et := http.Header{"Accept-Language": {"et"}}
+
+	assert.Equal(t, "call 1 en", s.do(http.MethodGet, target, en).Body.String())
+	assert.Equal(t, "call 2 et", s.do(http.MethodGet, target, et).Body.String())
+	assert.Equal(t, "call 1 en", s.do(http.MethodGet, target, en).Body.String())
+	assert.Equal(t, "call 2 et", s.do(http.MethodGet, target, et).Body.String())
+	assert.Equal(t, "call 3 ", s.do(http.MethodGet, target, nil).Body.String())
+}
+
+func TestCache_headAndConditional(t *testing.T) {
+	s := newCacheTestServer(CacheConfig{})
+	target := "/?cc=max-age=60"
+
+	// HEAD responses are not stored
+	rec := s.do(http.MethodHead, target, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	s.do(http.MethodGet, target, nil)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&s.calls))
+
+	// HEAD is served from stored GET response
+	rec = s.do(http.MethodHead, target, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get(echo.HeaderAge))
+	assert.Empty(t, rec.Body.String())
+
+	rec = s.do(http.MethodGet, target, http.Header{echo.HeaderIfNoneMatch: {`"v2"`}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&s.calls))
+}
+
+func TestCache_staleWhileRevalidate(t *testing.T) {
+	s := newCacheTestServer(CacheConfig{})
+	target := "/?cc=max-age=60,stale-while-revalidate=30"
+
+	assert.Equal(t, "call 1 ", s.do(http.MethodGet, target, nil).Body.String())
+
+	// stale response is served and revalidated in the background
+	s.now = s.now.Add(70 * time.Second)
+	rec := s.do(http.MethodGet, target, nil)
+	assert.Equal(t, "call 1 ", rec.Body.String())
+	assert.Equal(t, "70", rec.Header().Get(echo.HeaderAge))
+	assert.Eventually(t, func() bool {
+		entry, err := s.cache.config.Store.Get(defaultCacheTestKey)
+		return err == nil && entry.StoredAt.Equal(s.now)
+	}, time.Second, time.Millisecond)
+
+	rec = s.do(http.MethodGet, target, nil)
+	assert.Equal(t, "call 2 ", rec.Body.String())
+	assert.Equal(t, "0", rec.Header().Get(echo.HeaderAge))
+
+	// response past stale-while-revalidate window is not served
+	s.now = s.now.Add(91 * time.Second)
+	assert.Equal(t, "call 3 ", s.do(http.MethodGet, target, nil).Body.String())
+
+	// must-revalidate disables serving stale responses
+	target = "/?cc=max-age=60,stale-while-revalidate=30,must-revalidate"
+	s.do(http.MethodGet, target, nil)
+	s.now = s.now.Add(70 * time.Second)
+	assert.Equal(t, "call 5 ", s.do(http.MethodGet, target, nil).Body.String())
+}
+
+func TestCache_revalidationPanic(t *testing.T) {
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	var calls int32
+	cache := newResponseCache(CacheConfig{})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.timeNow = func() time.Time { return now }
+	cache.config.Store.(*CacheMemoryStore).timeNow = cache.timeNow
+	e.Use(cache.middleware)
+	e.GET("/", func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			panic("revalidation failed")
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, "max-age=60,stale-while-revalidate=30")
+		return c.String(http.StatusOK, "ok")
+	})
+	do := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	assert.Equal(t, "ok", do().Body.String())
+	now = now.Add(70 * time.Second)
+	assert.Equal(t, "ok", do().Body.String())
+
+	// panic in the background revalidation is recovered and the key can be revalidated again
+	assert.Eventually(t, func() bool {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+		return atomic.LoadInt32(&calls) == 2 && len(cache.flights) == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "ok", do().Body.String())
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 3 }, time.Second, time.Millisecond)
+}
+
+const defaultCacheTestKey = "example.com/?cc=max-age=60,stale-while-revalidate=30"
+
+func TestCache_coalescing(t *testing.T) {
+	e := echo.New()
+	var calls int32
+	release := make(chan struct{})
+	e.Use(Cache())
+	e.GET("/", func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.Response().Header().Set(echo.HeaderCacheControl, "max-age=60")
+		return c.String(http.StatusOK, "ok")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, "ok", rec.Body.String())
+		}()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // give other requests time to reach the handler if coalescing does not work
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCache_invalidation(t *testing.T) {
+	e := echo.New()
+	var calls int32
+	e.Use(Cache())
+	e.GET("/products/:id", func(c echo.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		CacheTags(c, "products")
+		c.Response().Header().Set(echo.HeaderCacheControl, "max-age=60")
+		return c.String(http.StatusOK, c.Param("id")+" "+strconv.Itoa(int(n)))
+	}).Name = "product"
+	e.POST("/products/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.POST("/invalidate/:by", func(c echo.Context) error {
+		if c.Param("by") == "route" {
+			return CacheInvalidateRoute(c, "product")
+		}
+		return CacheInvalidateTags(c, "products")
+	})
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+	post := func(target string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, "1 1", get("/products/1"))
+	assert.Equal(t, "2 2", get("/products/2"))
+	assert.Equal(t, "1 1", get("/products/1"))
+
+	// unsafe request invalidates the same URI
+	assert.Equal(t, http.StatusNoContent, post("/products/1"))
+	assert.Equal(t, "1 3", get("/products/1"))
+	assert.Equal(t, "2 2", get("/products/2"))
+
+	assert.Equal(t, http.StatusOK, post("/invalidate/tag"))
+	assert.Equal(t, "1 4", get("/products/1"))
+	assert.Equal(t, "2 5", get("/products/2"))
+
+	assert.Equal(t, http.StatusOK, post("/invalidate/route"))
+	assert.Equal(t, "1 6", get("/products/1"))
+	assert.Equal(t, "2 7", get("/products/2"))
+}
+
+func TestCache_maxEntrySize(t *testing.T) {
+	e := echo.New()
+	var calls int32
+	e.Use(CacheWithConfig(CacheConfig{MaxEntrySize: 10}))
+	e.GET("/", func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		c.Response().Header().Set(echo.HeaderCacheControl, "max-age=60")
+		return c.String(http.StatusOK, strings.Repeat("x", 11))
+	})
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, strings.Repeat("x", 11), rec.Body.String())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCacheInvalidateTags_withoutMiddleware(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.EqualError(t, CacheInvalidateTags(c, "a"), "echo: cache middleware is not used for the request")
+	assert.EqualError(t, CacheInvalidateRoute(c, "a"), "echo: route not found: a")
+}
+
+func TestCacheMemoryStore(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewCacheMemoryStore(30)
+	store.timeNow = func() time.Time { return now }
+	entry := func(body string, tags ...string) *CacheEntry {
+		return &CacheEntry{Body: []byte(body), StaleUntil: now.Add(time.Minute), Tags: tags}
+	}
+
+	assert.NoError(t, store.Set("a", entry("1234567890", "t1")))
+	assert.NoError(t, store.Set("b", entry("1234567890", "t2")))
+	_, err := store.Get("a") // a is now most recently used
+	assert.NoError(t, err)
+
+	// least recently used entry is evicted when size limit is exceeded
+	assert.NoError(t, store.Set("c", entry("1234567890", "t1")))
+	_, err = store.Get("b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, int64(26), store.size)
+
+	// entry larger than the store is not stored
+	assert.NoError(t, store.Set("d", entry(strings.Repeat("x", 30))))
+	_, err = store.Get("d")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, store.Invalidate("t1"))
+	_, err = store.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = store.Get("c")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, int64(0), store.size)
+	assert.Empty(t, store.tags)
+
+	assert.NoError(t, store.Set("e", entry("x")))
+	now = now.Add(time.Minute)
+	_, err = store.Get("e")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Empty(t, store.entries)
+
+	assert.PanicsWithValue(t, "echo: cache memory store size must be greater than zero", func() {
+		NewCacheMemoryStore(0)
+	})
+}
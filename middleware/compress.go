@@ -6,6 +6,7 @@ package middleware
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
 	"io"
 	"net"
@@ -39,17 +40,6 @@ type GzipConfig struct {
 	MinLength int
 }
 
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-	wroteHeader       bool
-	wroteBody         bool
-	minLength         int
-	minLengthExceeded bool
-	buffer            *bytes.Buffer
-	code              int
-}
-
 const (
 	gzipScheme = "gzip"
 )
@@ -81,7 +71,7 @@ func GzipWithConfig(config GzipConfig) echo.MiddlewareFunc {
 		config.MinLength = DefaultGzipConfig.MinLength
 	}
 
-	pool := gzipCompressPool(config)
+	pool := compressWriterPool(GzipEncoder(config.Level))
 	bpool := bufferPool()
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -90,128 +80,406 @@ func GzipWithConfig(config GzipConfig) echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			res := c.Response()
-			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			echo.AddVary(c.Response().Header(), echo.HeaderAcceptEncoding)
 			if strings.Contains(c.Request().Header.Get(echo.HeaderAcceptEncoding), gzipScheme) {
-				i := pool.Get()
-				w, ok := i.(*gzip.Writer)
-				if !ok {
-					return echo.NewHTTPError(http.StatusInternalServerError, i.(error).Error())
-				}
-				rw := res.Writer
-				w.Reset(rw)
-
-				buf := bpool.Get().(*bytes.Buffer)
-				buf.Reset()
-
-				grw := &gzipResponseWriter{Writer: w, ResponseWriter: rw, minLength: config.MinLength, buffer: buf}
-				defer func() {
-					// There are different reasons for cases when we have not yet written response to the client and now need to do so.
-					// a) handler response had only response code and no response body (ala 404 or redirects etc). Response code need to be written now.
-					// b) body is shorter than our minimum length threshold and being buffered currently and needs to be written
-					if !grw.wroteBody {
-						if res.Header().Get(echo.HeaderContentEncoding) == gzipScheme {
-							res.Header().Del(echo.HeaderContentEncoding)
-						}
-						if grw.wroteHeader {
-							rw.WriteHeader(grw.code)
-						}
-						// We have to reset response to it's pristine state when
-						// nothing is written to body or error is returned.
-						// See issue #424, #407.
-						res.Writer = rw
-						w.Reset(io.Discard)
-					} else if !grw.minLengthExceeded {
-						// Write uncompressed response
-						res.Writer = rw
-						if grw.wroteHeader {
-							grw.ResponseWriter.WriteHeader(grw.code)
-						}
-						grw.buffer.WriteTo(rw)
-						w.Reset(io.Discard)
-					}
-					w.Close()
-					bpool.Put(buf)
-					pool.Put(w)
-				}()
-				res.Writer = grw
+				return compressResponse(c, next, gzipScheme, &pool, &bpool, config.MinLength, nil)
 			}
 			return next(c)
 		}
 	}
 }
 
-func (w *gzipResponseWriter) WriteHeader(code int) {
-	w.Header().Del(echo.HeaderContentLength) // Issue #444
+// CompressConfig defines the config for Compress middleware.
+type CompressConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Encoders are content codings the response can be compressed with, in order of server preference which is used
+	// when client accepts multiple codings with the same q-value. Custom encoders (i.e. brotli, zstd) can be added
+	// with CompressEncoder.
+	// Optional. Default value gzip and deflate with default compression level.
+	Encoders []CompressEncoder
+
+	// Length threshold before compression is applied. Shorter responses are sent uncompressed.
+	// Optional. Default value 0.
+	MinLength int
+
+	// ContentTypes is list of media types that are compressed. Wildcard subtype (`text/*`) matches all subtypes.
+	// Optional. Default value empty which means that all media types, except ExcludedContentTypes, are compressed.
+	ContentTypes []string
+
+	// ExcludedContentTypes is list of media types that are never compressed. Wildcard subtype (`video/*`) matches
+	// all subtypes.
+	// Optional. Default value DefaultCompressExcludedContentTypes.
+	ExcludedContentTypes []string
+}
+
+// CompressEncoder is content coding used by Compress middleware.
+type CompressEncoder struct {
+	// Encoding is content coding name used in `Accept-Encoding` and `Content-Encoding` headers, i.e. "br".
+	Encoding string
+
+	// NewWriter returns writer compressing data written into w. Writers are pooled and reused with Reset.
+	NewWriter func(w io.Writer) (CompressWriter, error)
+}
+
+// CompressWriter is the interface implemented by compressing writers (`*gzip.Writer`, `*flate.Writer`, brotli and zstd
+// writers from popular libraries).
+type CompressWriter interface {
+	io.WriteCloser
+	// Flush writes any pending data to the underlying writer.
+	Flush() error
+	// Reset discards writer state and makes it write into w.
+	Reset(w io.Writer)
+}
+
+const (
+	deflateScheme  = "deflate"
+	identityScheme = "identity"
+)
+
+// GzipEncoder returns gzip CompressEncoder with given compression level.
+func GzipEncoder(level int) CompressEncoder {
+	return CompressEncoder{
+		Encoding: gzipScheme,
+		NewWriter: func(w io.Writer) (CompressWriter, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+	}
+}
 
+// DeflateEncoder returns deflate CompressEncoder with given compression level.
+func DeflateEncoder(level int) CompressEncoder {
+	return CompressEncoder{
+		Encoding: deflateScheme,
+		NewWriter: func(w io.Writer) (CompressWriter, error) {
+			return flate.NewWriter(w, level)
+		},
+	}
+}
+
+// DefaultCompressExcludedContentTypes are media types that are already compressed and gain nothing from compression.
+var DefaultCompressExcludedContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+}
+
+// DefaultCompressConfig is the default Compress middleware config.
+var DefaultCompressConfig = CompressConfig{
+	Skipper:              DefaultSkipper,
+	Encoders:             []CompressEncoder{GzipEncoder(gzip.DefaultCompression), DeflateEncoder(flate.DefaultCompression)},
+	MinLength:            0,
+	ExcludedContentTypes: DefaultCompressExcludedContentTypes,
+}
+
+// Compress returns a middleware which compresses HTTP response with the best content coding accepted by the client.
+// Content coding is negotiated using q-values of the `Accept-Encoding` header. When client does not accept any of the
+// encoders nor uncompressed response (`identity;q=0`) the "406 Not Acceptable" error is returned.
+func Compress() echo.MiddlewareFunc {
+	return CompressWithConfig(DefaultCompressConfig)
+}
+
+// CompressWithConfig returns Compress middleware with config.
+// See: `Compress()`.
+func CompressWithConfig(config CompressConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCompressConfig.Skipper
+	}
+	if len(config.Encoders) == 0 {
+		config.Encoders = DefaultCompressConfig.Encoders
+	}
+	if config.MinLength < 0 {
+		config.MinLength = DefaultCompressConfig.MinLength
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = DefaultCompressConfig.ExcludedContentTypes
+	}
+
+	encodings := make([]string, 0, len(config.Encoders))
+	pools := make(map[string]*sync.Pool, len(config.Encoders))
+	for _, encoder := range config.Encoders {
+		if encoder.Encoding == "" || encoder.NewWriter == nil {
+			panic("echo: compress middleware encoder requires encoding and NewWriter")
+		}
+		name := strings.ToLower(encoder.Encoding)
+		pool := compressWriterPool(encoder)
+		pools[name] = &pool
+		encodings = append(encodings, name)
+	}
+	bpool := bufferPool()
+
+	skip := func(header http.Header) bool {
+		if header.Get(echo.HeaderContentRange) != "" {
+			return true // compressing part of the representation would break range
+		}
+		for _, v := range header.Values(echo.HeaderCacheControl) {
+			if strings.Contains(strings.ToLower(v), "no-transform") {
+				return true
+			}
+		}
+		mediaType, _, _ := strings.Cut(header.Get(echo.HeaderContentType), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if len(config.ContentTypes) > 0 && !matchMediaType(mediaType, config.ContentTypes) {
+			return true
+		}
+		return matchMediaType(mediaType, config.ExcludedContentTypes)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			echo.AddVary(c.Response().Header(), echo.HeaderAcceptEncoding)
+			encoding, acceptable := negotiateCompression(c.Request().Header.Get(echo.HeaderAcceptEncoding), encodings)
+			if !acceptable {
+				return echo.ErrNotAcceptable
+			}
+			if encoding == "" {
+				return next(c)
+			}
+			return compressResponse(c, next, encoding, pools[encoding], &bpool, config.MinLength, skip)
+		}
+	}
+}
+
+// negotiateCompression selects content coding for the response from encodings. Empty encoding means that response is
+// sent uncompressed, which is the case also when client prefers identity over the encodings. Returns false when neither
+// the encodings nor the uncompressed response are acceptable.
+// See RFC 9110 section 12.5.3: https://www.rfc-editor.org/rfc/rfc9110#section-12.5.3
+func negotiateCompression(acceptEncoding string, encodings []string) (string, bool) {
+	if acceptEncoding == "" {
+		return "", true
+	}
+	accepted := echo.ParseAcceptEncoding(acceptEncoding)
+	identityQ, ok := accepted[identityScheme]
+	if !ok {
+		if identityQ, ok = accepted["*"]; !ok {
+			identityQ = 1 // identity is always acceptable unless explicitly excluded
+		}
+	}
+
+	encoding := echo.NegotiateEncoding(acceptEncoding, encodings...)
+	if encoding == "" {
+		return "", identityQ > 0
+	}
+	q, ok := accepted[encoding]
+	if !ok {
+		q = accepted["*"]
+	}
+	if q < identityQ {
+		return "", true
+	}
+	return encoding, true
+}
+
+// matchMediaType checks if media type matches any of the patterns. Pattern with wildcard subtype (`text/*`) matches
+// all subtypes of the type.
+func matchMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType || pattern == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// compressResponse compresses response of the handler with writer from the pool. Response is written uncompressed when
+// it is shorter than minLength, is already encoded by the handler or skip returns true for response headers.
+func compressResponse(c echo.Context, next echo.HandlerFunc, encoding string, pool *sync.Pool, bpool *sync.Pool, minLength int, skip func(header http.Header) bool) error {
+	res := c.Response()
+	i := pool.Get()
+	w, ok := i.(CompressWriter)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, i.(error).Error())
+	}
+	rw := res.Writer
+	w.Reset(rw)
+
+	buf := bpool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	crw := &compressResponseWriter{writer: w, ResponseWriter: rw, encoding: encoding, minLength: minLength, buffer: buf, skip: skip}
+	defer func() {
+		// There are different reasons for cases when we have not yet written response to the client and now need to do so.
+		// a) handler response had only response code and no response body (ala 404 or redirects etc). Response code need to be written now.
+		// b) body is shorter than our minimum length threshold and being buffered currently and needs to be written
+		if crw.passThrough {
+			// response was already encoded by the handler or must not be compressed and has been written as is
+			res.Writer = rw
+			w.Reset(io.Discard)
+		} else if !crw.wroteBody {
+			if res.Header().Get(echo.HeaderContentEncoding) == encoding {
+				res.Header().Del(echo.HeaderContentEncoding)
+			}
+			if crw.wroteHeader {
+				rw.WriteHeader(crw.code)
+			}
+			// We have to reset response to it's pristine state when
+			// nothing is written to body or error is returned.
+			// See issue #424, #407.
+			res.Writer = rw
+			w.Reset(io.Discard)
+		} else if !crw.minLengthExceeded {
+			// Write uncompressed response
+			res.Writer = rw
+			if crw.wroteHeader {
+				crw.ResponseWriter.WriteHeader(crw.code)
+			}
+			crw.buffer.WriteTo(rw)
+			w.Reset(io.Discard)
+		}
+		w.Close()
+		bpool.Put(buf)
+		pool.Put(w)
+	}()
+	res.Writer = crw
+	return next(c)
+}
+
+type compressResponseWriter struct {
+	http.ResponseWriter
+	writer            CompressWriter
+	encoding          string
+	skip              func(header http.Header) bool
+	wroteHeader       bool
+	wroteBody         bool
+	minLength         int
+	minLengthExceeded bool
+	passThrough       bool
+	buffer            *bytes.Buffer
+	code              int
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
 	w.wroteHeader = true
+	if w.passThrough || w.isEncodedByHandler() {
+		w.passThrough = true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.Header().Del(echo.HeaderContentLength) // Issue #444
 
 	// Delay writing of the header until we know if we'll actually compress the response
 	w.code = code
 }
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+// isEncodedByHandler checks if response has been already encoded (for example precompressed static file is served) and
+// must not be compressed again.
+func (w *compressResponseWriter) isEncodedByHandler() bool {
+	return !w.wroteBody && w.buffer.Len() == 0 && w.Header().Get(echo.HeaderContentEncoding) != ""
+}
+
+// startPassThrough writes response as is when it must not be compressed.
+func (w *compressResponseWriter) startPassThrough() {
+	w.passThrough = true
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.passThrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.isEncodedByHandler() {
+		w.startPassThrough()
+		return w.ResponseWriter.Write(b)
+	}
 	if w.Header().Get(echo.HeaderContentType) == "" {
 		w.Header().Set(echo.HeaderContentType, http.DetectContentType(b))
 	}
+	if !w.wroteBody && w.skip != nil && w.skip(w.Header()) {
+		w.startPassThrough()
+		return w.ResponseWriter.Write(b)
+	}
 	w.wroteBody = true
 
 	if !w.minLengthExceeded {
 		n, err := w.buffer.Write(b)
 
 		if w.buffer.Len() >= w.minLength {
-			w.minLengthExceeded = true
-
-			// The minimum length is exceeded, add Content-Encoding header and write the header
-			w.Header().Set(echo.HeaderContentEncoding, gzipScheme) // Issue #806
-			if w.wroteHeader {
-				w.ResponseWriter.WriteHeader(w.code)
-			}
-
-			return w.Writer.Write(w.buffer.Bytes())
+			w.startCompression()
+			return w.writer.Write(w.buffer.Bytes())
 		}
 
 		return n, err
 	}
 
-	return w.Writer.Write(b)
+	return w.writer.Write(b)
 }
 
-func (w *gzipResponseWriter) Flush() {
+// startCompression adds Content-Encoding header and writes the header.
+func (w *compressResponseWriter) startCompression() {
+	w.minLengthExceeded = true
+	w.Header().Set(echo.HeaderContentEncoding, w.encoding) // Issue #806
+	if etag := w.Header().Get(echo.HeaderETag); strings.HasPrefix(etag, `"`) {
+		// compressed representation is not byte-for-byte identical so strong entity tag is turned into a weak one.
+		// Weak comparison is used for `If-None-Match` so conditional requests keep working.
+		w.Header().Set(echo.HeaderETag, "W/"+etag)
+	}
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.passThrough && !w.wroteBody && w.skip != nil && w.Header().Get(echo.HeaderContentType) != "" && w.skip(w.Header()) {
+		w.startPassThrough()
+	}
+	if w.passThrough {
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+		return
+	}
 	if !w.minLengthExceeded {
 		// Enforce compression because we will not know how much more data will come
-		w.minLengthExceeded = true
-		w.Header().Set(echo.HeaderContentEncoding, gzipScheme) // Issue #806
-		if w.wroteHeader {
-			w.ResponseWriter.WriteHeader(w.code)
-		}
-
-		w.Writer.Write(w.buffer.Bytes())
+		w.startCompression()
+		w.writer.Write(w.buffer.Bytes())
 	}
 
-	w.Writer.(*gzip.Writer).Flush()
+	w.writer.Flush()
 	_ = http.NewResponseController(w.ResponseWriter).Flush()
 }
 
-func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
 	return w.ResponseWriter
 }
 
-func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return http.NewResponseController(w.ResponseWriter).Hijack()
 }
 
-func (w *gzipResponseWriter) Push(target string, opts *http.PushOptions) error {
+func (w *compressResponseWriter) Push(target string, opts *http.PushOptions) error {
 	if p, ok := w.ResponseWriter.(http.Pusher); ok {
 		return p.Push(target, opts)
 	}
 	return http.ErrNotSupported
 }
 
-func gzipCompressPool(config GzipConfig) sync.Pool {
+// compressWriterPool returns pool of writers of the encoder. Pool returns error when writer can not be created.
+func compressWriterPool(encoder CompressEncoder) sync.Pool {
 	return sync.Pool{
 		New: func() interface{} {
-			w, err := gzip.NewWriterLevel(io.Discard, config.Level)
+			w, err := encoder.NewWriter(io.Discard)
 			if err != nil {
 				return err
 			}
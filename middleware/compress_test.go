@@ -5,12 +5,15 @@ package middleware
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -316,7 +319,7 @@ func TestGzipWithStatic(t *testing.T) {
 
 func TestGzipResponseWriter_CanUnwrap(t *testing.T) {
 	trwu := &testResponseWriterUnwrapper{rw: httptest.NewRecorder()}
-	bdrw := gzipResponseWriter{
+	bdrw := compressResponseWriter{
 		ResponseWriter: trwu,
 	}
 
@@ -326,7 +329,7 @@ func TestGzipResponseWriter_CanUnwrap(t *testing.T) {
 
 func TestGzipResponseWriter_CanHijack(t *testing.T) {
 	trwu := testResponseWriterUnwrapperHijack{testResponseWriterUnwrapper: testResponseWriterUnwrapper{rw: httptest.NewRecorder()}}
-	bdrw := gzipResponseWriter{
+	bdrw := compressResponseWriter{
 		ResponseWriter: &trwu, // this RW supports hijacking through unwrapping
 	}
 
@@ -336,7 +339,7 @@ func TestGzipResponseWriter_CanHijack(t *testing.T) {
 
 func TestGzipResponseWriter_CanNotHijack(t *testing.T) {
 	trwu := testResponseWriterUnwrapper{rw: httptest.NewRecorder()}
-	bdrw := gzipResponseWriter{
+	bdrw := compressResponseWriter{
 		ResponseWriter: &trwu, // this RW supports hijacking through unwrapping
 	}
 
@@ -381,7 +384,7 @@ func (m *mockPusher) Push(target string, opts *http.PushOptions) error {
 	return m.err
 }
 
-// TestGzipResponseWriter_Push tests the Push method of the gzipResponseWriter type.
+// TestGzipResponseWriter_Push tests the Push method of the compressResponseWriter type.
 // It verifies two cases:
 // 1. When the underlying ResponseWriter implements the http.Pusher interface,
 //    it checks that the Push method is called without error.
@@ -393,7 +396,7 @@ func TestGzipResponseWriter_Push_WithPusher(t *testing.T) {
 
 	// Case 1: ResponseWriter implements http.Pusher
 	mock := &mockPusher{} // Implements Pusher
-	w := &gzipResponseWriter{ResponseWriter: mock}
+	w := &compressResponseWriter{ResponseWriter: mock}
 	err := w.Push(target, opts)
 
 	if err != nil {
@@ -410,10 +413,218 @@ func TestGzipResponseWriter_Push_WithoutPusher(t *testing.T) {
 
 	// Case 2: ResponseWriter does not implement http.Pusher
 	nonPusher := httptest.NewRecorder() // Does not implement Pusher
-	w := &gzipResponseWriter{ResponseWriter: nonPusher}
+	w := &compressResponseWriter{ResponseWriter: nonPusher}
 	err := w.Push(target, opts)
 
 	if err != http.ErrNotSupported {
 		t.Errorf("expected error %v, got %v", http.ErrNotSupported, err)
 	}
 }
+
+func TestGzip_DoesNotCompressEncodedResponse(t *testing.T) {
+	e := echo.New()
+	e.Use(Gzip())
+	e.Use(StaticWithConfig(StaticConfig{
+		Filesystem: http.FS(fstest.MapFS{
+			"app.js":    {Data: []byte("console.log('raw')")},
+			"app.js.br": {Data: []byte("BR")},
+		}),
+		Precompressed: true,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip, br")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "br", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, []string{echo.HeaderAcceptEncoding}, rec.Header().Values(echo.HeaderVary))
+	assert.Equal(t, "BR", rec.Body.String())
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		givenAccept      string
+		expectEncoding   string
+		expectAcceptable bool
+	}{
+		{name: "no header", givenAccept: "", expectEncoding: "", expectAcceptable: true},
+		{name: "gzip", givenAccept: "gzip", expectEncoding: "gzip", expectAcceptable: true},
+		{name: "server preference on tie", givenAccept: "deflate, gzip", expectEncoding: "gzip", expectAcceptable: true},
+		{name: "higher q-value wins", givenAccept: "gzip;q=0.5, deflate", expectEncoding: "deflate", expectAcceptable: true},
+		{name: "gzip excluded", givenAccept: "gzip;q=0, deflate", expectEncoding: "deflate", expectAcceptable: true},
+		{name: "wildcard", givenAccept: "*", expectEncoding: "gzip", expectAcceptable: true},
+		{name: "unsupported coding", givenAccept: "br", expectEncoding: "", expectAcceptable: true},
+		{name: "identity preferred", givenAccept: "gzip;q=0.5, identity", expectEncoding: "", expectAcceptable: true},
+		{name: "identity excluded", givenAccept: "br, identity;q=0", expectEncoding: "", expectAcceptable: false},
+		{name: "wildcard excludes identity", givenAccept: "br, *;q=0", expectEncoding: "", expectAcceptable: false},
+		{name: "identity not acceptable but gzip is", givenAccept: "gzip;q=0.3, identity;q=0", expectEncoding: "gzip", expectAcceptable: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoding, acceptable := negotiateCompression(tc.givenAccept, []string{"gzip", "deflate"})
+			assert.Equal(t, tc.expectEncoding, encoding)
+			assert.Equal(t, tc.expectAcceptable, acceptable)
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello world ", 100)
+
+	var testCases = []struct {
+		name               string
+		givenConfig        CompressConfig
+		givenAccept        string
+		givenContentType   string
+		givenCacheControl  string
+		expectCode         int
+		expectEncoding     string
+		expectUncompressed bool
+	}{
+		{
+			name:           "ok, gzip",
+			givenAccept:    "gzip, deflate",
+			expectCode:     http.StatusOK,
+			expectEncoding: "gzip",
+		},
+		{
+			name:           "ok, deflate by q-value",
+			givenAccept:    "gzip;q=0.8, deflate",
+			expectCode:     http.StatusOK,
+			expectEncoding: "deflate",
+		},
+		{
+			name:               "ok, no accept encoding",
+			expectCode:         http.StatusOK,
+			expectUncompressed: true,
+		},
+		{
+			name:               "ok, excluded content type",
+			givenAccept:        "gzip",
+			givenContentType:   "image/png",
+			expectCode:         http.StatusOK,
+			expectUncompressed: true,
+		},
+		{
+			name:               "ok, not in allowed content types",
+			givenConfig:        CompressConfig{ContentTypes: []string{"application/json", "text/*"}},
+			givenAccept:        "gzip",
+			givenContentType:   "application/xml",
+			expectCode:         http.StatusOK,
+			expectUncompressed: true,
+		},
+		{
+			name:             "ok, wildcard allowed content type",
+			givenConfig:      CompressConfig{ContentTypes: []string{"application/json", "text/*"}},
+			givenAccept:      "gzip",
+			givenContentType: "text/html; charset=UTF-8",
+			expectCode:       http.StatusOK,
+			expectEncoding:   "gzip",
+		},
+		{
+			name:               "ok, no-transform",
+			givenAccept:        "gzip",
+			givenCacheControl:  "public, no-transform",
+			expectCode:         http.StatusOK,
+			expectUncompressed: true,
+		},
+		{
+			name:               "ok, shorter than min length",
+			givenConfig:        CompressConfig{MinLength: 2048},
+			givenAccept:        "gzip",
+			expectCode:         http.StatusOK,
+			expectUncompressed: true,
+		},
+		{
+			name:        "nok, identity not acceptable",
+			givenAccept: "br, identity;q=0",
+			expectCode:  http.StatusNotAcceptable,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(CompressWithConfig(tc.givenConfig))
+			e.GET("/", func(c echo.Context) error {
+				if tc.givenCacheControl != "" {
+					c.Response().Header().Set(echo.HeaderCacheControl, tc.givenCacheControl)
+				}
+				contentType := tc.givenContentType
+				if contentType == "" {
+					contentType = echo.MIMETextPlainCharsetUTF8
+				}
+				return c.Blob(http.StatusOK, contentType, []byte(body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.givenAccept != "" {
+				req.Header.Set(echo.HeaderAcceptEncoding, tc.givenAccept)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			assert.Equal(t, []string{echo.HeaderAcceptEncoding}, rec.Header().Values(echo.HeaderVary))
+			assert.Equal(t, tc.expectEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			if tc.expectUncompressed {
+				assert.Equal(t, body, rec.Body.String())
+			}
+			if tc.expectEncoding == "" {
+				return
+			}
+			var r io.Reader
+			if tc.expectEncoding == "gzip" {
+				gr, err := gzip.NewReader(rec.Body)
+				assert.NoError(t, err)
+				r = gr
+			} else {
+				r = flate.NewReader(rec.Body)
+			}
+			b, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, body, string(b))
+		})
+	}
+}
+
+// testUpperWriter is writer of custom encoder standing in for codings like brotli or zstd that are not in standard library
+type testUpperWriter struct {
+	w io.Writer
+}
+
+func (u *testUpperWriter) Write(b []byte) (int, error) { return u.w.Write(bytes.ToUpper(b)) }
+func (u *testUpperWriter) Close() error                { return nil }
+func (u *testUpperWriter) Flush() error                { return nil }
+func (u *testUpperWriter) Reset(w io.Writer)           { u.w = w }
+
+func TestCompressWithConfig_customEncoder(t *testing.T) {
+	e := echo.New()
+	e.Use(CompressWithConfig(CompressConfig{
+		Encoders: []CompressEncoder{
+			{Encoding: "upper", NewWriter: func(w io.Writer) (CompressWriter, error) { return &testUpperWriter{w: w}, nil }},
+			GzipEncoder(gzip.BestSpeed),
+		},
+	}))
+	e.GET("/", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderETag, `"abc"`)
+		return c.String(http.StatusOK, "test")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip, upper")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, "upper", rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, `W/"abc"`, rec.Header().Get(echo.HeaderETag))
+	assert.Equal(t, "TEST", rec.Body.String())
+}
+
+func TestCompressWithConfig_panics(t *testing.T) {
+	assert.PanicsWithValue(t, "echo: compress middleware encoder requires encoding and NewWriter", func() {
+		CompressWithConfig(CompressConfig{Encoders: []CompressEncoder{{Encoding: "br"}}})
+	})
+}
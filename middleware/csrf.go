@@ -63,6 +63,11 @@ type CSRFConfig struct {
 	// Optional. Default value SameSiteDefaultMode.
 	CookieSameSite http.SameSite `yaml:"cookie_same_site"`
 
+	// UseSession stores CSRF token in the session instead of the CSRF cookie. Token is then bound to the session and
+	// is rotated when session ID is regenerated. Session middleware must be executed before CSRF middleware.
+	// Optional. Default value false.
+	UseSession bool `yaml:"use_session"`
+
 	// ErrorHandler defines a function which is executed for returning custom errors.
 	ErrorHandler CSRFErrorHandler
 }
@@ -73,6 +78,10 @@ type CSRFErrorHandler func(err error, c echo.Context) error
 // ErrCSRFInvalid is returned when CSRF check fails
 var ErrCSRFInvalid = echo.NewHTTPError(http.StatusForbidden, "invalid csrf token")
 
+// ErrCSRFSessionMissing is returned when UseSession is set but there is no session in the context because Session
+// middleware is not executed before CSRF middleware.
+var ErrCSRFSessionMissing = echo.NewHTTPError(http.StatusInternalServerError, "csrf middleware requires session middleware when UseSession is set")
+
 // DefaultCSRFConfig is the default CSRF middleware config.
 var DefaultCSRFConfig = CSRFConfig{
 	Skipper:        DefaultSkipper,
@@ -130,7 +139,13 @@ func CSRFWithConfig(config CSRFConfig) echo.MiddlewareFunc {
 			}
 
 			token := ""
-			if k, err := c.Cookie(config.CookieName); err != nil {
+			if config.UseSession {
+				sess := GetSession(c)
+				if sess == nil {
+					return ErrCSRFSessionMissing
+				}
+				token = sess.csrfToken(c, config.ContextKey, config.TokenLength)
+			} else if k, err := c.Cookie(config.CookieName); err != nil {
 				token = randomString(config.TokenLength)
 			} else {
 				token = k.Value // Reuse token
@@ -184,6 +199,13 @@ func CSRFWithConfig(config CSRFConfig) echo.MiddlewareFunc {
 				}
 			}
 
+			// Store token in the context
+			c.Set(config.ContextKey, token)
+
+			if config.UseSession {
+				return next(c) // session middleware takes care of the cookie and Vary header
+			}
+
 			// Set CSRF cookie
 			cookie := new(http.Cookie)
 			cookie.Name = config.CookieName
@@ -202,9 +224,6 @@ func CSRFWithConfig(config CSRFConfig) echo.MiddlewareFunc {
 			cookie.HttpOnly = config.CookieHTTPOnly
 			c.SetCookie(cookie)
 
-			// Store token in the context
-			c.Set(config.ContextKey, token)
-
 			// Protect clients from caching the response
 			c.Response().Header().Add(echo.HeaderVary, echo.HeaderCookie)
 
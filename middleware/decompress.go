@@ -4,12 +4,18 @@
 package middleware
 
 import (
+	"bufio"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/bytes"
 )
 
 // DecompressConfig defines the config for Decompress middleware.
@@ -19,11 +25,35 @@ type DecompressConfig struct {
 
 	// GzipDecompressPool defines an interface to provide the sync.Pool used to create/store Gzip readers
 	GzipDecompressPool Decompressor
+
+	// MaxDecompressedSize is maximum allowed size of the decompressed request body, it can be specified as `4x` or
+	// `4xB`, where x is one of the multiple from K, M, G, T or P. Reading more results "413 - Request Entity Too Large"
+	// error. Note that `BodyLimit` middleware limits only the compressed size of the body.
+	// Optional. Default value "" (no limit).
+	MaxDecompressedSize string `yaml:"max_decompressed_size"`
+	maxDecompressedSize int64
+
+	// MaxRatio is maximum allowed ratio of decompressed and compressed body size. Reading more results
+	// "413 - Request Entity Too Large" error. Ratio is enforced only after 64KB of the body is decompressed so that
+	// small well compressible bodies are not rejected. Protects against decompression bombs (small compressed body
+	// expanding into huge payload) without limiting the size of legitimate bodies.
+	// Optional. Default value 0 (no limit).
+	MaxRatio float64 `yaml:"max_ratio"`
 }
 
 // GZIPEncoding content-encoding header if set to "gzip", decompress body contents.
 const GZIPEncoding string = "gzip"
 
+// DeflateEncoding content-encoding header if set to "deflate", decompress body contents.
+const DeflateEncoding string = "deflate"
+
+// decompressMaxCodings is maximum number of stacked content codings. Every coding allocates a decompressor so the
+// number is limited to prevent resource exhaustion with long `Content-Encoding` header.
+const decompressMaxCodings = 4
+
+// decompressRatioMinSize is size of the decompressed body after which MaxRatio is enforced.
+const decompressRatioMinSize = 64 * 1024
+
 // Decompressor is used to get the sync.Pool used by the middleware to get Gzip readers
 type Decompressor interface {
 	gzipDecompressPool() sync.Pool
@@ -43,12 +73,16 @@ func (d *DefaultGzipDecompressPool) gzipDecompressPool() sync.Pool {
 	return sync.Pool{New: func() interface{} { return new(gzip.Reader) }}
 }
 
-// Decompress decompresses request body based if content encoding type is set to "gzip" with default config
+// Decompress decompresses request body based if content encoding type is set to "gzip" or "deflate" with default
+// config. Stacked encodings (i.e. "gzip, deflate") are decoded in reverse order of application. Requests with
+// encodings the middleware does not support are passed to the handler as is.
 func Decompress() echo.MiddlewareFunc {
 	return DecompressWithConfig(DefaultDecompressConfig)
 }
 
-// DecompressWithConfig decompresses request body based if content encoding type is set to "gzip" with config
+// DecompressWithConfig decompresses request body based if content encoding type is set to "gzip" or "deflate" with
+// config.
+// See: `Decompress()`.
 func DecompressWithConfig(config DecompressConfig) echo.MiddlewareFunc {
 	// Defaults
 	if config.Skipper == nil {
@@ -57,6 +91,16 @@ func DecompressWithConfig(config DecompressConfig) echo.MiddlewareFunc {
 	if config.GzipDecompressPool == nil {
 		config.GzipDecompressPool = DefaultDecompressConfig.GzipDecompressPool
 	}
+	if config.MaxDecompressedSize != "" {
+		limit, err := bytes.Parse(config.MaxDecompressedSize)
+		if err != nil {
+			panic(fmt.Errorf("echo: invalid decompress max decompressed size=%s", config.MaxDecompressedSize))
+		}
+		config.maxDecompressedSize = limit
+	}
+	if config.MaxRatio < 0 {
+		panic("echo: decompress max ratio can not be negative")
+	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		pool := config.GzipDecompressPool.gzipDecompressPool()
@@ -66,33 +110,134 @@ func DecompressWithConfig(config DecompressConfig) echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			if c.Request().Header.Get(echo.HeaderContentEncoding) != GZIPEncoding {
+			codings, ok := parseContentEncoding(c.Request().Header.Get(echo.HeaderContentEncoding))
+			if !ok || len(codings) == 0 {
 				return next(c)
 			}
-
-			i := pool.Get()
-			gr, ok := i.(*gzip.Reader)
-			if !ok || gr == nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, i.(error).Error())
+			if len(codings) > decompressMaxCodings {
+				return echo.NewHTTPError(http.StatusUnsupportedMediaType, "too many content codings")
 			}
-			defer pool.Put(gr)
 
-			b := c.Request().Body
+			req := c.Request()
+			b := req.Body
 			defer b.Close()
 
-			if err := gr.Reset(b); err != nil {
+			compressed := &countingReader{reader: b}
+			var r io.Reader = compressed
+			// content codings are listed in order they were applied so they are decoded in reverse order
+			for i := len(codings) - 1; i >= 0; i-- {
+				var err error
+				switch codings[i] {
+				case GZIPEncoding:
+					p := pool.Get()
+					gr, ok := p.(*gzip.Reader)
+					if !ok || gr == nil {
+						return echo.NewHTTPError(http.StatusInternalServerError, p.(error).Error())
+					}
+					defer pool.Put(gr)
+					if err = gr.Reset(r); err == nil {
+						// only Close gzip reader if it was set to a proper gzip source otherwise it will panic on close.
+						defer gr.Close()
+						r = gr
+					}
+				case DeflateEncoding:
+					var dr io.ReadCloser
+					if dr, err = newDeflateReader(r); err == nil {
+						defer dr.Close()
+						r = dr
+					}
+				}
 				if err == io.EOF { //ignore if body is empty
+					req.Body = http.NoBody
+					req.ContentLength = 0
 					return next(c)
 				}
-				return err
+				if err != nil {
+					return err
+				}
 			}
 
-			// only Close gzip reader if it was set to a proper gzip source otherwise it will panic on close.
-			defer gr.Close()
-
-			c.Request().Body = gr
+			req.Body = &decompressedReader{
+				reader:     r,
+				compressed: compressed,
+				maxSize:    config.maxDecompressedSize,
+				maxRatio:   config.MaxRatio,
+			}
+			// size of the decompressed body is not known in advance
+			req.ContentLength = -1
+			req.Header.Del(echo.HeaderContentLength)
 
 			return next(c)
 		}
 	}
 }
+
+// parseContentEncoding returns content codings from `Content-Encoding` header value in order they were applied.
+// Returns false when any of the codings is not supported.
+func parseContentEncoding(contentEncoding string) ([]string, bool) {
+	var codings []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "", "identity":
+		case GZIPEncoding, "x-gzip":
+			codings = append(codings, GZIPEncoding)
+		case DeflateEncoding:
+			codings = append(codings, DeflateEncoding)
+		default:
+			return nil, false
+		}
+	}
+	return codings, true
+}
+
+// newDeflateReader returns reader for "deflate" coding. HTTP "deflate" is zlib format (RFC 9110 section 8.4.1.2) but
+// some clients send raw deflate data, so zlib header is checked before choosing the format.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if len(header) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// countingReader counts bytes read from the underlying reader.
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	return n, err
+}
+
+// decompressedReader enforces decompressed size and compression ratio limits while body is being read.
+type decompressedReader struct {
+	reader     io.Reader
+	compressed *countingReader
+	maxSize    int64
+	maxRatio   float64
+	read       int64
+}
+
+func (r *decompressedReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	if r.maxSize > 0 && r.read > r.maxSize {
+		return n, echo.ErrStatusRequestEntityTooLarge
+	}
+	if r.maxRatio > 0 && r.read > decompressRatioMinSize && float64(r.read) > r.maxRatio*float64(r.compressed.read) {
+		return n, echo.ErrStatusRequestEntityTooLarge
+	}
+	return n, err
+}
+
+// Close does nothing as the original body is closed by the middleware when request is handled.
+func (r *decompressedReader) Close() error {
+	return nil
+}
@@ -5,11 +5,14 @@ package middleware
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -208,3 +211,192 @@ func gzipString(body string) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+func TestDecompressWithConfig(t *testing.T) {
+	body := strings.Repeat(`{"name": "echo"}`, 10)
+
+	gzipped, _ := gzipString(body)
+	var zlibbed, rawDeflate, stacked, bomb bytes.Buffer
+	zw := zlib.NewWriter(&zlibbed)
+	zw.Write([]byte(body))
+	zw.Close()
+	fw, _ := flate.NewWriter(&rawDeflate, flate.DefaultCompression)
+	fw.Write([]byte(body))
+	fw.Close()
+	// "gzip, deflate" means that gzip was applied first and then deflate
+	zw = zlib.NewWriter(&stacked)
+	zw.Write(gzipped)
+	zw.Close()
+	gw := gzip.NewWriter(&bomb)
+	gw.Write(make([]byte, 1024*1024))
+	gw.Close()
+
+	var testCases = []struct {
+		name              string
+		givenConfig       DecompressConfig
+		givenEncoding     string
+		givenBody         []byte
+		expectBody        string
+		expectCode        int
+		expectContentSize int64
+	}{
+		{
+			name:              "ok, gzip",
+			givenEncoding:     "gzip",
+			givenBody:         gzipped,
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, x-gzip",
+			givenEncoding:     "x-gzip",
+			givenBody:         gzipped,
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, deflate in zlib format",
+			givenEncoding:     "deflate",
+			givenBody:         zlibbed.Bytes(),
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, raw deflate",
+			givenEncoding:     "Deflate",
+			givenBody:         rawDeflate.Bytes(),
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, stacked encodings",
+			givenEncoding:     "gzip, identity, deflate",
+			givenBody:         stacked.Bytes(),
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, unsupported encoding is passed as is",
+			givenEncoding:     "gzip, br",
+			givenBody:         []byte("brotli"),
+			expectBody:        "brotli",
+			expectCode:        http.StatusOK,
+			expectContentSize: 6,
+		},
+		{
+			name:              "ok, empty body",
+			givenEncoding:     "deflate",
+			givenBody:         []byte{},
+			expectBody:        "",
+			expectCode:        http.StatusOK,
+			expectContentSize: 0,
+		},
+		{
+			name:          "nok, too many encodings",
+			givenEncoding: "gzip, gzip, gzip, gzip, gzip",
+			givenBody:     gzipped,
+			expectCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:          "nok, decompressed size exceeded",
+			givenConfig:   DecompressConfig{MaxDecompressedSize: "100B"},
+			givenEncoding: "gzip",
+			givenBody:     gzipped,
+			expectCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:              "ok, decompressed size within limit",
+			givenConfig:       DecompressConfig{MaxDecompressedSize: "1KB"},
+			givenEncoding:     "gzip",
+			givenBody:         gzipped,
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:              "ok, ratio is not enforced for small bodies",
+			givenConfig:       DecompressConfig{MaxRatio: 1},
+			givenEncoding:     "gzip",
+			givenBody:         gzipped,
+			expectBody:        body,
+			expectCode:        http.StatusOK,
+			expectContentSize: -1,
+		},
+		{
+			name:          "nok, ratio exceeded",
+			givenConfig:   DecompressConfig{MaxRatio: 100},
+			givenEncoding: "gzip",
+			givenBody:     bomb.Bytes(),
+			expectCode:    http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(DecompressWithConfig(tc.givenConfig))
+			e.POST("/", func(c echo.Context) error {
+				b, err := io.ReadAll(c.Request().Body)
+				if err != nil {
+					return err
+				}
+				assert.Equal(t, tc.expectContentSize, c.Request().ContentLength)
+				if tc.expectContentSize == -1 {
+					assert.Empty(t, c.Request().Header.Get(echo.HeaderContentLength))
+				}
+				return c.String(http.StatusOK, string(b))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.givenBody))
+			req.Header.Set(echo.HeaderContentEncoding, tc.givenEncoding)
+			req.Header.Set(echo.HeaderContentLength, strconv.Itoa(len(tc.givenBody)))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			if tc.expectCode == http.StatusOK {
+				assert.Equal(t, tc.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecompressWithConfig_panics(t *testing.T) {
+	assert.PanicsWithError(t, "echo: invalid decompress max decompressed size=abc", func() {
+		DecompressWithConfig(DecompressConfig{MaxDecompressedSize: "abc"})
+	})
+	assert.PanicsWithValue(t, "echo: decompress max ratio can not be negative", func() {
+		DecompressWithConfig(DecompressConfig{MaxRatio: -1})
+	})
+}
+
+func TestDecompressWithConfig_formBind(t *testing.T) {
+	e := echo.New()
+	e.Use(DecompressWithConfig(DecompressConfig{MaxDecompressedSize: "100B"}))
+	e.POST("/", func(c echo.Context) error {
+		var form struct {
+			Name string `form:"name"`
+		}
+		err := c.Bind(&form)
+
+		var he *echo.HTTPError
+		if assert.ErrorAs(t, err, &he) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, he.Code)
+		}
+		return err
+	})
+
+	gz, err := gzipString("name=" + strings.Repeat("x", 200))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gz))
+	req.Header.Set(echo.HeaderContentEncoding, GZIPEncoding)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
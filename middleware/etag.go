@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ETagConfig defines the config for ETag middleware.
+type ETagConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Weak defines if generated entity tags are weak validators (`W/"..."`). Weak tags should be used when the same
+	// representation can be served with different byte content, for example when compression is applied later.
+	// Optional. Default value false.
+	Weak bool
+
+	// MaxBufferSize is maximum size of response body (in bytes) that is buffered for ETag calculation. Responses
+	// exceeding that size are sent to the client as is, without ETag.
+	// Optional. Default value 0 (no limit).
+	MaxBufferSize int
+}
+
+// DefaultETagConfig is the default ETag middleware config.
+var DefaultETagConfig = ETagConfig{
+	Skipper: DefaultSkipper,
+}
+
+// ETag returns a middleware which computes strong entity tag for successful GET and HEAD responses and answers
+// conditional requests (`If-None-Match`, `If-Match`, `If-Modified-Since`, `If-Unmodified-Since`) with
+// "304 Not Modified" or "412 Precondition Failed" without sending the response body.
+//
+// Response body is buffered until the handler returns. Handlers that stream responses or call `Flush` are served
+// without ETag. For unsafe methods (PUT, PATCH, DELETE) use `Context#CheckPreconditions` in handler instead.
+func ETag() echo.MiddlewareFunc {
+	return ETagWithConfig(DefaultETagConfig)
+}
+
+// ETagWithConfig returns an ETag middleware with config.
+// See: `ETag()`.
+func ETagWithConfig(config ETagConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultETagConfig.Skipper
+	}
+	if config.MaxBufferSize < 0 {
+		config.MaxBufferSize = DefaultETagConfig.MaxBufferSize
+	}
+
+	bpool := bufferPool()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			req := c.Request()
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next(c)
+			}
+
+			res := c.Response()
+			buf := bpool.Get().(*bytes.Buffer)
+			buf.Reset()
+			defer bpool.Put(buf)
+
+			rw := res.Writer
+			erw := &etagResponseWriter{ResponseWriter: rw, buffer: buf, maxBufferSize: config.MaxBufferSize}
+			res.Writer = erw
+			defer func() {
+				res.Writer = rw
+			}()
+
+			if err := next(c); err != nil {
+				if erw.passThrough || !erw.wroteHeader {
+					return err
+				}
+				// handler has "written" response before returning an error. Send what we have buffered so far as
+				// global error handler would not be able to send anything (response is committed).
+				return erw.flushBuffered(err)
+			}
+			if erw.passThrough || !erw.wroteHeader {
+				return nil
+			}
+			return erw.finish(req, res, config.Weak)
+		}
+	}
+}
+
+type etagResponseWriter struct {
+	http.ResponseWriter
+	buffer        *bytes.Buffer
+	maxBufferSize int
+	code          int
+	wroteHeader   bool
+	passThrough   bool
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	if w.passThrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+}
+
+func (w *etagResponseWriter) Write(b []byte) (int, error) {
+	if w.passThrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.code != http.StatusOK || (w.maxBufferSize > 0 && w.buffer.Len()+len(b) > w.maxBufferSize) {
+		if err := w.startPassThrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buffer.Write(b)
+}
+
+func (w *etagResponseWriter) startPassThrough() error {
+	w.passThrough = true
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	if w.buffer.Len() > 0 {
+		_, err := w.buffer.WriteTo(w.ResponseWriter)
+		return err
+	}
+	return nil
+}
+
+func (w *etagResponseWriter) flushBuffered(err error) error {
+	if wErr := w.startPassThrough(); wErr != nil {
+		return wErr
+	}
+	return err
+}
+
+func (w *etagResponseWriter) finish(req *http.Request, res *echo.Response, weak bool) error {
+	if w.code != http.StatusOK {
+		return w.startPassThrough()
+	}
+	header := res.Header()
+	etag := header.Get(echo.HeaderETag)
+	if etag == "" {
+		etag = echo.GenerateETag(w.buffer.Bytes(), weak)
+		header.Set(echo.HeaderETag, etag)
+	}
+	var modTime time.Time
+	if lm := header.Get(echo.HeaderLastModified); lm != "" {
+		modTime, _ = http.ParseTime(lm)
+	}
+
+	w.passThrough = true
+	switch echo.EvaluatePreconditions(req, etag, modTime) {
+	case http.StatusNotModified:
+		w.buffer.Reset()
+		// echo.Response is already marked as committed by the handler, so we write directly to the underlying writer.
+		res.Status = http.StatusNotModified
+		echo.WriteNotModified(w.ResponseWriter)
+		return nil
+	case http.StatusPreconditionFailed:
+		w.buffer.Reset()
+		header.Del(echo.HeaderContentType)
+		header.Del(echo.HeaderContentLength)
+		res.Status = http.StatusPreconditionFailed
+		w.ResponseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return nil
+	}
+	w.passThrough = false
+	return w.startPassThrough()
+}
+
+func (w *etagResponseWriter) Flush() {
+	if !w.passThrough {
+		// streaming responses can not be hashed, send everything as is from now on
+		_ = w.startPassThrough()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *etagResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	body := `{"name":"Jon Snow"}`
+	etag := echo.GenerateETag([]byte(body+"\n"), false)
+
+	var testCases = []struct {
+		name        string
+		givenConfig ETagConfig
+		whenMethod  string
+		whenHeaders map[string]string
+		whenHandler echo.HandlerFunc
+		expectCode  int
+		expectBody  string
+		expectETag  string
+		expectErr   string
+	}{
+		{
+			name:       "ok, etag is added",
+			whenMethod: http.MethodGet,
+			expectCode: http.StatusOK,
+			expectBody: body + "\n",
+			expectETag: etag,
+		},
+		{
+			name:        "ok, weak etag is added",
+			givenConfig: ETagConfig{Weak: true},
+			whenMethod:  http.MethodGet,
+			expectCode:  http.StatusOK,
+			expectBody:  body + "\n",
+			expectETag:  "W/" + etag,
+		},
+		{
+			name:        "ok, not modified",
+			whenMethod:  http.MethodGet,
+			whenHeaders: map[string]string{echo.HeaderIfNoneMatch: etag},
+			expectCode:  http.StatusNotModified,
+			expectBody:  "",
+			expectETag:  etag,
+		},
+		{
+			name:        "ok, If-Match does not match",
+			whenMethod:  http.MethodGet,
+			whenHeaders: map[string]string{echo.HeaderIfMatch: `"other"`},
+			expectCode:  http.StatusPreconditionFailed,
+			expectBody:  "",
+			expectETag:  etag,
+		},
+		{
+			name:        "ok, handler provided etag is used",
+			whenMethod:  http.MethodGet,
+			whenHeaders: map[string]string{echo.HeaderIfNoneMatch: `"custom"`},
+			whenHandler: func(c echo.Context) error {
+				c.Response().Header().Set(echo.HeaderETag, `"custom"`)
+				return c.JSON(http.StatusOK, map[string]string{"name": "Jon Snow"})
+			},
+			expectCode: http.StatusNotModified,
+			expectETag: `"custom"`,
+		},
+		{
+			name:        "ok, non 200 responses are not tagged",
+			whenMethod:  http.MethodGet,
+			whenHeaders: map[string]string{echo.HeaderIfNoneMatch: "*"},
+			whenHandler: func(c echo.Context) error {
+				return c.String(http.StatusCreated, "created")
+			},
+			expectCode: http.StatusCreated,
+			expectBody: "created",
+		},
+		{
+			name:       "ok, POST is skipped",
+			whenMethod: http.MethodPost,
+			expectCode: http.StatusOK,
+			expectBody: body + "\n",
+		},
+		{
+			name:        "ok, body larger than MaxBufferSize is passed through",
+			givenConfig: ETagConfig{MaxBufferSize: 5},
+			whenMethod:  http.MethodGet,
+			expectCode:  http.StatusOK,
+			expectBody:  body + "\n",
+		},
+		{
+			name:       "nok, handler error is returned",
+			whenMethod: http.MethodGet,
+			whenHandler: func(c echo.Context) error {
+				return errors.New("handler error")
+			},
+			expectCode: http.StatusOK,
+			expectErr:  "handler error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tc.whenMethod, "/", nil)
+			for k, v := range tc.whenHeaders {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := tc.whenHandler
+			if h == nil {
+				h = func(c echo.Context) error {
+					return c.JSON(http.StatusOK, map[string]string{"name": "Jon Snow"})
+				}
+			}
+			err := ETagWithConfig(tc.givenConfig)(h)(c)
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectCode, rec.Code)
+			if tc.expectErr == "" {
+				assert.Equal(t, tc.expectCode, c.Response().Status)
+			}
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+			assert.Equal(t, tc.expectETag, rec.Header().Get(echo.HeaderETag))
+			if tc.expectCode == http.StatusNotModified {
+				assert.Equal(t, "", rec.Header().Get(echo.HeaderContentType))
+			}
+		})
+	}
+}
+
+func TestETag_Flush(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := ETag()(func(c echo.Context) error {
+		c.Response().Write([]byte("part1,"))
+		c.Response().Flush()
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "part1,", rec.Body.String())
+		c.Response().Write([]byte("part2"))
+		return nil
+	})(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "part1,part2", rec.Body.String())
+	assert.Equal(t, "", rec.Header().Get(echo.HeaderETag))
+}
+
+func TestETag_CheckPreconditionsInHandler(t *testing.T) {
+	e := echo.New()
+	e.PUT("/doc", func(c echo.Context) error {
+		if done, err := c.CheckPreconditions(`"v2"`, time.Time{}); done || err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodPut, "/doc", strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderIfMatch, `"v1"`)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IdempotencyConfig defines the config for Idempotency middleware.
+type IdempotencyConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Store persists locks and responses of idempotency keys.
+	// Optional. Default value memory store.
+	Store IdempotencyStore
+
+	// HeaderName is name of the request header containing the idempotency key.
+	// Optional. Default value "Idempotency-Key".
+	HeaderName string
+
+	// Methods are request methods the middleware applies to.
+	// Optional. Default value POST and PATCH.
+	Methods []string
+
+	// TTL is time the response is stored and replayed for repeated requests with the same key.
+	// Optional. Default value 24 hours.
+	TTL time.Duration
+
+	// Required makes requests without idempotency key fail with "400 Bad Request".
+	// Optional. Default value false.
+	Required bool
+
+	// MaxKeyLength is maximum length of the idempotency key. Longer keys result "400 Bad Request".
+	// Optional. Default value 255.
+	MaxKeyLength int
+
+	// Scope returns namespace of the idempotency keys, i.e. ID of the authenticated user, so that keys of different
+	// clients can not collide and responses are never replayed to other clients.
+	// Optional. Default value none (all clients share the same namespace).
+	Scope func(c echo.Context) string
+
+	// Fingerprint returns fingerprint of the request. Request with known key but different fingerprint results
+	// "422 Unprocessable Entity".
+	// Optional. Default value is hash of the request method, URI and body.
+	Fingerprint func(c echo.Context) (string, error)
+}
+
+// IdempotencyStore is the interface to be implemented by idempotency stores.
+type IdempotencyStore interface {
+	// Lock reserves the key for the request with given fingerprint for ttl. When the key already exists, lock is not
+	// acquired and existing record is returned.
+	Lock(key string, fingerprint string, ttl time.Duration) (record *IdempotencyRecord, acquired bool, err error)
+	// Save stores the response of the locked key for ttl.
+	Save(key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Unlock removes lock of the key without response so that the request can be retried.
+	Unlock(key string) error
+}
+
+// IdempotencyRecord is state of the idempotency key persisted by IdempotencyStore.
+type IdempotencyRecord struct {
+	Fingerprint string
+	// Completed is false while the request is being processed.
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+}
+
+// HeaderIdempotentReplayed is set to "true" on responses replayed by Idempotency middleware.
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+var (
+	// ErrIdempotencyKeyMissing is returned when idempotency key is required but the request does not have it.
+	ErrIdempotencyKeyMissing = echo.NewHTTPError(http.StatusBadRequest, "missing idempotency key")
+	// ErrIdempotencyKeyInvalid is returned when idempotency key is too long.
+	ErrIdempotencyKeyInvalid = echo.NewHTTPError(http.StatusBadRequest, "invalid idempotency key")
+	// ErrIdempotencyKeyInFlight is returned when request with the same idempotency key is being processed.
+	ErrIdempotencyKeyInFlight = echo.NewHTTPError(http.StatusConflict, "request with the same idempotency key is being processed")
+	// ErrIdempotencyKeyMismatch is returned when idempotency key has been used for a different request.
+	ErrIdempotencyKeyMismatch = echo.NewHTTPError(http.StatusUnprocessableEntity, "idempotency key has been used for a different request")
+)
+
+// DefaultIdempotencyConfig is the default Idempotency middleware config.
+var DefaultIdempotencyConfig = IdempotencyConfig{
+	Skipper:      DefaultSkipper,
+	HeaderName:   "Idempotency-Key",
+	Methods:      []string{http.MethodPost, http.MethodPatch},
+	TTL:          24 * time.Hour,
+	MaxKeyLength: 255,
+	Fingerprint:  defaultIdempotencyFingerprint,
+}
+
+// Idempotency returns a middleware which makes unsafe requests safe to retry with `Idempotency-Key` header.
+//
+// The first request with a key locks the key and its response (status, headers and body) is stored. Repeated requests
+// with the same key get the stored response replayed with `Idempotent-Replayed: true` header without executing the
+// handler. Request with the same key arriving while the first one is being processed gets "409 Conflict" and request
+// with the same key but different method, URI or body gets "422 Unprocessable Entity".
+//
+// Responses are not stored when the handler returns an error or the response status is 5xx, so such requests can be
+// retried with the same key.
+func Idempotency() echo.MiddlewareFunc {
+	return IdempotencyWithConfig(DefaultIdempotencyConfig)
+}
+
+// IdempotencyWithConfig returns an Idempotency middleware with config.
+// See: `Idempotency()`.
+func IdempotencyWithConfig(config IdempotencyConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultIdempotencyConfig.Skipper
+	}
+	if config.Store == nil {
+		config.Store = NewIdempotencyMemoryStore()
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = DefaultIdempotencyConfig.HeaderName
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = DefaultIdempotencyConfig.Methods
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultIdempotencyConfig.TTL
+	}
+	if config.MaxKeyLength <= 0 {
+		config.MaxKeyLength = DefaultIdempotencyConfig.MaxKeyLength
+	}
+	if config.Fingerprint == nil {
+		config.Fingerprint = DefaultIdempotencyConfig.Fingerprint
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			req := c.Request()
+			applies := false
+			for _, m := range config.Methods {
+				if m == req.Method {
+					applies = true
+					break
+				}
+			}
+			if !applies {
+				return next(c)
+			}
+
+			key := req.Header.Get(config.HeaderName)
+			if key == "" {
+				if config.Required {
+					return ErrIdempotencyKeyMissing
+				}
+				return next(c)
+			}
+			if len(key) > config.MaxKeyLength {
+				return ErrIdempotencyKeyInvalid
+			}
+			if config.Scope != nil {
+				key = config.Scope(c) + ":" + key
+			}
+
+			fingerprint, err := config.Fingerprint(c)
+			if err != nil {
+				return err
+			}
+			record, acquired, err := config.Store.Lock(key, fingerprint, config.TTL)
+			if err != nil {
+				return &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Internal: err}
+			}
+			if !acquired {
+				switch {
+				case record.Fingerprint != fingerprint:
+					return ErrIdempotencyKeyMismatch
+				case !record.Completed:
+					return ErrIdempotencyKeyInFlight
+				}
+				return replayIdempotentResponse(c, record)
+			}
+
+			saved := false
+			defer func() {
+				// handler failed or panicked, release the key so request can be retried
+				if !saved {
+					if uErr := config.Store.Unlock(key); uErr != nil {
+						c.Logger().Error(uErr)
+					}
+				}
+			}()
+
+			res := c.Response()
+			buf := new(bytes.Buffer)
+			rw := res.Writer
+			crw := &cacheResponseWriter{ResponseWriter: rw, buffer: buf, maxSize: math.MaxInt}
+			res.Writer = crw
+			err = next(c)
+			res.Writer = rw
+			if err != nil || crw.hijacked || !res.Committed || res.Status >= http.StatusInternalServerError {
+				return err
+			}
+
+			record = &IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      res.Status,
+				Header:      res.Header().Clone(),
+				Body:        buf.Bytes(),
+			}
+			if sErr := config.Store.Save(key, record, config.TTL); sErr != nil {
+				c.Logger().Error(sErr)
+				return nil
+			}
+			saved = true
+			return nil
+		}
+	}
+}
+
+func replayIdempotentResponse(c echo.Context, record *IdempotencyRecord) error {
+	res := c.Response()
+	header := res.Header()
+	for k, v := range record.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	res.WriteHeader(record.Status)
+	_, err := res.Write(record.Body)
+	return err
+}
+
+// defaultIdempotencyFingerprint returns hash of the request method, URI and body. Body is restored for the handler.
+func defaultIdempotencyFingerprint(c echo.Context) (string, error) {
+	req := c.Request()
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
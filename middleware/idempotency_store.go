@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// IdempotencyMemoryStore stores idempotency keys in the server memory. Keys are lost when the server is restarted and
+// are not shared between server instances.
+type IdempotencyMemoryStore struct {
+	mutex       sync.Mutex
+	records     map[string]*idempotencyMemoryRecord
+	lastCleanup time.Time
+
+	timeNow func() time.Time
+}
+
+type idempotencyMemoryRecord struct {
+	record    *IdempotencyRecord
+	expiresAt time.Time
+}
+
+// idempotencyMemoryStoreCleanupInterval is interval of removing expired keys from IdempotencyMemoryStore.
+const idempotencyMemoryStoreCleanupInterval = time.Minute
+
+// NewIdempotencyMemoryStore returns new IdempotencyMemoryStore.
+func NewIdempotencyMemoryStore() *IdempotencyMemoryStore {
+	return &IdempotencyMemoryStore{
+		records: map[string]*idempotencyMemoryRecord{},
+		timeNow: time.Now,
+	}
+}
+
+// Lock implements IdempotencyStore.Lock
+func (s *IdempotencyMemoryStore) Lock(key string, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	s.cleanup(now)
+	if r, ok := s.records[key]; ok && now.Before(r.expiresAt) {
+		return r.record, false, nil
+	}
+	s.records[key] = &idempotencyMemoryRecord{
+		record:    &IdempotencyRecord{Fingerprint: fingerprint},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+// Save implements IdempotencyStore.Save
+func (s *IdempotencyMemoryStore) Save(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[key] = &idempotencyMemoryRecord{record: record, expiresAt: s.timeNow().Add(ttl)}
+	return nil
+}
+
+// Unlock implements IdempotencyStore.Unlock
+func (s *IdempotencyMemoryStore) Unlock(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r, ok := s.records[key]; ok && !r.record.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *IdempotencyMemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < idempotencyMemoryStoreCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+	for key, r := range s.records {
+		if !now.Before(r.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotencyTestEcho(config IdempotencyConfig, calls *int32) *echo.Echo {
+	e := echo.New()
+	e.Use(IdempotencyWithConfig(config))
+	e.POST("/payments", func(c echo.Context) error {
+		n := atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(c.Request().Body)
+		c.Response().Header().Set("X-Payment", strconv.Itoa(int(n)))
+		return c.String(http.StatusCreated, "paid "+string(body))
+	})
+	e.POST("/fail", func(c echo.Context) error {
+		atomic.AddInt32(calls, 1)
+		return errors.New("failed")
+	})
+	e.PUT("/payments", func(c echo.Context) error {
+		atomic.AddInt32(calls, 1)
+		return c.NoContent(http.StatusNoContent)
+	})
+	return e
+}
+
+func doIdempotencyRequest(e *echo.Echo, method string, target string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	e := newIdempotencyTestEcho(IdempotencyConfig{}, &calls)
+
+	rec := doIdempotencyRequest(e, http.MethodPost, "/payments", "key-1", "10EUR")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "paid 10EUR", rec.Body.String())
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+
+	// stored response is replayed
+	rec = doIdempotencyRequest(e, http.MethodPost, "/payments", "key-1", "10EUR")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "paid 10EUR", rec.Body.String())
+	assert.Equal(t, "1", rec.Header().Get("X-Payment"))
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// same key with different request
+	rec = doIdempotencyRequest(e, http.MethodPost, "/payments", "key-1", "20EUR")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// different key
+	rec = doIdempotencyRequest(e, http.MethodPost, "/payments", "key-2", "10EUR")
+	assert.Equal(t, "2", rec.Header().Get("X-Payment"))
+
+	// requests without key and methods not configured are passed through
+	doIdempotencyRequest(e, http.MethodPost, "/payments", "", "10EUR")
+	doIdempotencyRequest(e, http.MethodPut, "/payments", "key-3", "")
+	doIdempotencyRequest(e, http.MethodPut, "/payments", "key-3", "")
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	// failed request releases the key so it can be retried
+	rec = doIdempotencyRequest(e, http.MethodPost, "/fail", "key-4", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	doIdempotencyRequest(e, http.MethodPost, "/fail", "key-4", "")
+	assert.Equal(t, int32(7), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotency_inFlight(t *testing.T) {
+	e := echo.New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e.Use(Idempotency())
+	e.POST("/", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.String(http.StatusOK, "ok")
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doIdempotencyRequest(e, http.MethodPost, "/", "key", "")
+	}()
+	<-started
+
+	rec := doIdempotencyRequest(e, http.MethodPost, "/", "key", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	close(release)
+	assert.Equal(t, "ok", (<-done).Body.String())
+	rec = doIdempotencyRequest(e, http.MethodPost, "/", "key", "")
+	assert.Equal(t, "ok", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+}
+
+func TestIdempotencyWithConfig(t *testing.T) {
+	var calls int32
+	e := newIdempotencyTestEcho(IdempotencyConfig{
+		Required:     true,
+		MaxKeyLength: 5,
+		Scope: func(c echo.Context) string {
+			return c.Request().Header.Get("X-User")
+		},
+	}, &calls)
+
+	rec := doIdempotencyRequest(e, http.MethodPost, "/payments", "", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "missing idempotency key")
+
+	rec = doIdempotencyRequest(e, http.MethodPost, "/payments", "123456", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid idempotency key")
+
+	// same key of different users does not collide
+	for _, user := range []string{"a", "b", "a"} {
+		req := httptest.NewRequest(http.MethodPost, "/payments", nil)
+		req.Header.Set("Idempotency-Key", "key")
+		req.Header.Set("X-User", user)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyMemoryStore(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewIdempotencyMemoryStore()
+	store.timeNow = func() time.Time { return now }
+
+	_, acquired, err := store.Lock("a", "fp", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	record, acquired, err := store.Lock("a", "fp2", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, &IdempotencyRecord{Fingerprint: "fp"}, record)
+
+	assert.NoError(t, store.Save("a", &IdempotencyRecord{Fingerprint: "fp", Completed: true, Status: http.StatusOK}, time.Minute))
+	// completed record is not removed by unlock
+	assert.NoError(t, store.Unlock("a"))
+	record, _, _ = store.Lock("a", "fp", time.Hour)
+	assert.True(t, record.Completed)
+
+	now = now.Add(2 * time.Minute)
+	_, acquired, _ = store.Lock("a", "fp", time.Hour)
+	assert.True(t, acquired)
+
+	assert.NoError(t, store.Unlock("a"))
+	assert.Empty(t, store.records)
+}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksMaxSize limits size of JSON Web Key Set document loaded from URL.
+const jwksMaxSize = 1 << 20
+
+// jsonWebKey is single key of JSON Web Key Set document (RFC 7517, RFC 7518 section 6 and RFC 8037).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// jwksKeySet loads keys from JSON Web Key Set document and caches them. Key set is loaded again when it is older than
+// refresh interval or when token has key ID which is not in the cached set (key rotation).
+type jwksKeySet struct {
+	url                string
+	file               string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	timeNow            func() time.Time
+
+	mutex       sync.Mutex
+	keys        []jwtKey
+	loadedAt    time.Time
+	attemptedAt time.Time
+	lastErr     error
+}
+
+func newJWKSKeySet(config JWTConfig) *jwksKeySet {
+	client := config.JWKSClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &jwksKeySet{
+		url:                config.JWKSURL,
+		file:               config.JWKSFile,
+		client:             client,
+		refreshInterval:    config.JWKSRefreshInterval,
+		minRefreshInterval: config.JWKSMinRefreshInterval,
+		timeNow:            time.Now,
+	}
+}
+
+// lookup returns keys for given key ID. When kid is empty all keys of the set are returned.
+func (s *jwksKeySet) lookup(kid string) ([]jwtKey, error) {
+	// loading is done while holding the lock so concurrent requests wait for single load instead of starting their own
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	canLoad := s.attemptedAt.IsZero() || now.Sub(s.attemptedAt) >= s.minRefreshInterval
+	if canLoad && (s.loadedAt.IsZero() || now.Sub(s.loadedAt) >= s.refreshInterval) {
+		s.load(now)
+		canLoad = false
+	}
+
+	keys := s.match(kid)
+	if len(keys) == 0 && kid != "" && canLoad {
+		s.load(now)
+		keys = s.match(kid)
+	}
+	if len(keys) == 0 {
+		if s.lastErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrJWTUnknownKey, s.lastErr)
+		}
+		return nil, ErrJWTUnknownKey
+	}
+	return keys, nil
+}
+
+func (s *jwksKeySet) match(kid string) []jwtKey {
+	if kid == "" {
+		return s.keys
+	}
+	var result []jwtKey
+	for _, k := range s.keys {
+		if k.kid == kid {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// load loads the key set. On failure previously loaded keys are kept.
+func (s *jwksKeySet) load(now time.Time) {
+	s.attemptedAt = now
+	b, err := s.read()
+	if err == nil {
+		var keys []jwtKey
+		if keys, err = parseJWKS(b); err == nil {
+			s.keys = keys
+			s.loadedAt = now
+		}
+	}
+	s.lastErr = err
+}
+
+func (s *jwksKeySet) read() ([]byte, error) {
+	if s.file != "" {
+		return os.ReadFile(s.file)
+	}
+	// request is not bound to the context of incoming request as loaded keys are shared by all requests
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d when loading jwks", res.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, jwksMaxSize))
+}
+
+// parseJWKS parses JSON Web Key Set document. Keys that are not meant for signatures or are of unsupported type are
+// skipped.
+func parseJWKS(b []byte) ([]jwtKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks document: %w", err)
+	}
+	keys := make([]jwtKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, jwtKey{kid: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks document does not contain any supported signing keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point size")
+		}
+		// ecdh validates that point is on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("invalid symmetric key")
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeJWKBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid big integer value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
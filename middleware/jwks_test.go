@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testJWK(kid string, key interface{}) map[string]string {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "n": enc(k.N.Bytes()), "e": enc(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": enc(x), "y": enc(y)}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": enc(k)}
+	case []byte:
+		return map[string]string{"kty": "oct", "kid": kid, "k": enc(k)}
+	}
+	panic("unsupported key")
+}
+
+func testJWKS(t *testing.T, keys ...map[string]string) []byte {
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+	return b
+}
+
+func TestParseJWKS(t *testing.T) {
+	doc := testJWKS(t,
+		testJWK("rsa", &testJWTRSAKey.PublicKey),
+		testJWK("ec", &testJWTECDSAKey.PublicKey),
+		testJWK("ed", testJWTEd25519Key.Public()),
+		testJWK("oct", testJWTSecret),
+		map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]string{"kty": "EC", "kid": "not-on-curve", "crv": "P-256", "x": base64.RawURLEncoding.EncodeToString(make([]byte, 32)), "y": base64.RawURLEncoding.EncodeToString(make([]byte, 32))},
+		map[string]string{"kty": "unknown", "kid": "unknown"},
+	)
+
+	keys, err := parseJWKS(doc)
+
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 4) {
+		assert.Equal(t, jwtKey{kid: "rsa", key: &testJWTRSAKey.PublicKey}, keys[0])
+		assert.True(t, testJWTECDSAKey.PublicKey.Equal(keys[1].key))
+		assert.Equal(t, testJWTEd25519Key.Public(), keys[2].key)
+		assert.Equal(t, testJWTSecret, keys[3].key)
+	}
+
+	_, err = parseJWKS([]byte(`{"keys":[]}`))
+	assert.EqualError(t, err, "jwks document does not contain any supported signing keys")
+
+	_, err = parseJWKS([]byte(`{`))
+	assert.EqualError(t, err, "invalid jwks document: unexpected end of JSON input")
+}
+
+func TestJWTWithConfig_JWKSFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	jwk := testJWK("ed", testJWTEd25519Key.Public())
+	jwk["alg"] = "EdDSA"
+	assert.NoError(t, os.WriteFile(file, testJWKS(t, jwk), 0o600))
+
+	v, err := newJWTVerifier(JWTConfig{JWKSFile: file, JWKSRefreshInterval: time.Hour, JWKSMinRefreshInterval: time.Minute})
+	assert.NoError(t, err)
+
+	token, err := v.verify(signTestJWT(t, "EdDSA", testJWTEd25519Key, nil, map[string]interface{}{"sub": "jon"}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "jon", token.Claims.Subject())
+	}
+}
+
+func TestJWTWithConfig_JWKSURLRotation(t *testing.T) {
+	var requests int32
+	var doc atomic.Value
+	doc.Store(testJWKS(t, testJWK("key-1", &testJWTRSAKey.PublicKey)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write(doc.Load().([]byte))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v, err := newJWTVerifier(JWTConfig{
+		JWKSURL:                server.URL,
+		JWKSRefreshInterval:    time.Hour,
+		JWKSMinRefreshInterval: time.Minute,
+	})
+	assert.NoError(t, err)
+	v.keySet.timeNow = func() time.Time { return now }
+
+	// first token loads key set
+	_, err = v.verify(signTestJWT(t, "RS256", testJWTRSAKey, map[string]interface{}{"kid": "key-1"}, nil))
+	assert.NoError(t, err)
+	// cached key set is used
+	_, err = v.verify(signTestJWT(t, "RS256", testJWTRSAKey, map[string]interface{}{"kid": "key-1"}, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// key is rotated at issuer. Unknown kid within minimum refresh interval does not load key set again.
+	doc.Store(testJWKS(t, testJWK("key-2", &testJWTECDSAKey.PublicKey)))
+	rotated := signTestJWT(t, "ES256", testJWTECDSAKey, map[string]interface{}{"kid": "key-2"}, nil)
+	_, err = v.verify(rotated)
+	assert.ErrorIs(t, err, ErrJWTUnknownKey)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	now = now.Add(time.Minute)
+	_, err = v.verify(rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// old key is no longer in the key set
+	_, err = v.verify(signTestJWT(t, "RS256", testJWTRSAKey, map[string]interface{}{"kid": "key-1"}, nil))
+	assert.ErrorIs(t, err, ErrJWTUnknownKey)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// failed load after refresh interval keeps previously loaded keys
+	doc.Store([]byte(`{"keys":[]}`))
+	now = now.Add(time.Hour)
+	_, err = v.verify(rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestJWTWithConfig_JWKSURLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	v, err := newJWTVerifier(JWTConfig{JWKSURL: server.URL, JWKSRefreshInterval: time.Hour, JWKSMinRefreshInterval: time.Minute})
+	assert.NoError(t, err)
+
+	_, err = v.verify(signTestJWT(t, "HS256", testJWTSecret, nil, nil))
+	assert.ErrorIs(t, err, ErrJWTUnknownKey)
+	assert.EqualError(t, err, "token signing key is unknown: unexpected status code 500 when loading jwks")
+}
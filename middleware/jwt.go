@@ -0,0 +1,589 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWTConfig defines the config for JWT middleware.
+type JWTConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// BeforeFunc defines a function which is executed just before the middleware.
+	BeforeFunc BeforeFunc
+
+	// SuccessHandler defines a function which is executed for a valid token before the next handler is called.
+	SuccessHandler JWTSuccessHandler
+
+	// ErrorHandler defines a function which is executed when all lookups have been done and none of them passed
+	// validation. It may be used to define a custom JWT error.
+	ErrorHandler JWTErrorHandler
+
+	// ContinueOnIgnoredError allows the next middleware/handler to be called when ErrorHandler decides to
+	// ignore the error (by returning `nil`).
+	// This is useful when parts of your site/api allow public access and some authorized routes provide extra functionality.
+	ContinueOnIgnoredError bool
+
+	// ContextKey is the key used to store verified token (*JWTToken) in the context.
+	// Optional. Default value "user".
+	ContextKey string
+
+	// TokenLookup is a string in the form of "<source>:<name>" or "<source>:<name>,<source>:<name>" that is used
+	// to extract token from the request. See `CreateExtractors` for possible values.
+	// Optional. Default value "header:Authorization".
+	TokenLookup string
+
+	// AuthScheme to be used in the Authorization header.
+	// Optional. Default value "Bearer".
+	AuthScheme string
+
+	// SigningMethods are algorithms (JWS `alg` header values) accepted by the middleware.
+	// Optional. Default value is all supported algorithms: HS256, HS384, HS512, RS256, RS384, RS512, PS256, PS384,
+	// PS512, ES256, ES384, ES512 and EdDSA.
+	// Regardless of this list token is verified only with keys of matching type i.e. HMAC algorithms only accept
+	// []byte keys so RSA public key can not be used as HMAC secret.
+	SigningMethods []string
+
+	// SigningKey is key used to verify tokens without `kid` header or with `kid` not found in SigningKeys.
+	// Supported types are []byte/string (HMAC), *rsa.PublicKey, *ecdsa.PublicKey and ed25519.PublicKey. Private
+	// keys of the same types are accepted and their public part is used.
+	SigningKey interface{}
+
+	// SigningKeys is map of keys by their key ID (`kid` header).
+	SigningKeys map[string]interface{}
+
+	// JWKSURL is URL of JSON Web Key Set (RFC 7517) document to load verification keys from.
+	JWKSURL string
+
+	// JWKSFile is path to file containing JSON Web Key Set document.
+	JWKSFile string
+
+	// JWKSRefreshInterval is time after which key set is loaded again.
+	// Optional. Default value 1 hour.
+	JWKSRefreshInterval time.Duration
+
+	// JWKSMinRefreshInterval is minimum time between loads of key set. When token has `kid` not present in the cached
+	// key set, the key set is loaded again (key rotation) but not more often than this interval.
+	// Optional. Default value 5 minutes.
+	JWKSMinRefreshInterval time.Duration
+
+	// JWKSClient is HTTP client used to load JWKSURL.
+	// Optional. Default value is client with 10 second timeout.
+	JWKSClient *http.Client
+
+	// Issuer is expected value of `iss` claim. Empty value means `iss` is not checked.
+	Issuer string
+
+	// Audience is list of accepted values for `aud` claim. Token is valid when its audience contains at least one of
+	// them. Empty list means `aud` is not checked.
+	Audience []string
+
+	// ClockSkew is allowed difference between server and token issuer clocks when checking `exp`, `nbf` and `iat` claims.
+	ClockSkew time.Duration
+
+	// RequireExpiration makes tokens without `exp` claim invalid.
+	RequireExpiration bool
+}
+
+// JWTSuccessHandler defines a function which is executed for a valid token.
+type JWTSuccessHandler func(c echo.Context)
+
+// JWTErrorHandler defines a function which is executed for an invalid or missing token.
+type JWTErrorHandler func(c echo.Context, err error) error
+
+// JWTClaims are claims of verified JSON Web Token. Numeric values are json.Number.
+type JWTClaims map[string]interface{}
+
+// JWTToken is verified JSON Web Token stored in the context by JWT middleware.
+type JWTToken struct {
+	// Raw is token as it was extracted from the request.
+	Raw string
+	// Header is decoded JOSE header.
+	Header map[string]interface{}
+	// Claims are decoded claims of the token.
+	Claims JWTClaims
+}
+
+// Errors returned by JWT middleware.
+var (
+	ErrJWTMissing = echo.NewHTTPError(http.StatusBadRequest, "missing or malformed jwt")
+	ErrJWTInvalid = echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+)
+
+// Errors set as internal error of ErrJWTInvalid describing why token was rejected.
+var (
+	ErrJWTMalformed            = errors.New("token is malformed")
+	ErrJWTUnsupportedAlgorithm = errors.New("token signing algorithm is not supported")
+	ErrJWTUnknownKey           = errors.New("token signing key is unknown")
+	ErrJWTInvalidSignature     = errors.New("token signature is invalid")
+	ErrJWTExpired              = errors.New("token is expired")
+	ErrJWTNotValidYet          = errors.New("token is not valid yet")
+	ErrJWTUsedBeforeIssued     = errors.New("token used before issued")
+	ErrJWTInvalidIssuer        = errors.New("token has invalid issuer")
+	ErrJWTInvalidAudience      = errors.New("token has invalid audience")
+	ErrJWTInvalidClaim         = errors.New("token has invalid claim")
+)
+
+// DefaultJWTConfig is the default JWT middleware config.
+var DefaultJWTConfig = JWTConfig{
+	Skipper:                DefaultSkipper,
+	ContextKey:             "user",
+	TokenLookup:            "header:" + echo.HeaderAuthorization,
+	AuthScheme:             "Bearer",
+	JWKSRefreshInterval:    time.Hour,
+	JWKSMinRefreshInterval: 5 * time.Minute,
+}
+
+type jwtAlgorithm struct {
+	hash   crypto.Hash
+	verify func(alg jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool
+	// keySize is size of ECDSA key in bytes
+	keySize int
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {hash: crypto.SHA256, verify: verifyJWTHMAC},
+	"HS384": {hash: crypto.SHA384, verify: verifyJWTHMAC},
+	"HS512": {hash: crypto.SHA512, verify: verifyJWTHMAC},
+	"RS256": {hash: crypto.SHA256, verify: verifyJWTRSA},
+	"RS384": {hash: crypto.SHA384, verify: verifyJWTRSA},
+	"RS512": {hash: crypto.SHA512, verify: verifyJWTRSA},
+	"PS256": {hash: crypto.SHA256, verify: verifyJWTRSAPSS},
+	"PS384": {hash: crypto.SHA384, verify: verifyJWTRSAPSS},
+	"PS512": {hash: crypto.SHA512, verify: verifyJWTRSAPSS},
+	"ES256": {hash: crypto.SHA256, verify: verifyJWTECDSA, keySize: 32},
+	"ES384": {hash: crypto.SHA384, verify: verifyJWTECDSA, keySize: 48},
+	"ES512": {hash: crypto.SHA512, verify: verifyJWTECDSA, keySize: 66},
+	"EdDSA": {verify: verifyJWTEdDSA},
+}
+
+// JWT returns a JSON Web Token (JWT) auth middleware.
+//
+// For valid token, it sets the verified token (*JWTToken) in context and calls next handler.
+// For invalid token, it returns "401 - Unauthorized" error.
+// For missing token, it returns "400 - Bad Request" error.
+//
+// See: https://jwt.io/introduction
+func JWT(key interface{}) echo.MiddlewareFunc {
+	c := DefaultJWTConfig
+	c.SigningKey = key
+	return JWTWithConfig(c)
+}
+
+// JWTWithConfig returns a JWT auth middleware with config.
+// See: `JWT()`.
+func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultJWTConfig.Skipper
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultJWTConfig.ContextKey
+	}
+	if config.TokenLookup == "" {
+		config.TokenLookup = DefaultJWTConfig.TokenLookup
+	}
+	if config.AuthScheme == "" {
+		config.AuthScheme = DefaultJWTConfig.AuthScheme
+	}
+	if config.JWKSRefreshInterval <= 0 {
+		config.JWKSRefreshInterval = DefaultJWTConfig.JWKSRefreshInterval
+	}
+	if config.JWKSMinRefreshInterval <= 0 {
+		config.JWKSMinRefreshInterval = DefaultJWTConfig.JWKSMinRefreshInterval
+	}
+
+	verifier, err := newJWTVerifier(config)
+	if err != nil {
+		panic(err)
+	}
+	extractors, cErr := createExtractors(config.TokenLookup, config.AuthScheme)
+	if cErr != nil {
+		panic(cErr)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			if config.BeforeFunc != nil {
+				config.BeforeFunc(c)
+			}
+
+			var lastExtractorErr error
+			var lastTokenErr error
+			for _, extractor := range extractors {
+				auths, err := extractor(c)
+				if err != nil {
+					lastExtractorErr = err
+					continue
+				}
+				for _, auth := range auths {
+					token, err := verifier.verify(auth)
+					if err != nil {
+						lastTokenErr = err
+						continue
+					}
+					c.Set(config.ContextKey, token)
+					if config.SuccessHandler != nil {
+						config.SuccessHandler(c)
+					}
+					return next(c)
+				}
+			}
+
+			// we are here only when we did not successfully extract or verify any of the tokens
+			err := lastTokenErr
+			if err == nil { // prioritize token errors over extracting errors
+				err = lastExtractorErr
+			}
+			if config.ErrorHandler != nil {
+				tmpErr := config.ErrorHandler(c, err)
+				if config.ContinueOnIgnoredError && tmpErr == nil {
+					return next(c)
+				}
+				return tmpErr
+			}
+			if lastTokenErr != nil {
+				return &echo.HTTPError{Code: ErrJWTInvalid.Code, Message: ErrJWTInvalid.Message, Internal: err}
+			}
+			return &echo.HTTPError{Code: ErrJWTMissing.Code, Message: ErrJWTMissing.Message, Internal: err}
+		}
+	}
+}
+
+// jwtKey is verification key with optional key ID and algorithm restriction.
+type jwtKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+type jwtVerifier struct {
+	methods    map[string]jwtAlgorithm
+	signingKey interface{}
+	keys       map[string]interface{}
+	keySet     *jwksKeySet
+	issuer     string
+	audience   []string
+	clockSkew  time.Duration
+	requireExp bool
+	timeNow    func() time.Time
+}
+
+func newJWTVerifier(config JWTConfig) (*jwtVerifier, error) {
+	v := &jwtVerifier{
+		methods:    jwtAlgorithms,
+		issuer:     config.Issuer,
+		audience:   config.Audience,
+		clockSkew:  config.ClockSkew,
+		requireExp: config.RequireExpiration,
+		timeNow:    time.Now,
+	}
+	if len(config.SigningMethods) > 0 {
+		v.methods = make(map[string]jwtAlgorithm, len(config.SigningMethods))
+		for _, m := range config.SigningMethods {
+			alg, ok := jwtAlgorithms[m]
+			if !ok {
+				return nil, fmt.Errorf("echo: jwt middleware does not support signing method %q", m)
+			}
+			v.methods[m] = alg
+		}
+	}
+	if config.SigningKey != nil {
+		key, err := normalizeJWTKey(config.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		v.signingKey = key
+	}
+	if len(config.SigningKeys) > 0 {
+		v.keys = make(map[string]interface{}, len(config.SigningKeys))
+		for kid, k := range config.SigningKeys {
+			key, err := normalizeJWTKey(k)
+			if err != nil {
+				return nil, err
+			}
+			v.keys[kid] = key
+		}
+	}
+	if config.JWKSURL != "" || config.JWKSFile != "" {
+		v.keySet = newJWKSKeySet(config)
+	}
+	if v.signingKey == nil && v.keys == nil && v.keySet == nil {
+		return nil, errors.New("echo: jwt middleware requires signing key")
+	}
+	return v, nil
+}
+
+// normalizeJWTKey converts key to type used for verification.
+func normalizeJWTKey(key interface{}) (interface{}, error) {
+	switch k := key.(type) {
+	case string:
+		return []byte(k), nil
+	case []byte, *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k.Public(), nil
+	}
+	return nil, fmt.Errorf("echo: jwt middleware does not support signing key type %T", key)
+}
+
+func (v *jwtVerifier) verify(raw string) (*JWTToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+	token := &JWTToken{Raw: raw}
+	if err := decodeJWTSegment(parts[0], &token.Header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	if _, ok := token.Header["crit"]; ok {
+		// none of the extensions are understood so RFC 7515 requires such token to be rejected
+		return nil, fmt.Errorf("%w: unsupported critical header", ErrJWTMalformed)
+	}
+
+	algName, _ := token.Header["alg"].(string)
+	alg, ok := v.methods[algName]
+	if !ok {
+		return nil, ErrJWTUnsupportedAlgorithm
+	}
+	kid, _ := token.Header["kid"].(string)
+	keys, err := v.keysFor(kid)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(raw[:len(parts[0])+1+len(parts[1])])
+	verified := false
+	for _, k := range keys {
+		if k.alg != "" && k.alg != algName {
+			continue
+		}
+		if alg.verify(alg, k.key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrJWTInvalidSignature
+	}
+
+	if err := decodeJWTSegment(parts[1], &token.Claims); err != nil {
+		return nil, err
+	}
+	if err := v.validateClaims(token.Claims); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (v *jwtVerifier) keysFor(kid string) ([]jwtKey, error) {
+	var keys []jwtKey
+	if k, ok := v.keys[kid]; ok && kid != "" {
+		keys = append(keys, jwtKey{kid: kid, key: k})
+	} else if v.signingKey != nil {
+		keys = append(keys, jwtKey{key: v.signingKey})
+	}
+	if v.keySet != nil {
+		setKeys, err := v.keySet.lookup(kid)
+		if err != nil && len(keys) == 0 {
+			return nil, err
+		}
+		keys = append(keys, setKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, ErrJWTUnknownKey
+	}
+	return keys, nil
+}
+
+func decodeJWTSegment(segment string, dest interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(dest); err != nil {
+		return fmt.Errorf("%w: %v", ErrJWTMalformed, err)
+	}
+	return nil
+}
+
+func (v *jwtVerifier) validateClaims(claims JWTClaims) error {
+	now := v.timeNow()
+
+	exp, ok, err := claims.numericDate("exp")
+	if err != nil {
+		return err
+	}
+	if !ok && v.requireExp {
+		return fmt.Errorf("%w: missing exp", ErrJWTInvalidClaim)
+	}
+	if ok && !now.Before(exp.Add(v.clockSkew)) {
+		return ErrJWTExpired
+	}
+
+	nbf, ok, err := claims.numericDate("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.clockSkew).Before(nbf) {
+		return ErrJWTNotValidYet
+	}
+
+	iat, ok, err := claims.numericDate("iat")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.clockSkew).Before(iat) {
+		return ErrJWTUsedBeforeIssued
+	}
+
+	if v.issuer != "" && claims.Issuer() != v.issuer {
+		return ErrJWTInvalidIssuer
+	}
+	if len(v.audience) > 0 {
+		for _, aud := range claims.Audience() {
+			for _, expected := range v.audience {
+				if aud == expected {
+					return nil
+				}
+			}
+		}
+		return ErrJWTInvalidAudience
+	}
+	return nil
+}
+
+// Subject returns `sub` claim.
+func (c JWTClaims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns `iss` claim.
+func (c JWTClaims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns `aud` claim. Claim can be single string or list of strings.
+func (c JWTClaims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		result := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// ExpiresAt returns `exp` claim. Returns false when claim does not exist or is not a number.
+func (c JWTClaims) ExpiresAt() (time.Time, bool) {
+	t, ok, err := c.numericDate("exp")
+	return t, ok && err == nil
+}
+
+func (c JWTClaims) numericDate(name string) (time.Time, bool, error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %v is not a number", ErrJWTInvalidClaim, name)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %v is not a number", ErrJWTInvalidClaim, name)
+	}
+	sec, frac := int64(f), f-float64(int64(f))
+	return time.Unix(sec, int64(frac*1e9)), true, nil
+}
+
+func jwtDigest(hash crypto.Hash, signed []byte) []byte {
+	h := hash.New()
+	h.Write(signed)
+	return h.Sum(nil)
+}
+
+func verifyJWTHMAC(alg jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool {
+	secret, ok := key.([]byte)
+	if !ok || len(secret) == 0 {
+		return false
+	}
+	mac := hmac.New(alg.hash.New, secret)
+	mac.Write(signed)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func verifyJWTRSA(alg jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool {
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return false
+	}
+	return rsa.VerifyPKCS1v15(pub, alg.hash, jwtDigest(alg.hash, signed), signature) == nil
+}
+
+func verifyJWTRSAPSS(alg jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool {
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return false
+	}
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+	return rsa.VerifyPSS(pub, alg.hash, jwtDigest(alg.hash, signed), signature, opts) == nil
+}
+
+func verifyJWTECDSA(alg jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool {
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok || (pub.Curve.Params().BitSize+7)/8 != alg.keySize || len(signature) != 2*alg.keySize {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:alg.keySize])
+	s := new(big.Int).SetBytes(signature[alg.keySize:])
+	return ecdsa.Verify(pub, jwtDigest(alg.hash, signed), r, s)
+}
+
+func verifyJWTEdDSA(_ jwtAlgorithm, key interface{}, signed []byte, signature []byte) bool {
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, signed, signature)
+}
@@ -0,0 +1,444 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testJWTRSAKey, _        = rsa.GenerateKey(rand.Reader, 2048)
+	testJWTECDSAKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, testJWTEd25519Key, _ = ed25519.GenerateKey(rand.Reader)
+	testJWTSecret           = []byte("secret")
+)
+
+// signTestJWT creates signed token for tests. Key type must match the algorithm.
+func signTestJWT(t *testing.T, alg string, key interface{}, header map[string]interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	h := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	for k, v := range header {
+		h[k] = v
+	}
+	hb, err := json.Marshal(h)
+	assert.NoError(t, err)
+	cb, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	var sig []byte
+	switch alg {
+	case "HS256", "HS384", "HS512":
+		a := jwtAlgorithms[alg]
+		mac := hmac.New(a.hash.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256", "RS384", "RS512":
+		a := jwtAlgorithms[alg]
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), a.hash, jwtDigest(a.hash, []byte(signed)))
+	case "PS256", "PS384", "PS512":
+		a := jwtAlgorithms[alg]
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		sig, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), a.hash, jwtDigest(a.hash, []byte(signed)), opts)
+	case "ES256", "ES384", "ES512":
+		a := jwtAlgorithms[alg]
+		r, s, sErr := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), jwtDigest(a.hash, []byte(signed)))
+		err = sErr
+		sig = make([]byte, 2*a.keySize)
+		r.FillBytes(sig[:a.keySize])
+		s.FillBytes(sig[a.keySize:])
+	case "EdDSA":
+		sig = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signed))
+	case "none":
+	}
+	assert.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWT_Algorithms(t *testing.T) {
+	var testCases = []struct {
+		alg        string
+		signingKey interface{}
+		verifyKey  interface{}
+	}{
+		{alg: "HS256", signingKey: testJWTSecret, verifyKey: testJWTSecret},
+		{alg: "HS384", signingKey: testJWTSecret, verifyKey: "secret"},
+		{alg: "HS512", signingKey: testJWTSecret, verifyKey: testJWTSecret},
+		{alg: "RS256", signingKey: testJWTRSAKey, verifyKey: &testJWTRSAKey.PublicKey},
+		{alg: "RS512", signingKey: testJWTRSAKey, verifyKey: testJWTRSAKey},
+		{alg: "PS256", signingKey: testJWTRSAKey, verifyKey: &testJWTRSAKey.PublicKey},
+		{alg: "ES256", signingKey: testJWTECDSAKey, verifyKey: &testJWTECDSAKey.PublicKey},
+		{alg: "EdDSA", signingKey: testJWTEd25519Key, verifyKey: testJWTEd25519Key.Public()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.alg, func(t *testing.T) {
+			token := signTestJWT(t, tc.alg, tc.signingKey, nil, map[string]interface{}{"sub": "jon"})
+
+			e := echo.New()
+			e.Use(JWT(tc.verifyKey))
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, c.Get("user").(*JWTToken).Claims.Subject())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "jon", rec.Body.String())
+		})
+	}
+}
+
+func TestJWT_Verify(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	unix := now.Unix()
+
+	var testCases = []struct {
+		name         string
+		givenConfig  func(c *JWTConfig)
+		whenToken    func(t *testing.T) string
+		expectClaims JWTClaims
+		expectErr    error
+		expectErrMsg string
+	}{
+		{
+			name: "ok, claims within clock skew",
+			givenConfig: func(c *JWTConfig) {
+				c.ClockSkew = time.Minute
+				c.Issuer = "https://issuer.example.com"
+				c.Audience = []string{"other", "api"}
+			},
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{
+					"iss": "https://issuer.example.com",
+					"aud": []string{"api"},
+					"exp": unix - 30,
+					"nbf": unix + 30,
+					"iat": unix + 30,
+				})
+			},
+			expectClaims: JWTClaims{
+				"iss": "https://issuer.example.com",
+				"aud": []interface{}{"api"},
+				"exp": json.Number("1714564770"),
+				"nbf": json.Number("1714564830"),
+				"iat": json.Number("1714564830"),
+			},
+		},
+		{
+			name: "ok, kid selects key",
+			givenConfig: func(c *JWTConfig) {
+				c.SigningKey = nil
+				c.SigningKeys = map[string]interface{}{"a": []byte("other"), "b": testJWTSecret}
+			},
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, map[string]interface{}{"kid": "b"}, map[string]interface{}{"aud": "api"})
+			},
+			expectClaims: JWTClaims{"aud": "api"},
+		},
+		{
+			name: "nok, expired",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"exp": unix})
+			},
+			expectErr: ErrJWTExpired,
+		},
+		{
+			name:        "nok, expired beyond clock skew",
+			givenConfig: func(c *JWTConfig) { c.ClockSkew = time.Minute },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"exp": unix - 60})
+			},
+			expectErr: ErrJWTExpired,
+		},
+		{
+			name: "nok, not valid yet",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"nbf": unix + 1})
+			},
+			expectErr: ErrJWTNotValidYet,
+		},
+		{
+			name: "nok, issued in future",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"iat": unix + 1})
+			},
+			expectErr: ErrJWTUsedBeforeIssued,
+		},
+		{
+			name:        "nok, missing exp",
+			givenConfig: func(c *JWTConfig) { c.RequireExpiration = true },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{})
+			},
+			expectErrMsg: "token has invalid claim: missing exp",
+		},
+		{
+			name: "nok, exp is not a number",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"exp": "tomorrow"})
+			},
+			expectErrMsg: "token has invalid claim: exp is not a number",
+		},
+		{
+			name:        "nok, issuer",
+			givenConfig: func(c *JWTConfig) { c.Issuer = "a" },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"iss": "b"})
+			},
+			expectErr: ErrJWTInvalidIssuer,
+		},
+		{
+			name:        "nok, audience",
+			givenConfig: func(c *JWTConfig) { c.Audience = []string{"api"} },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"aud": []string{"web"}})
+			},
+			expectErr: ErrJWTInvalidAudience,
+		},
+		{
+			name: "nok, signature",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", []byte("other"), nil, map[string]interface{}{})
+			},
+			expectErr: ErrJWTInvalidSignature,
+		},
+		{
+			name: "nok, alg none",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "none", nil, nil, map[string]interface{}{})
+			},
+			expectErr: ErrJWTUnsupportedAlgorithm,
+		},
+		{
+			name:        "nok, algorithm not in signing methods",
+			givenConfig: func(c *JWTConfig) { c.SigningMethods = []string{"HS512"} },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{})
+			},
+			expectErr: ErrJWTUnsupportedAlgorithm,
+		},
+		{
+			name:        "nok, RSA public key used as HMAC secret",
+			givenConfig: func(c *JWTConfig) { c.SigningKey = &testJWTRSAKey.PublicKey },
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{})
+			},
+			expectErr: ErrJWTInvalidSignature,
+		},
+		{
+			name: "nok, unknown kid",
+			givenConfig: func(c *JWTConfig) {
+				c.SigningKey = nil
+				c.SigningKeys = map[string]interface{}{"a": testJWTSecret}
+			},
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, map[string]interface{}{"kid": "b"}, map[string]interface{}{})
+			},
+			expectErr: ErrJWTUnknownKey,
+		},
+		{
+			name: "nok, critical header",
+			whenToken: func(t *testing.T) string {
+				return signTestJWT(t, "HS256", testJWTSecret, map[string]interface{}{"crit": []string{"exp"}}, map[string]interface{}{})
+			},
+			expectErr: ErrJWTMalformed,
+		},
+		{
+			name:      "nok, malformed",
+			whenToken: func(t *testing.T) string { return "a.b" },
+			expectErr: ErrJWTMalformed,
+		},
+		{
+			name:      "nok, invalid base64",
+			whenToken: func(t *testing.T) string { return "!.b.c" },
+			expectErr: ErrJWTMalformed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := JWTConfig{SigningKey: testJWTSecret}
+			if tc.givenConfig != nil {
+				tc.givenConfig(&config)
+			}
+			v, err := newJWTVerifier(config)
+			assert.NoError(t, err)
+			v.timeNow = func() time.Time { return now }
+
+			token, err := v.verify(tc.whenToken(t))
+
+			if tc.expectErr != nil || tc.expectErrMsg != "" {
+				if tc.expectErr != nil {
+					assert.ErrorIs(t, err, tc.expectErr)
+				}
+				if tc.expectErrMsg != "" {
+					assert.EqualError(t, err, tc.expectErrMsg)
+				}
+				assert.Nil(t, token)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectClaims, token.Claims)
+		})
+	}
+}
+
+func TestJWTWithConfig(t *testing.T) {
+	valid := signTestJWT(t, "HS256", testJWTSecret, nil, map[string]interface{}{"sub": "jon"})
+	invalid := signTestJWT(t, "HS256", []byte("other"), nil, map[string]interface{}{"sub": "jon"})
+
+	var testCases = []struct {
+		name        string
+		givenConfig JWTConfig
+		whenHeader  string
+		whenURL     string
+		expectCode  int
+		expectBody  string
+		expectError string
+	}{
+		{
+			name:       "ok, header",
+			whenHeader: "Bearer " + valid,
+			expectCode: http.StatusOK,
+			expectBody: "jon",
+		},
+		{
+			name:        "ok, query and custom context key",
+			givenConfig: JWTConfig{TokenLookup: "header:X-Token,query:token", ContextKey: "token"},
+			whenURL:     "/?token=" + valid,
+			expectCode:  http.StatusOK,
+			expectBody:  "jon",
+		},
+		{
+			name:        "nok, missing",
+			expectError: "code=400, message=missing or malformed jwt, internal=missing value in request header",
+		},
+		{
+			name:        "nok, invalid scheme",
+			whenHeader:  "Basic " + valid,
+			expectError: "code=400, message=missing or malformed jwt, internal=invalid value in request header",
+		},
+		{
+			name:        "nok, invalid",
+			whenHeader:  "Bearer " + invalid,
+			expectError: "code=401, message=invalid or expired jwt, internal=token signature is invalid",
+		},
+		{
+			name: "ok, error handler ignores error",
+			givenConfig: JWTConfig{
+				ErrorHandler:           func(c echo.Context, err error) error { return nil },
+				ContinueOnIgnoredError: true,
+			},
+			whenHeader: "Bearer " + invalid,
+			expectCode: http.StatusOK,
+			expectBody: "public",
+		},
+		{
+			name: "nok, error handler",
+			givenConfig: JWTConfig{
+				ErrorHandler: func(c echo.Context, err error) error {
+					return echo.NewHTTPError(http.StatusTeapot, err.Error())
+				},
+			},
+			whenHeader:  "Bearer " + invalid,
+			expectError: "code=418, message=token signature is invalid",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			config := tc.givenConfig
+			config.SigningKey = testJWTSecret
+			key := config.ContextKey
+			if key == "" {
+				key = "user"
+			}
+			mw := JWTWithConfig(config)
+			h := mw(func(c echo.Context) error {
+				token, ok := c.Get(key).(*JWTToken)
+				if !ok {
+					return c.String(http.StatusOK, "public")
+				}
+				return c.String(http.StatusOK, token.Claims.Subject())
+			})
+
+			url := tc.whenURL
+			if url == "" {
+				url = "/"
+			}
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			if tc.whenHeader != "" {
+				req.Header.Set(echo.HeaderAuthorization, tc.whenHeader)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h(c)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectCode, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+		})
+	}
+}
+
+func TestJWTWithConfig_panics(t *testing.T) {
+	assert.PanicsWithError(t, "echo: jwt middleware requires signing key", func() {
+		JWTWithConfig(JWTConfig{})
+	})
+	assert.PanicsWithError(t, `echo: jwt middleware does not support signing method "none"`, func() {
+		JWTWithConfig(JWTConfig{SigningKey: testJWTSecret, SigningMethods: []string{"none"}})
+	})
+	assert.PanicsWithError(t, "echo: jwt middleware does not support signing key type int", func() {
+		JWT(1)
+	})
+}
+
+func TestJWTClaims(t *testing.T) {
+	claims := JWTClaims{"sub": "jon", "iss": "echo", "aud": "api", "exp": json.Number("1714564800.5")}
+
+	assert.Equal(t, "jon", claims.Subject())
+	assert.Equal(t, "echo", claims.Issuer())
+	assert.Equal(t, []string{"api"}, claims.Audience())
+	exp, ok := claims.ExpiresAt()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1714564800, 500_000_000), exp)
+
+	_, ok = JWTClaims{}.ExpiresAt()
+	assert.False(t, ok)
+	assert.Nil(t, JWTClaims{"aud": 1}.Audience())
+}
+
+func TestVerifyJWTECDSA_curveMismatch(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	token := signTestJWT(t, "ES384", key, nil, map[string]interface{}{})
+	parts := strings.Split(token, ".")
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+
+	// ES256 token verification must not accept P-384 key
+	assert.False(t, verifyJWTECDSA(jwtAlgorithms["ES256"], &key.PublicKey, []byte(parts[0]+"."+parts[1]), sig))
+	assert.True(t, verifyJWTECDSA(jwtAlgorithms["ES384"], &key.PublicKey, []byte(parts[0]+"."+parts[1]), sig))
+	assert.Equal(t, crypto.SHA384, jwtAlgorithms["ES384"].hash)
+}
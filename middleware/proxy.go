@@ -84,6 +84,45 @@ type ProxyConfig struct {
 
 	// ModifyResponse defines function to modify response from ProxyTarget.
 	ModifyResponse func(*http.Response) error
+
+	// StickySession enables session affinity. Client gets a cookie naming the target
+	// its request was proxied to and subsequent requests with the cookie are proxied
+	// to the same target for as long as the balancer provides it. When the target is
+	// removed or unhealthy the request is proxied to the target selected by the
+	// balancer and the cookie is updated. Retried requests always use the balancer,
+	// which does not select the failed target from the cookie again (except random
+	// balancer, which does not avoid previously failed targets). Targets without Name
+	// are not sticky. Balancer must implement NamedTargetProvider, as all balancers in
+	// this package do.
+	// Optional. Default value nil (disabled).
+	StickySession *ProxyStickySessionConfig
+}
+
+// ProxyStickySessionConfig defines the config of sticky sessions for Proxy middleware.
+type ProxyStickySessionConfig struct {
+	// CookieName is name of the cookie holding name of the target.
+	// Optional. Default value "_echo_proxy_target".
+	CookieName string
+
+	// CookieDomain is domain of the cookie.
+	// Optional. Default value none.
+	CookieDomain string
+
+	// CookiePath is path of the cookie.
+	// Optional. Default value "/".
+	CookiePath string
+
+	// CookieMaxAge is max age (in seconds) of the cookie.
+	// Optional. Default value 0 (cookie is removed when the browser is closed).
+	CookieMaxAge int
+
+	// CookieSecure indicates if the cookie is secure.
+	// Optional. Default value false.
+	CookieSecure bool
+
+	// CookieSameSite indicates SameSite mode of the cookie.
+	// Optional. Default value SameSiteDefaultMode.
+	CookieSameSite http.SameSite
 }
 
 // ProxyTarget defines the upstream target.
@@ -91,6 +130,9 @@ type ProxyTarget struct {
 	Name string
 	URL  *url.URL
 	Meta echo.Map
+	// Weight is relative share of requests the target receives from weighted balancers
+	// (weighted round-robin, least-connections and consistent-hash). Values less than 1 are treated as 1.
+	Weight int
 }
 
 // ProxyBalancer defines an interface to implement a load balancing technique.
@@ -106,6 +148,26 @@ type TargetProvider interface {
 	NextTarget(echo.Context) (*ProxyTarget, error)
 }
 
+// NamedTargetProvider defines an interface that gives the opportunity for balancer
+// to select the target by its name, i.e. for sticky sessions.
+type NamedTargetProvider interface {
+	// NamedTarget returns the target with the given name or nil when the target does
+	// not exist or should not be used, i.e. is unhealthy. Returned target should be
+	// treated as selected for the request so that retries use other targets.
+	NamedTarget(c echo.Context, name string) *ProxyTarget
+}
+
+// TargetObserver defines an interface that gives the opportunity for balancer
+// to observe the outcome of requests proxied to the selected targets, e.g. to
+// track health or load of the targets.
+type TargetObserver interface {
+	// TargetDone is called after each attempt to proxy the request to the target,
+	// including attempts that are retried. err is the same error RetryFilter would
+	// receive or nil when the target responded, in which case the response status
+	// is available from c.Response().Status.
+	TargetDone(c echo.Context, target *ProxyTarget, err error)
+}
+
 type commonBalancer struct {
 	targets []*ProxyTarget
 	mutex   sync.Mutex
@@ -124,6 +186,15 @@ type roundRobinBalancer struct {
 	i int
 }
 
+// roundRobinLastIndexKey is context key of the target index selected for the previous attempt of retried request
+const roundRobinLastIndexKey = "_round_robin_last_index"
+
+// DefaultProxyStickySessionConfig is the default config of sticky sessions for Proxy middleware.
+var DefaultProxyStickySessionConfig = ProxyStickySessionConfig{
+	CookieName: "_echo_proxy_target",
+	CookiePath: "/",
+}
+
 // DefaultProxyConfig is the default Proxy middleware config.
 var DefaultProxyConfig = ProxyConfig{
 	Skipper:    DefaultSkipper,
@@ -213,6 +284,35 @@ func (b *commonBalancer) RemoveTarget(name string) bool {
 	return false
 }
 
+// NamedTarget returns an upstream target by name.
+//
+// Note: `nil` is returned in case no target with the name is found.
+func (b *commonBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, t := range b.targets {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// NamedTarget returns an upstream target by name. Retried request is sent to the target following it.
+//
+// Note: `nil` is returned in case no target with the name is found.
+func (b *roundRobinBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, t := range b.targets {
+		if t.Name == name {
+			c.Set(roundRobinLastIndexKey, i)
+			return t
+		}
+	}
+	return nil
+}
+
 // Next randomly returns an upstream target.
 //
 // Note: `nil` is returned in case upstream target list is empty.
@@ -245,12 +345,11 @@ func (b *roundRobinBalancer) Next(c echo.Context) *ProxyTarget {
 	}
 
 	var i int
-	const lastIdxKey = "_round_robin_last_index"
 	// This request is a retry, start from the index of the previous
 	// target to ensure we don't attempt to retry the request with
 	// the same failed target
-	if c.Get(lastIdxKey) != nil {
-		i = c.Get(lastIdxKey).(int)
+	if c.Get(roundRobinLastIndexKey) != nil {
+		i = c.Get(roundRobinLastIndexKey).(int)
 		i++
 		if i >= len(b.targets) {
 			i = 0
@@ -264,7 +363,7 @@ func (b *roundRobinBalancer) Next(c echo.Context) *ProxyTarget {
 		b.i++
 	}
 
-	c.Set(lastIdxKey, i)
+	c.Set(roundRobinLastIndexKey, i)
 	return b.targets[i]
 }
 
@@ -309,7 +408,25 @@ func ProxyWithConfig(config ProxyConfig) echo.MiddlewareFunc {
 		}
 	}
 
+	var namedProvider NamedTargetProvider
+	if config.StickySession != nil {
+		sticky := *config.StickySession
+		if sticky.CookieName == "" {
+			sticky.CookieName = DefaultProxyStickySessionConfig.CookieName
+		}
+		if sticky.CookiePath == "" {
+			sticky.CookiePath = DefaultProxyStickySessionConfig.CookiePath
+		}
+		config.StickySession = &sticky
+
+		var ok bool
+		if namedProvider, ok = config.Balancer.(NamedTargetProvider); !ok {
+			panic("echo: proxy middleware sticky session requires balancer implementing NamedTargetProvider")
+		}
+	}
+
 	provider, isTargetProvider := config.Balancer.(TargetProvider)
+	observer, isTargetObserver := config.Balancer.(TargetObserver)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -340,16 +457,24 @@ func ProxyWithConfig(config ProxyConfig) echo.MiddlewareFunc {
 			for {
 				var tgt *ProxyTarget
 				var err error
-				if isTargetProvider {
-					tgt, err = provider.NextTarget(c)
-					if err != nil {
-						return config.ErrorHandler(c, err)
+				if config.StickySession != nil && retries == config.RetryCount {
+					tgt = stickyTarget(c, config.StickySession, namedProvider)
+				}
+				if tgt == nil {
+					if isTargetProvider {
+						tgt, err = provider.NextTarget(c)
+						if err != nil {
+							return config.ErrorHandler(c, err)
+						}
+					} else {
+						tgt = config.Balancer.Next(c)
 					}
-				} else {
-					tgt = config.Balancer.Next(c)
 				}
 
 				c.Set(config.ContextKey, tgt)
+				if config.StickySession != nil && tgt != nil {
+					setStickyCookie(c, config.StickySession, tgt)
+				}
 
 				//If retrying a failed request, clear any previous errors from
 				//context here so that balancers have the option to check for
@@ -371,6 +496,9 @@ func ProxyWithConfig(config ProxyConfig) echo.MiddlewareFunc {
 				}
 
 				err, hasError := c.Get("_error").(error)
+				if isTargetObserver && tgt != nil {
+					observer.TargetDone(c, tgt, err)
+				}
 				if !hasError {
 					return nil
 				}
@@ -386,6 +514,56 @@ func ProxyWithConfig(config ProxyConfig) echo.MiddlewareFunc {
 	}
 }
 
+// stickyTarget returns the target named by the sticky session cookie of the request.
+func stickyTarget(c echo.Context, config *ProxyStickySessionConfig, provider NamedTargetProvider) *ProxyTarget {
+	cookie, err := c.Cookie(config.CookieName)
+	if err != nil {
+		return nil
+	}
+	name, err := url.QueryUnescape(cookie.Value)
+	if err != nil || name == "" {
+		return nil
+	}
+	c.Set("_sticky_target", name)
+	return provider.NamedTarget(c, name)
+}
+
+// setStickyCookie sets the sticky session cookie to the target unless the request already has it. Cookie set for
+// the target of the previous attempt of retried request is replaced.
+func setStickyCookie(c echo.Context, config *ProxyStickySessionConfig, tgt *ProxyTarget) {
+	if tgt.Name == "" {
+		return // target without name can not be found by NamedTarget
+	}
+	if name, ok := c.Get("_sticky_target").(string); ok && name == tgt.Name {
+		return
+	}
+	header := c.Response().Header()
+	prefix := config.CookieName + "="
+	cookies := header[echo.HeaderSetCookie][:0]
+	for _, v := range header[echo.HeaderSetCookie] {
+		if !strings.HasPrefix(v, prefix) {
+			cookies = append(cookies, v)
+		}
+	}
+	if len(cookies) == 0 {
+		header.Del(echo.HeaderSetCookie)
+	} else {
+		header[echo.HeaderSetCookie] = cookies
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     config.CookieName,
+		Value:    url.QueryEscape(tgt.Name),
+		Path:     config.CookiePath,
+		Domain:   config.CookieDomain,
+		MaxAge:   config.CookieMaxAge,
+		Secure:   config.CookieSecure,
+		HttpOnly: true,
+		SameSite: config.CookieSameSite,
+	})
+	c.Set("_sticky_target", tgt.Name)
+}
+
 // StatusCodeContextCanceled is a custom HTTP status code for situations
 // where a client unexpectedly closed the connection to the server.
 // As there is no standard error code for "client closed connection", but
@@ -0,0 +1,377 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// context keys of the target selected for the previous attempt of retried request
+const (
+	weightedRoundRobinLastTargetKey = "_weighted_round_robin_last_target"
+	leastConnectionsLastTargetKey   = "_least_connections_last_target"
+	consistentHashLastIndexKey      = "_consistent_hash_last_index"
+)
+
+// weightedRoundRobinBalancer implements a smooth weighted round-robin load balancing technique.
+type weightedRoundRobinBalancer struct {
+	commonBalancer
+	// current weights of the targets
+	current map[*ProxyTarget]int
+}
+
+// leastConnectionsBalancer implements a least outstanding requests load balancing technique.
+type leastConnectionsBalancer struct {
+	commonBalancer
+	// number of requests in progress for the targets
+	outstanding map[*ProxyTarget]int
+	// rotates start of the search so that ties are spread evenly
+	i int
+}
+
+// consistentHashBalancer implements a consistent hashing load balancing technique.
+type consistentHashBalancer struct {
+	commonBalancer
+	extractors []ValuesExtractor
+	replicas   int
+	ring       []consistentHashPoint
+}
+
+type consistentHashPoint struct {
+	hash   uint64
+	target *ProxyTarget
+}
+
+// ConsistentHashConfig defines the config for consistent-hash proxy balancer.
+type ConsistentHashConfig struct {
+	// KeyLookup is a string in the form of "<source>:<name>" or "<source>:<name>,<source>:<name>" that is used
+	// to extract the key requests are hashed by. First found value is used. See `CreateExtractors` for possible
+	// sources, i.e. "header:X-User-ID", "cookie:session" or "param:id".
+	// Optional. Default value "" (client IP address returned by `echo.Context.RealIP`).
+	KeyLookup string
+
+	// Replicas is number of points each target has on the hash ring multiplied by target weight. More points give
+	// more even distribution of keys.
+	// Optional. Default value 100.
+	Replicas int
+}
+
+// DefaultConsistentHashConfig is the default consistent-hash proxy balancer config.
+var DefaultConsistentHashConfig = ConsistentHashConfig{
+	Replicas: 100,
+}
+
+// NewWeightedRoundRobinBalancer returns a weighted round-robin proxy balancer. Targets receive requests in
+// proportion to their `Weight` and are interleaved smoothly, i.e. weights 2 and 1 result order A, B, A.
+func NewWeightedRoundRobinBalancer(targets []*ProxyTarget) ProxyBalancer {
+	b := weightedRoundRobinBalancer{current: map[*ProxyTarget]int{}}
+	b.targets = targets
+	return &b
+}
+
+// NewLeastConnectionsBalancer returns a proxy balancer which sends requests to the target with the least number of
+// requests in progress relative to its `Weight`. Requests are counted until Proxy middleware reports them done
+// with `TargetDone`.
+func NewLeastConnectionsBalancer(targets []*ProxyTarget) ProxyBalancer {
+	b := leastConnectionsBalancer{outstanding: map[*ProxyTarget]int{}}
+	b.targets = targets
+	return &b
+}
+
+// NewConsistentHashBalancer returns a proxy balancer which sends requests with the same key to the same target,
+// i.e. for cache affinity. Adding or removing a target remaps only keys of that target.
+//
+// Targets are placed on the hash ring by their `Name`, or by their URL when the name is empty, so targets should have
+// unique names or URLs.
+func NewConsistentHashBalancer(targets []*ProxyTarget, config ConsistentHashConfig) ProxyBalancer {
+	// Defaults
+	if config.Replicas <= 0 {
+		config.Replicas = DefaultConsistentHashConfig.Replicas
+	}
+	extractors, err := CreateExtractors(config.KeyLookup)
+	if err != nil {
+		panic(err)
+	}
+
+	b := consistentHashBalancer{extractors: extractors, replicas: config.Replicas}
+	b.targets = targets
+	b.rebuild()
+	return &b
+}
+
+func proxyTargetWeight(t *ProxyTarget) int {
+	if t.Weight < 1 {
+		return 1
+	}
+	return t.Weight
+}
+
+// RemoveTarget removes an upstream target from the list by name.
+//
+// Returns `true` on success, `false` if no target with the name is found.
+func (b *weightedRoundRobinBalancer) RemoveTarget(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, t := range b.targets {
+		if t.Name == name {
+			b.targets = append(b.targets[:i], b.targets[i+1:]...)
+			delete(b.current, t)
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns an upstream target using smooth weighted round-robin technique. Retried request is not sent to the
+// target the previous attempt failed with.
+//
+// Note: `nil` is returned in case upstream target list is empty.
+func (b *weightedRoundRobinBalancer) Next(c echo.Context) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.targets) == 0 {
+		return nil
+	} else if len(b.targets) == 1 {
+		return b.targets[0]
+	}
+
+	previous, retry := c.Get(weightedRoundRobinLastTargetKey).(*ProxyTarget)
+
+	var best *ProxyTarget
+	total := 0
+	for _, t := range b.targets {
+		w := proxyTargetWeight(t)
+		b.current[t] += w
+		total += w
+		if retry && t == previous {
+			continue
+		}
+		if best == nil || b.current[t] > b.current[best] {
+			best = t
+		}
+	}
+	b.current[best] -= total
+
+	c.Set(weightedRoundRobinLastTargetKey, best)
+	return best
+}
+
+// NamedTarget returns an upstream target by name. Retried request is not sent to this target.
+//
+// Note: `nil` is returned in case no target with the name is found.
+func (b *weightedRoundRobinBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	t := b.commonBalancer.NamedTarget(c, name)
+	if t != nil {
+		c.Set(weightedRoundRobinLastTargetKey, t)
+	}
+	return t
+}
+
+// RemoveTarget removes an upstream target from the list by name.
+//
+// Returns `true` on success, `false` if no target with the name is found.
+func (b *leastConnectionsBalancer) RemoveTarget(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, t := range b.targets {
+		if t.Name == name {
+			b.targets = append(b.targets[:i], b.targets[i+1:]...)
+			delete(b.outstanding, t)
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns an upstream target with the least number of requests in progress relative to its weight. Retried
+// request is not sent to the target the previous attempt failed with.
+//
+// Note: `nil` is returned in case upstream target list is empty.
+func (b *leastConnectionsBalancer) Next(c echo.Context) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.targets) == 0 {
+		return nil
+	} else if len(b.targets) == 1 {
+		b.outstanding[b.targets[0]]++
+		return b.targets[0]
+	}
+
+	previous, retry := c.Get(leastConnectionsLastTargetKey).(*ProxyTarget)
+
+	if b.i >= len(b.targets) {
+		b.i = 0
+	}
+	var best *ProxyTarget
+	for n := 0; n < len(b.targets); n++ {
+		t := b.targets[(b.i+n)%len(b.targets)]
+		if retry && t == previous {
+			continue
+		}
+		// compare outstanding/weight ratios without division
+		if best == nil || b.outstanding[t]*proxyTargetWeight(best) < b.outstanding[best]*proxyTargetWeight(t) {
+			best = t
+		}
+	}
+	b.i++
+	b.outstanding[best]++
+
+	c.Set(leastConnectionsLastTargetKey, best)
+	return best
+}
+
+// NamedTarget returns an upstream target by name and counts the request as in progress for it. Retried request is
+// not sent to this target.
+//
+// Note: `nil` is returned in case no target with the name is found.
+func (b *leastConnectionsBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, t := range b.targets {
+		if t.Name == name {
+			b.outstanding[t]++
+			c.Set(leastConnectionsLastTargetKey, t)
+			return t
+		}
+	}
+	return nil
+}
+
+// TargetDone implements TargetObserver.TargetDone by decreasing number of requests in progress for the target.
+func (b *leastConnectionsBalancer) TargetDone(c echo.Context, target *ProxyTarget, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.outstanding[target] > 0 {
+		b.outstanding[target]--
+	}
+}
+
+// AddTarget adds an upstream target to the list and to the hash ring and returns `true`.
+//
+// However, if a target with the same name already exists then the operation is aborted returning `false`.
+func (b *consistentHashBalancer) AddTarget(target *ProxyTarget) bool {
+	if !b.commonBalancer.AddTarget(target) {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rebuild()
+	return true
+}
+
+// RemoveTarget removes an upstream target from the list and from the hash ring by name.
+//
+// Returns `true` on success, `false` if no target with the name is found.
+func (b *consistentHashBalancer) RemoveTarget(name string) bool {
+	if !b.commonBalancer.RemoveTarget(name) {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rebuild()
+	return true
+}
+
+// Next returns an upstream target the request key hashes to. Retried request is sent to the next target on the
+// hash ring.
+//
+// Note: `nil` is returned in case upstream target list is empty.
+func (b *consistentHashBalancer) Next(c echo.Context) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.targets) == 0 {
+		return nil
+	} else if len(b.targets) == 1 {
+		return b.targets[0]
+	}
+
+	var i int
+	if last, ok := c.Get(consistentHashLastIndexKey).(int); ok {
+		// This request is a retry, continue to the next target on the ring
+		i = last % len(b.ring)
+		previous := b.ring[i].target
+		for b.ring[i].target == previous {
+			i = (i + 1) % len(b.ring)
+		}
+	} else {
+		h := hashProxyKey(b.key(c))
+		i = sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+		if i == len(b.ring) {
+			i = 0
+		}
+	}
+
+	c.Set(consistentHashLastIndexKey, i)
+	return b.ring[i].target
+}
+
+// NamedTarget returns an upstream target by name. Retried request is sent to the next target on the hash ring.
+//
+// Note: `nil` is returned in case no target with the name is found.
+func (b *consistentHashBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, p := range b.ring {
+		if p.target.Name == name {
+			c.Set(consistentHashLastIndexKey, i)
+			return p.target
+		}
+	}
+	return nil
+}
+
+func (b *consistentHashBalancer) key(c echo.Context) string {
+	for _, extractor := range b.extractors {
+		values, err := extractor(c)
+		if err == nil && len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return c.RealIP()
+}
+
+// rebuild recreates the hash ring from the targets. Points of a target depend only on its name (or URL) and weight
+// so other targets keep their keys when a target is added or removed.
+func (b *consistentHashBalancer) rebuild() {
+	ring := make([]consistentHashPoint, 0, len(b.targets)*b.replicas)
+	for _, t := range b.targets {
+		id := consistentHashTargetID(t)
+		points := b.replicas * proxyTargetWeight(t)
+		for i := 0; i < points; i++ {
+			ring = append(ring, consistentHashPoint{hash: hashProxyKey(id + "#" + strconv.Itoa(i)), target: t})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash == ring[j].hash {
+			return consistentHashTargetID(ring[i].target) < consistentHashTargetID(ring[j].target)
+		}
+		return ring[i].hash < ring[j].hash
+	})
+	b.ring = ring
+}
+
+// consistentHashTargetID returns string the target is placed on the hash ring by.
+func consistentHashTargetID(t *ProxyTarget) string {
+	if t.Name == "" && t.URL != nil {
+		return t.URL.String()
+	}
+	return t.Name
+}
+
+// hashProxyKey returns FNV-1a hash of the key with a finalizer spreading similar keys over the whole hash ring.
+func hashProxyKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
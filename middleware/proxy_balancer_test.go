@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBalancerContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+}
+
+func TestWeightedRoundRobinBalancer(t *testing.T) {
+	b := NewWeightedRoundRobinBalancer([]*ProxyTarget{
+		{Name: "a", Weight: 3},
+		{Name: "b"},
+	})
+
+	names := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		names = append(names, b.Next(newTestBalancerContext()).Name)
+	}
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, names)
+
+	assert.True(t, b.AddTarget(&ProxyTarget{Name: "c", Weight: 4}))
+	assert.True(t, b.RemoveTarget("a"))
+	counts := map[string]int{}
+	for i := 0; i < 10; i++ {
+		counts[b.Next(newTestBalancerContext()).Name]++
+	}
+	assert.Equal(t, map[string]int{"b": 2, "c": 8}, counts)
+}
+
+func TestWeightedRoundRobinBalancer_Retry(t *testing.T) {
+	b := NewWeightedRoundRobinBalancer([]*ProxyTarget{
+		{Name: "a", Weight: 10},
+		{Name: "b"},
+	})
+
+	c := newTestBalancerContext()
+	assert.Equal(t, "a", b.Next(c).Name)
+	assert.Equal(t, "b", b.Next(c).Name)
+	assert.Equal(t, "a", b.Next(c).Name)
+}
+
+func TestWeightedRoundRobinBalancer_UnnamedTargets(t *testing.T) {
+	a := &ProxyTarget{URL: &url.URL{Host: "a"}, Weight: 2}
+	b := &ProxyTarget{URL: &url.URL{Host: "b"}}
+	lb := NewWeightedRoundRobinBalancer([]*ProxyTarget{a, b})
+
+	targets := make([]*ProxyTarget, 0, 3)
+	for i := 0; i < 3; i++ {
+		targets = append(targets, lb.Next(newTestBalancerContext()))
+	}
+	assert.Equal(t, []*ProxyTarget{a, b, a}, targets)
+
+	c := newTestBalancerContext()
+	assert.Same(t, a, lb.Next(c))
+	assert.Same(t, b, lb.Next(c))
+}
+
+func TestLeastConnectionsBalancer(t *testing.T) {
+	a := &ProxyTarget{Name: "a"}
+	b := &ProxyTarget{Name: "b", Weight: 2}
+	lb := NewLeastConnectionsBalancer([]*ProxyTarget{a, b})
+	observer := lb.(TargetObserver)
+
+	c := newTestBalancerContext()
+	counts := map[string]int{}
+	for i := 0; i < 6; i++ {
+		counts[lb.Next(newTestBalancerContext()).Name]++
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 4}, counts)
+
+	observer.TargetDone(c, a, nil)
+	observer.TargetDone(c, a, nil)
+	assert.Equal(t, "a", lb.Next(newTestBalancerContext()).Name)
+	assert.Equal(t, "a", lb.Next(newTestBalancerContext()).Name)
+
+	// retried request goes to another target
+	c = newTestBalancerContext()
+	observer.TargetDone(c, a, nil)
+	assert.Equal(t, "a", lb.Next(c).Name)
+	assert.Equal(t, "b", lb.Next(c).Name)
+
+	assert.True(t, lb.RemoveTarget("b"))
+	observer.TargetDone(c, b, nil) // done of removed target is ignored
+	assert.Equal(t, "a", lb.Next(c).Name)
+}
+
+func TestLeastConnectionsBalancer_UnnamedTargets(t *testing.T) {
+	a := &ProxyTarget{URL: &url.URL{Host: "a"}}
+	b := &ProxyTarget{URL: &url.URL{Host: "b"}}
+	lb := NewLeastConnectionsBalancer([]*ProxyTarget{a, b})
+	observer := lb.(TargetObserver)
+
+	c := newTestBalancerContext()
+	first := lb.Next(c)
+	second := lb.Next(newTestBalancerContext())
+	assert.NotSame(t, first, second)
+
+	// retried request goes to another target even though it has more requests in progress
+	observer.TargetDone(c, first, nil)
+	assert.Same(t, second, lb.Next(c))
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	targets := make([]*ProxyTarget, 0, 4)
+	for i := 0; i < 4; i++ {
+		targets = append(targets, &ProxyTarget{Name: "target " + strconv.Itoa(i)})
+	}
+	b := NewConsistentHashBalancer(targets, ConsistentHashConfig{KeyLookup: "header:X-User-ID"})
+
+	next := func(key string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-ID", key)
+		return b.Next(echo.New().NewContext(req, httptest.NewRecorder())).Name
+	}
+
+	const keys = 1000
+	before := make([]string, keys)
+	counts := map[string]int{}
+	for i := range before {
+		before[i] = next("user-" + strconv.Itoa(i))
+		counts[before[i]]++
+	}
+	assert.Len(t, counts, 4)
+	for name, count := range counts {
+		assert.Greater(t, count, keys/8, name)
+	}
+	for i := range before {
+		assert.Equal(t, before[i], next("user-"+strconv.Itoa(i)))
+	}
+
+	// only keys of removed target are remapped
+	assert.True(t, b.RemoveTarget("target 2"))
+	for i, name := range before {
+		if name != "target 2" {
+			assert.Equal(t, name, next("user-"+strconv.Itoa(i)))
+		} else {
+			assert.NotEqual(t, "target 2", next("user-"+strconv.Itoa(i)))
+		}
+	}
+
+	// only keys of added target are remapped
+	assert.True(t, b.AddTarget(&ProxyTarget{Name: "target 2"}))
+	for i, name := range before {
+		assert.Equal(t, name, next("user-"+strconv.Itoa(i)))
+	}
+}
+
+func TestConsistentHashBalancer_DefaultKeyAndRetry(t *testing.T) {
+	b := NewConsistentHashBalancer([]*ProxyTarget{{Name: "a"}, {Name: "b"}, {Name: "c"}}, ConsistentHashConfig{})
+
+	next := func(c echo.Context, ip string) string {
+		c.Request().RemoteAddr = ip + ":1234"
+		return b.Next(c).Name
+	}
+
+	first := next(newTestBalancerContext(), "192.0.2.1")
+	assert.Equal(t, first, next(newTestBalancerContext(), "192.0.2.1"))
+
+	c := newTestBalancerContext()
+	assert.Equal(t, first, next(c, "192.0.2.1"))
+	assert.NotEqual(t, first, next(c, "192.0.2.1"))
+}
+
+func TestConsistentHashBalancer_UnnamedTargets(t *testing.T) {
+	targets := make([]*ProxyTarget, 0, 4)
+	for i := 0; i < 4; i++ {
+		targets = append(targets, &ProxyTarget{URL: &url.URL{Scheme: "http", Host: "10.0.0." + strconv.Itoa(i)}})
+	}
+	b := NewConsistentHashBalancer(targets, ConsistentHashConfig{KeyLookup: "header:X-User-ID"})
+
+	hashes := map[uint64]bool{}
+	for _, p := range b.(*consistentHashBalancer).ring {
+		hashes[p.hash] = true
+	}
+	assert.Len(t, hashes, 4*DefaultConsistentHashConfig.Replicas)
+
+	const keys = 1000
+	counts := map[*ProxyTarget]int{}
+	for i := 0; i < keys; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-ID", "user-"+strconv.Itoa(i))
+		counts[b.Next(echo.New().NewContext(req, httptest.NewRecorder()))]++
+	}
+	assert.Len(t, counts, 4)
+	for target, count := range counts {
+		assert.Greater(t, count, keys/8, target.URL.Host)
+	}
+}
+
+func TestConsistentHashBalancer_InvalidKeyLookup(t *testing.T) {
+	assert.Panics(t, func() {
+		NewConsistentHashBalancer(nil, ConsistentHashConfig{KeyLookup: "header"})
+	})
+}
+
+func TestProxyWithLeastConnectionsBalancer(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+
+	b := NewLeastConnectionsBalancer([]*ProxyTarget{{Name: "a", URL: u}, {Name: "b", URL: u}})
+	e := echo.New()
+	e.Use(Proxy(b))
+
+	for i := 0; i < 4; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Len(t, b.(*leastConnectionsBalancer).outstanding, 2)
+	for _, n := range b.(*leastConnectionsBalancer).outstanding {
+		assert.Equal(t, 0, n)
+	}
+}
@@ -0,0 +1,422 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProxyHealthCheckConfig defines the config for HealthCheckBalancer.
+type ProxyHealthCheckConfig struct {
+	// Path is requested from every target with GET method to actively check health of the targets. Target is healthy
+	// when it responds with 2xx or 3xx status. Path is resolved against target URL so it can contain query string.
+	// Optional. Default value "" (active health checking is disabled).
+	Path string
+
+	// Interval between active health checks.
+	// Optional. Default value 10 seconds.
+	Interval time.Duration
+
+	// Client is used to send active health check requests.
+	// Optional. Default value client with 5 seconds timeout.
+	Client *http.Client
+
+	// HealthyThreshold is number of consecutive successful active health checks after which unhealthy target is
+	// healthy again.
+	// Optional. Default value 2.
+	HealthyThreshold int
+
+	// UnhealthyThreshold is number of consecutive failed active health checks after which target is unhealthy.
+	// Optional. Default value 2.
+	UnhealthyThreshold int
+
+	// MaxFailures is number of consecutive failed proxied requests after which the target is ejected from the
+	// balancer. Request fails when the target is unreachable or responds with 5xx status. Set to -1 to disable
+	// passive health checking.
+	// Optional. Default value 5.
+	MaxFailures int
+
+	// EjectionDuration is time the target is ejected for the first time. Every consecutive ejection of the target
+	// doubles the duration until the target serves a request successfully.
+	// Optional. Default value 30 seconds.
+	EjectionDuration time.Duration
+
+	// MaxEjectionDuration limits time the target can be ejected for.
+	// Optional. Default value 5 minutes.
+	MaxEjectionDuration time.Duration
+}
+
+// HealthCheckBalancer wraps ProxyBalancer so that unhealthy targets are removed from the wrapped balancer and added
+// back when they are healthy again.
+//
+// Targets are checked actively by requesting configured path periodically and passively by observing outcome of
+// the proxied requests. Target that fails too many requests in a row is ejected for a time that grows exponentially
+// with every consecutive ejection.
+type HealthCheckBalancer struct {
+	balancer ProxyBalancer
+	config   ProxyHealthCheckConfig
+
+	mutex       sync.Mutex
+	targets     []*healthCheckTarget
+	nextReadmit time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	timeNow  func() time.Time
+}
+
+type healthCheckTarget struct {
+	target *ProxyTarget
+	active bool // true when the target is in the wrapped balancer
+
+	unhealthy bool // result of active health checks
+	successes int
+	failures  int
+
+	requestFailures int
+	ejections       int
+	ejectedUntil    time.Time
+}
+
+// ErrProxyNoHealthyTarget is returned by HealthCheckBalancer when there are no healthy targets.
+var ErrProxyNoHealthyTarget = echo.NewHTTPError(http.StatusServiceUnavailable, "no healthy upstream target")
+
+// DefaultProxyHealthCheckConfig is the default HealthCheckBalancer config.
+var DefaultProxyHealthCheckConfig = ProxyHealthCheckConfig{
+	Interval:            10 * time.Second,
+	HealthyThreshold:    2,
+	UnhealthyThreshold:  2,
+	MaxFailures:         5,
+	EjectionDuration:    30 * time.Second,
+	MaxEjectionDuration: 5 * time.Minute,
+}
+
+// NewHealthCheckBalancer returns a balancer checking health of the targets and passing only healthy targets to the
+// given balancer. Targets are added to the given balancer, which can be created with no targets, i.e.
+// `NewHealthCheckBalancer(NewRoundRobinBalancer(nil), targets, config)`.
+//
+// Targets must have unique non-empty names as unhealthy targets are removed from the wrapped balancer by name.
+//
+// When active health checking is enabled, checks run in a background goroutine until Stop is called.
+func NewHealthCheckBalancer(balancer ProxyBalancer, targets []*ProxyTarget, config ProxyHealthCheckConfig) *HealthCheckBalancer {
+	if balancer == nil {
+		panic("echo: health check balancer requires balancer")
+	}
+	// Defaults
+	if config.Interval <= 0 {
+		config.Interval = DefaultProxyHealthCheckConfig.Interval
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if config.HealthyThreshold <= 0 {
+		config.HealthyThreshold = DefaultProxyHealthCheckConfig.HealthyThreshold
+	}
+	if config.UnhealthyThreshold <= 0 {
+		config.UnhealthyThreshold = DefaultProxyHealthCheckConfig.UnhealthyThreshold
+	}
+	if config.MaxFailures == 0 {
+		config.MaxFailures = DefaultProxyHealthCheckConfig.MaxFailures
+	}
+	if config.EjectionDuration <= 0 {
+		config.EjectionDuration = DefaultProxyHealthCheckConfig.EjectionDuration
+	}
+	if config.MaxEjectionDuration <= 0 {
+		config.MaxEjectionDuration = DefaultProxyHealthCheckConfig.MaxEjectionDuration
+	}
+	if config.MaxEjectionDuration < config.EjectionDuration {
+		config.MaxEjectionDuration = config.EjectionDuration
+	}
+
+	b := &HealthCheckBalancer{
+		balancer: balancer,
+		config:   config,
+		stop:     make(chan struct{}),
+		timeNow:  time.Now,
+	}
+	for _, t := range targets {
+		if !b.AddTarget(t) {
+			panic("echo: health check balancer requires targets with unique non-empty names")
+		}
+	}
+	if config.Path != "" {
+		go b.run()
+	}
+	return b
+}
+
+// AddTarget adds an upstream target to the list and to the wrapped balancer and returns `true`. New target is
+// considered healthy until checked otherwise.
+//
+// However, if the target has no name or a target with the same name already exists then the operation is aborted
+// returning `false`.
+func (b *HealthCheckBalancer) AddTarget(target *ProxyTarget) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if target.Name == "" || b.find(target.Name) != nil {
+		return false
+	}
+	t := &healthCheckTarget{target: target}
+	b.targets = append(b.targets, t)
+	b.update(t, b.timeNow())
+	return true
+}
+
+// RemoveTarget removes an upstream target from the list and from the wrapped balancer by name.
+//
+// Returns `true` on success, `false` if no target with the name is found.
+func (b *HealthCheckBalancer) RemoveTarget(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, t := range b.targets {
+		if t.target.Name == name {
+			b.targets = append(b.targets[:i], b.targets[i+1:]...)
+			if t.active {
+				b.balancer.RemoveTarget(name)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns a healthy upstream target selected by the wrapped balancer.
+//
+// Note: `nil` is returned in case there are no healthy targets.
+func (b *HealthCheckBalancer) Next(c echo.Context) *ProxyTarget {
+	t, _ := b.NextTarget(c)
+	return t
+}
+
+// NextTarget returns a healthy upstream target selected by the wrapped balancer or ErrProxyNoHealthyTarget when
+// there are no healthy targets.
+func (b *HealthCheckBalancer) NextTarget(c echo.Context) (*ProxyTarget, error) {
+	b.readmit()
+	if provider, ok := b.balancer.(TargetProvider); ok {
+		t, err := provider.NextTarget(c)
+		if err != nil || t != nil {
+			return t, err
+		}
+	} else if t := b.balancer.Next(c); t != nil {
+		return t, nil
+	}
+	return nil, ErrProxyNoHealthyTarget
+}
+
+// NamedTarget returns an upstream target by name when it is healthy. Target is selected by the wrapped balancer when
+// it implements NamedTargetProvider.
+//
+// Note: `nil` is returned in case no target with the name is found or the target is unhealthy.
+func (b *HealthCheckBalancer) NamedTarget(c echo.Context, name string) *ProxyTarget {
+	if !b.Healthy(name) {
+		return nil
+	}
+	if provider, ok := b.balancer.(NamedTargetProvider); ok {
+		return provider.NamedTarget(c, name)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if t := b.find(name); t != nil {
+		return t.target
+	}
+	return nil
+}
+
+// TargetDone implements TargetObserver.TargetDone. Target unreachable or responding with 5xx status is counted as
+// failure. Observation is passed on to the wrapped balancer when it implements TargetObserver too.
+func (b *HealthCheckBalancer) TargetDone(c echo.Context, target *ProxyTarget, err error) {
+	if observer, ok := b.balancer.(TargetObserver); ok {
+		observer.TargetDone(c, target, err)
+	}
+	if b.config.MaxFailures < 0 {
+		return
+	}
+
+	failed := false
+	if err != nil {
+		var he *echo.HTTPError
+		if !errors.As(err, &he) || he.Code != http.StatusBadGateway {
+			return // not caused by the target, i.e. client closed connection
+		}
+		failed = true
+	} else {
+		failed = c.Response().Status >= http.StatusInternalServerError
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	t := b.findTarget(target)
+	if t == nil {
+		return
+	}
+	if !failed {
+		t.requestFailures = 0
+		t.ejections = 0
+		return
+	}
+	t.requestFailures++
+	if t.requestFailures < b.config.MaxFailures {
+		return
+	}
+
+	now := b.timeNow()
+	t.requestFailures = 0
+	d := b.config.EjectionDuration
+	for i := 0; i < t.ejections && d < b.config.MaxEjectionDuration; i++ {
+		d *= 2
+	}
+	if d > b.config.MaxEjectionDuration {
+		d = b.config.MaxEjectionDuration
+	}
+	t.ejections++
+	t.ejectedUntil = now.Add(d)
+	if b.nextReadmit.IsZero() || t.ejectedUntil.Before(b.nextReadmit) {
+		b.nextReadmit = t.ejectedUntil
+	}
+	b.update(t, now)
+}
+
+// Healthy returns true when the target with given name exists and is healthy.
+func (b *HealthCheckBalancer) Healthy(name string) bool {
+	b.readmit()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	t := b.find(name)
+	return t != nil && t.active
+}
+
+// Stop stops active health checking.
+func (b *HealthCheckBalancer) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+}
+
+func (b *HealthCheckBalancer) find(name string) *healthCheckTarget {
+	for _, t := range b.targets {
+		if t.target.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (b *HealthCheckBalancer) findTarget(target *ProxyTarget) *healthCheckTarget {
+	for _, t := range b.targets {
+		if t.target == target {
+			return t
+		}
+	}
+	return nil
+}
+
+// update adds the target to or removes it from the wrapped balancer according to its health.
+func (b *HealthCheckBalancer) update(t *healthCheckTarget, now time.Time) {
+	healthy := !t.unhealthy && !now.Before(t.ejectedUntil)
+	if healthy == t.active {
+		return
+	}
+	if healthy {
+		b.balancer.AddTarget(t.target)
+	} else {
+		b.balancer.RemoveTarget(t.target.Name)
+	}
+	t.active = healthy
+}
+
+// readmit adds targets with expired ejection back to the wrapped balancer.
+func (b *HealthCheckBalancer) readmit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.timeNow()
+	if b.nextReadmit.IsZero() || now.Before(b.nextReadmit) {
+		return
+	}
+	b.nextReadmit = time.Time{}
+	for _, t := range b.targets {
+		b.update(t, now)
+		if now.Before(t.ejectedUntil) && (b.nextReadmit.IsZero() || t.ejectedUntil.Before(b.nextReadmit)) {
+			b.nextReadmit = t.ejectedUntil
+		}
+	}
+}
+
+func (b *HealthCheckBalancer) run() {
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+	for {
+		b.check()
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs active health check of all targets concurrently.
+func (b *HealthCheckBalancer) check() {
+	b.mutex.Lock()
+	targets := make([]*ProxyTarget, len(b.targets))
+	for i, t := range b.targets {
+		targets[i] = t.target
+	}
+	b.mutex.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target *ProxyTarget) {
+			defer wg.Done()
+			healthy := b.probe(target)
+
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			t := b.findTarget(target)
+			if t == nil {
+				return // removed while being checked
+			}
+			if healthy {
+				t.successes++
+				t.failures = 0
+				if t.unhealthy && t.successes >= b.config.HealthyThreshold {
+					t.unhealthy = false
+				}
+			} else {
+				t.failures++
+				t.successes = 0
+				if !t.unhealthy && t.failures >= b.config.UnhealthyThreshold {
+					t.unhealthy = true
+				}
+			}
+			b.update(t, b.timeNow())
+		}(target)
+	}
+	wg.Wait()
+}
+
+func (b *HealthCheckBalancer) probe(target *ProxyTarget) bool {
+	ref, err := url.Parse(b.config.Path)
+	if err != nil {
+		return false
+	}
+	req, err := http.NewRequest(http.MethodGet, target.URL.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return false
+	}
+	res, err := b.config.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024)) // allow connection reuse
+	return res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusBadRequest
+}
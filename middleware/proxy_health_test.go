@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckBalancer_PassiveEjection(t *testing.T) {
+	url1, _ := url.Parse("http://127.0.0.1:27121")
+	url2, _ := url.Parse("http://127.0.0.1:27122")
+	target1 := &ProxyTarget{Name: "target 1", URL: url1}
+	target2 := &ProxyTarget{Name: "target 2", URL: url2}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewHealthCheckBalancer(NewRoundRobinBalancer(nil), []*ProxyTarget{target1, target2}, ProxyHealthCheckConfig{
+		MaxFailures:         2,
+		EjectionDuration:    10 * time.Second,
+		MaxEjectionDuration: 30 * time.Second,
+	})
+	b.timeNow = func() time.Time { return now }
+
+	e := echo.New()
+	fail := func(target *ProxyTarget) {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		b.TargetDone(c, target, echo.NewHTTPError(http.StatusBadGateway, "unreachable"))
+	}
+	nextNames := func() []string {
+		names := make([]string, 0, 4)
+		for i := 0; i < 4; i++ {
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			names = append(names, b.Next(c).Name)
+		}
+		return names
+	}
+
+	fail(target1)
+	assert.True(t, b.Healthy("target 1"))
+	fail(target1)
+	assert.False(t, b.Healthy("target 1"))
+	assert.Equal(t, []string{"target 2", "target 2", "target 2", "target 2"}, nextNames())
+
+	// readmitted after ejection duration
+	now = now.Add(10 * time.Second)
+	assert.True(t, b.Healthy("target 1"))
+	assert.ElementsMatch(t, []string{"target 1", "target 1", "target 2", "target 2"}, nextNames())
+
+	// second ejection lasts twice as long
+	fail(target1)
+	fail(target1)
+	now = now.Add(19 * time.Second)
+	assert.False(t, b.Healthy("target 1"))
+	now = now.Add(time.Second)
+	assert.True(t, b.Healthy("target 1"))
+
+	// ejection duration is capped
+	fail(target1)
+	fail(target1)
+	now = now.Add(30 * time.Second)
+	assert.True(t, b.Healthy("target 1"))
+
+	// success resets ejection duration
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.Response().WriteHeader(http.StatusOK)
+	b.TargetDone(c, target1, nil)
+	fail(target1)
+	fail(target1)
+	now = now.Add(10 * time.Second)
+	assert.True(t, b.Healthy("target 1"))
+}
+
+func TestHealthCheckBalancer_TargetDone(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		status        int
+		err           error
+		expectHealthy bool
+	}{
+		{
+			name:          "ok, 5xx response is failure",
+			status:        http.StatusServiceUnavailable,
+			expectHealthy: false,
+		},
+		{
+			name:          "ok, unreachable target is failure",
+			err:           echo.NewHTTPError(http.StatusBadGateway, "unreachable"),
+			expectHealthy: false,
+		},
+		{
+			name:          "ok, 4xx response is not failure",
+			status:        http.StatusNotFound,
+			expectHealthy: true,
+		},
+		{
+			name:          "ok, client closed connection is not failure",
+			err:           echo.NewHTTPError(StatusCodeContextCanceled, "client closed connection"),
+			expectHealthy: true,
+		},
+		{
+			name:          "ok, internal error is not failure",
+			err:           errors.New("hijack error"),
+			expectHealthy: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _ := url.Parse("http://127.0.0.1:27121")
+			target := &ProxyTarget{Name: "target", URL: u}
+			b := NewHealthCheckBalancer(NewRandomBalancer(nil), []*ProxyTarget{target}, ProxyHealthCheckConfig{MaxFailures: 1})
+
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			if tc.status != 0 {
+				c.Response().WriteHeader(tc.status)
+			}
+			b.TargetDone(c, target, tc.err)
+
+			assert.Equal(t, tc.expectHealthy, b.Healthy("target"))
+		})
+	}
+}
+
+func TestHealthCheckBalancer_NoHealthyTarget(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:27121")
+	target := &ProxyTarget{Name: "target", URL: u}
+	b := NewHealthCheckBalancer(NewRandomBalancer(nil), []*ProxyTarget{target}, ProxyHealthCheckConfig{MaxFailures: 1})
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	b.TargetDone(c, target, echo.NewHTTPError(http.StatusBadGateway, "unreachable"))
+
+	tgt, err := b.NextTarget(c)
+	assert.Nil(t, tgt)
+	assert.Equal(t, ErrProxyNoHealthyTarget, err)
+	assert.Nil(t, b.Next(c))
+}
+
+func TestHealthCheckBalancer_AddRemoveTarget(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:27121")
+	rb := NewRoundRobinBalancer(nil)
+	b := NewHealthCheckBalancer(rb, nil, ProxyHealthCheckConfig{})
+
+	assert.True(t, b.AddTarget(&ProxyTarget{Name: "target", URL: u}))
+	assert.False(t, b.AddTarget(&ProxyTarget{Name: "target", URL: u}))
+	assert.Equal(t, "target", rb.Next(nil).Name)
+
+	assert.True(t, b.RemoveTarget("target"))
+	assert.False(t, b.RemoveTarget("target"))
+	assert.Nil(t, rb.Next(nil))
+	assert.False(t, b.Healthy("target"))
+}
+
+func TestHealthCheckBalancer_TargetNames(t *testing.T) {
+	u1, _ := url.Parse("http://127.0.0.1:27121")
+	u2, _ := url.Parse("http://127.0.0.1:27122")
+
+	assert.PanicsWithValue(t, "echo: health check balancer requires targets with unique non-empty names", func() {
+		NewHealthCheckBalancer(NewRoundRobinBalancer(nil), []*ProxyTarget{{URL: u1}, {URL: u2}}, ProxyHealthCheckConfig{})
+	})
+	assert.PanicsWithValue(t, "echo: health check balancer requires targets with unique non-empty names", func() {
+		NewHealthCheckBalancer(NewRoundRobinBalancer(nil), []*ProxyTarget{{Name: "a", URL: u1}, {Name: "a", URL: u2}}, ProxyHealthCheckConfig{})
+	})
+
+	rb := NewRoundRobinBalancer(nil)
+	b := NewHealthCheckBalancer(rb, nil, ProxyHealthCheckConfig{})
+	assert.False(t, b.AddTarget(&ProxyTarget{URL: u1}))
+	assert.Nil(t, rb.Next(nil))
+}
+
+func TestHealthCheckBalancer_ActiveCheck(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || r.URL.RawQuery != "full=1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL + "/api/")
+
+	b := NewHealthCheckBalancer(NewRandomBalancer(nil), []*ProxyTarget{{Name: "target", URL: u}}, ProxyHealthCheckConfig{
+		Path:     "/healthz?full=1",
+		Interval: time.Hour,
+	})
+	defer b.Stop()
+
+	b.check()
+	assert.True(t, b.Healthy("target"))
+
+	healthy.Store(false)
+	b.check()
+	assert.True(t, b.Healthy("target"))
+	b.check()
+	assert.False(t, b.Healthy("target"))
+
+	healthy.Store(true)
+	b.check()
+	assert.False(t, b.Healthy("target"))
+	b.check()
+	assert.True(t, b.Healthy("target"))
+}
+
+func TestHealthCheckBalancer_ActiveCheckUnreachable(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:27121")
+	b := NewHealthCheckBalancer(NewRandomBalancer(nil), []*ProxyTarget{{Name: "target", URL: u}}, ProxyHealthCheckConfig{
+		Path:               "/healthz",
+		Interval:           10 * time.Millisecond,
+		UnhealthyThreshold: 1,
+	})
+	defer b.Stop()
+
+	assert.Eventually(t, func() bool {
+		return !b.Healthy("target")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProxyWithHealthCheckBalancer(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("good"))
+	}))
+	defer upstream.Close()
+	goodURL, _ := url.Parse(upstream.URL)
+	badURL, _ := url.Parse("http://127.0.0.1:27121")
+
+	b := NewHealthCheckBalancer(NewRoundRobinBalancer(nil), []*ProxyTarget{
+		{Name: "bad", URL: badURL},
+		{Name: "good", URL: goodURL},
+	}, ProxyHealthCheckConfig{MaxFailures: 1})
+
+	e := echo.New()
+	e.Use(Proxy(b))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.False(t, b.Healthy("bad"))
+
+	for i := 0; i < 3; i++ {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "good", rec.Body.String())
+	}
+}
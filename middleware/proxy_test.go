@@ -810,3 +810,206 @@ func TestModifyResponseUseContext(t *testing.T) {
 	assert.Equal(t, "OK", rec.Body.String())
 	assert.Equal(t, "CUSTOM_BALANCER", rec.Header().Get("FROM_BALANCER"))
 }
+
+func TestProxyStickySession(t *testing.T) {
+	newServer := func(name string) *ProxyTarget {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		t.Cleanup(server.Close)
+		u, _ := url.Parse(server.URL)
+		return &ProxyTarget{Name: name, URL: u}
+	}
+	unreachableURL, _ := url.Parse("http://127.0.0.1:27121")
+
+	var testCases = []struct {
+		name         string
+		whenCookie   string
+		whenRetry    int
+		givenTargets func() []*ProxyTarget
+		expectBody   string
+		expectCookie string
+	}{
+		{
+			name:         "ok, cookie is set for the target selected by balancer",
+			givenTargets: func() []*ProxyTarget { return []*ProxyTarget{newServer("target 1"), newServer("target 2")} },
+			expectBody:   "target 1",
+			expectCookie: "_echo_proxy_target=target+1; Path=/; HttpOnly",
+		},
+		{
+			name:         "ok, request is proxied to the target from cookie",
+			whenCookie:   "target+2",
+			givenTargets: func() []*ProxyTarget { return []*ProxyTarget{newServer("target 1"), newServer("target 2")} },
+			expectBody:   "target 2",
+		},
+		{
+			name:         "ok, unknown target from cookie fails over to balancer",
+			whenCookie:   "target+3",
+			givenTargets: func() []*ProxyTarget { return []*ProxyTarget{newServer("target 1"), newServer("target 2")} },
+			expectBody:   "target 1",
+			expectCookie: "_echo_proxy_target=target+1; Path=/; HttpOnly",
+		},
+		{
+			name:       "ok, failed target from cookie is retried with balancer",
+			whenCookie: "target+2",
+			whenRetry:  1,
+			givenTargets: func() []*ProxyTarget {
+				return []*ProxyTarget{newServer("target 1"), {Name: "target 2", URL: unreachableURL}}
+			},
+			expectBody:   "target 1",
+			expectCookie: "_echo_proxy_target=target+1; Path=/; HttpOnly",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(ProxyWithConfig(ProxyConfig{
+				Balancer:      NewRoundRobinBalancer(tc.givenTargets()),
+				RetryCount:    tc.whenRetry,
+				StickySession: &ProxyStickySessionConfig{},
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.whenCookie != "" {
+				req.AddCookie(&http.Cookie{Name: "_echo_proxy_target", Value: tc.whenCookie})
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+			if tc.expectCookie == "" {
+				assert.Empty(t, rec.Header().Values(echo.HeaderSetCookie))
+			} else {
+				assert.Equal(t, []string{tc.expectCookie}, rec.Header().Values(echo.HeaderSetCookie))
+			}
+		})
+	}
+}
+
+func TestProxyStickySessionWithHealthCheckBalancer(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("good"))
+	}))
+	defer upstream.Close()
+	goodURL, _ := url.Parse(upstream.URL)
+	badURL, _ := url.Parse("http://127.0.0.1:27121")
+
+	b := NewHealthCheckBalancer(NewRoundRobinBalancer(nil), []*ProxyTarget{
+		{Name: "bad", URL: badURL},
+		{Name: "good", URL: goodURL},
+	}, ProxyHealthCheckConfig{MaxFailures: 1})
+
+	e := echo.New()
+	e.Use(ProxyWithConfig(ProxyConfig{
+		Balancer:      b,
+		StickySession: &ProxyStickySessionConfig{CookieName: "affinity", CookieMaxAge: 60},
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "affinity", Value: "bad"})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send()
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+
+	// unhealthy sticky target fails over to healthy one
+	rec = send()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "good", rec.Body.String())
+	assert.Equal(t, "affinity=good; Path=/; Max-Age=60; HttpOnly", rec.Header().Get(echo.HeaderSetCookie))
+}
+
+func TestProxyStickySessionRetryExcludesStickyTarget(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("good"))
+	}))
+	defer upstream.Close()
+	goodURL, _ := url.Parse(upstream.URL)
+	badURL, _ := url.Parse("http://127.0.0.1:27121")
+	targets := func() []*ProxyTarget {
+		return []*ProxyTarget{{Name: "bad", URL: badURL}, {Name: "good", URL: goodURL}}
+	}
+
+	var testCases = []struct {
+		name          string
+		givenBalancer ProxyBalancer
+	}{
+		{name: "round robin", givenBalancer: NewRoundRobinBalancer(targets())},
+		{name: "weighted round robin", givenBalancer: NewWeightedRoundRobinBalancer(targets())},
+		{name: "least connections", givenBalancer: NewLeastConnectionsBalancer(targets())},
+		{name: "consistent hash", givenBalancer: NewConsistentHashBalancer(targets(), ConsistentHashConfig{})},
+		{name: "health check", givenBalancer: NewHealthCheckBalancer(NewRoundRobinBalancer(nil), targets(), ProxyHealthCheckConfig{})},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(ProxyWithConfig(ProxyConfig{
+				Balancer:      tc.givenBalancer,
+				RetryCount:    1,
+				StickySession: &ProxyStickySessionConfig{},
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(&http.Cookie{Name: "_echo_proxy_target", Value: "bad"})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "good", rec.Body.String())
+			assert.Equal(t, []string{"_echo_proxy_target=good; Path=/; HttpOnly"}, rec.Header().Values(echo.HeaderSetCookie))
+		})
+	}
+}
+
+func TestProxyStickySessionUnnamedTarget(t *testing.T) {
+	newURL := func(body string) *url.URL {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		u, _ := url.Parse(server.URL)
+		return u
+	}
+	namedURL := newURL("named")
+	unnamedURL := newURL("unnamed")
+
+	do := func(targets []*ProxyTarget, cookie *http.Cookie) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.Use(ProxyWithConfig(ProxyConfig{
+			Balancer:      NewRoundRobinBalancer(targets),
+			StickySession: &ProxyStickySessionConfig{},
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// no cookie is set for target without name
+	rec := do([]*ProxyTarget{{URL: unnamedURL}}, nil)
+	assert.Equal(t, "unnamed", rec.Body.String())
+	assert.Empty(t, rec.Header().Values(echo.HeaderSetCookie))
+
+	// empty cookie does not select target without name
+	rec = do([]*ProxyTarget{{Name: "named", URL: namedURL}, {URL: unnamedURL}}, &http.Cookie{Name: "_echo_proxy_target", Value: ""})
+	assert.Equal(t, "named", rec.Body.String())
+	assert.Equal(t, []string{"_echo_proxy_target=named; Path=/; HttpOnly"}, rec.Header().Values(echo.HeaderSetCookie))
+}
+
+func TestProxyStickySessionRequiresNamedTargetProvider(t *testing.T) {
+	assert.PanicsWithValue(t, "echo: proxy middleware sticky session requires balancer implementing NamedTargetProvider", func() {
+		ProxyWithConfig(ProxyConfig{
+			Balancer:      &customBalancer{},
+			StickySession: &ProxyStickySessionConfig{},
+		})
+	})
+}
@@ -0,0 +1,372 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionConfig defines the config for Session middleware.
+type SessionConfig struct {
+	// Skipper defines a function to skip middleware.
+	Skipper Skipper
+
+	// Store persists session data. Use NewSessionCookieStore to store data in the session cookie itself,
+	// NewSessionMemoryStore to keep data in the server memory or implement SessionStore for external backends.
+	// Required.
+	Store SessionStore
+
+	// MaxAge is time after session expires when it is not modified.
+	// Optional. Default value 24 hours.
+	MaxAge time.Duration
+
+	// Name of the session cookie.
+	// Optional. Default value "session".
+	CookieName string
+
+	// Domain of the session cookie.
+	// Optional. Default value none.
+	CookieDomain string
+
+	// Path of the session cookie.
+	// Optional. Default value "/".
+	CookiePath string
+
+	// Indicates if session cookie is secure. Session cookie is always HTTP only.
+	// Optional. Default value false.
+	CookieSecure bool
+
+	// Indicates SameSite mode of the session cookie.
+	// Optional. Default value http.SameSiteLaxMode.
+	CookieSameSite http.SameSite
+}
+
+// SessionStore is the interface to be implemented by session stores.
+type SessionStore interface {
+	// Load returns session referenced by the session cookie value. Returns ErrSessionNotFound when session does not
+	// exist, has expired or cookie value is not valid.
+	Load(cookieValue string) (*SessionData, error)
+	// Save persists the session and returns value for the session cookie.
+	Save(data *SessionData) (string, error)
+	// Delete removes the session from the store.
+	Delete(data *SessionData) error
+}
+
+// SessionData is session state persisted by SessionStore. Values are encoded with `encoding/gob` by the built-in
+// cookie store so custom types stored in the session must be registered with `gob.Register`.
+type SessionData struct {
+	ID        string
+	Values    map[string]interface{}
+	Flashes   []interface{}
+	ExpiresAt time.Time
+}
+
+// ErrSessionNotFound is returned by SessionStore when session does not exist.
+var ErrSessionNotFound = errors.New("session not found")
+
+// DefaultSessionConfig is the default Session middleware config.
+var DefaultSessionConfig = SessionConfig{
+	Skipper:        DefaultSkipper,
+	MaxAge:         24 * time.Hour,
+	CookieName:     "session",
+	CookiePath:     "/",
+	CookieSameSite: http.SameSiteLaxMode,
+}
+
+// sessionContextKey is key of the session in the context. It is fixed so GetSession and CSRF middleware can find it.
+const sessionContextKey = "_session"
+
+// sessionCSRFKey is key of the CSRF token in session values.
+const sessionCSRFKey = "_csrf"
+
+// SessionState is the session of the current request. It is safe for concurrent use.
+type SessionState struct {
+	mutex sync.Mutex
+	data  *SessionData
+
+	isNew    bool
+	modified bool
+	// expireCookie is set when session is destroyed and the client should remove the cookie
+	expireCookie bool
+	// obsolete are sessions that are removed from the store when session is saved (regenerated or destroyed)
+	obsolete []*SessionData
+	saved    bool
+	// csrf is set by CSRF middleware so that token rotated by RegenerateID is updated also in the context
+	csrf *sessionCSRF
+}
+
+type sessionCSRF struct {
+	c           echo.Context
+	contextKey  string
+	tokenLength uint8
+}
+
+// Session returns a Session middleware.
+//
+// Session is accessible in handlers with `GetSession(c)` and it is saved before the response is written.
+//
+// Example:
+//
+//	e.Use(middleware.Session(middleware.NewSessionCookieStore([]byte("32-byte-long-secret-key-12345678"))))
+//	e.POST("/login", func(c echo.Context) error {
+//		sess := middleware.GetSession(c)
+//		if err := sess.RegenerateID(); err != nil { // privilege change
+//			return err
+//		}
+//		sess.Set("user", "jon")
+//		sess.AddFlash("Welcome back!")
+//		return c.Redirect(http.StatusSeeOther, "/")
+//	})
+func Session(store SessionStore) echo.MiddlewareFunc {
+	c := DefaultSessionConfig
+	c.Store = store
+	return SessionWithConfig(c)
+}
+
+// SessionWithConfig returns a Session middleware with config.
+// See `Session()`.
+func SessionWithConfig(config SessionConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultSessionConfig.Skipper
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultSessionConfig.MaxAge
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultSessionConfig.CookieName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = DefaultSessionConfig.CookiePath
+	}
+	if config.CookieSameSite == 0 {
+		config.CookieSameSite = DefaultSessionConfig.CookieSameSite
+	}
+	if config.CookieSameSite == http.SameSiteNoneMode {
+		config.CookieSecure = true
+	}
+	if config.Store == nil {
+		panic("echo: session middleware requires a store")
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			sess, err := loadSession(c, config)
+			if err != nil {
+				return err
+			}
+			c.Set(sessionContextKey, sess)
+
+			// session cookie must be written before headers are sent
+			c.Response().Before(func() {
+				if err := sess.save(c, config); err != nil {
+					c.Logger().Error(err)
+				}
+			})
+
+			err = next(c)
+			if !c.Response().Committed {
+				if sErr := sess.save(c, config); sErr != nil && err == nil {
+					return sErr
+				}
+			}
+			return err
+		}
+	}
+}
+
+// GetSession returns session of the request. Returns nil when Session middleware is not used for the request.
+func GetSession(c echo.Context) *SessionState {
+	s, _ := c.Get(sessionContextKey).(*SessionState)
+	return s
+}
+
+func loadSession(c echo.Context, config SessionConfig) (*SessionState, error) {
+	if cookie, err := c.Cookie(config.CookieName); err == nil {
+		data, err := config.Store.Load(cookie.Value)
+		if err == nil && time.Now().Before(data.ExpiresAt) {
+			if data.Values == nil {
+				data.Values = map[string]interface{}{}
+			}
+			return &SessionState{data: data}, nil
+		}
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return nil, &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Internal: err}
+		}
+	}
+	return &SessionState{data: newSessionData(), isNew: true}, nil
+}
+
+func newSessionData() *SessionData {
+	return &SessionData{ID: randomString(32), Values: map[string]interface{}{}}
+}
+
+// ID returns session ID.
+func (s *SessionState) ID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.data.ID
+}
+
+// IsNew returns true when session was created for this request.
+func (s *SessionState) IsNew() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.isNew
+}
+
+// Get returns session value by key. Returns nil when value does not exist.
+func (s *SessionState) Get(key string) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.data.Values[key]
+}
+
+// Set sets session value.
+func (s *SessionState) Set(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+// Delete removes session value.
+func (s *SessionState) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.modified = true
+	}
+}
+
+// AddFlash adds flash message to the session. Flash messages are removed from the session when they are read.
+func (s *SessionState) AddFlash(value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Flashes = append(s.data.Flashes, value)
+	s.modified = true
+}
+
+// Flashes returns flash messages and removes them from the session.
+func (s *SessionState) Flashes() []interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	flashes := s.data.Flashes
+	if len(flashes) > 0 {
+		s.data.Flashes = nil
+		s.modified = true
+	}
+	return flashes
+}
+
+// RegenerateID changes session ID while keeping session values. It should be called on privilege change (i.e. login)
+// to prevent session fixation. Session stored with previous ID is deleted and CSRF token of the session is rotated.
+// Rotated CSRF token is available in the context under CSRF middleware ContextKey right away.
+func (s *SessionState) RegenerateID() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.saved {
+		return errors.New("echo: session can not be regenerated after it has been saved")
+	}
+	if !s.isNew {
+		old := *s.data
+		s.obsolete = append(s.obsolete, &old)
+	}
+	values := make(map[string]interface{}, len(s.data.Values))
+	for k, v := range s.data.Values {
+		values[k] = v
+	}
+	delete(values, sessionCSRFKey)
+	if s.csrf != nil {
+		token := randomString(s.csrf.tokenLength)
+		values[sessionCSRFKey] = token
+		s.csrf.c.Set(s.csrf.contextKey, token)
+	}
+	s.data = &SessionData{ID: randomString(32), Values: values, Flashes: s.data.Flashes}
+	s.modified = true
+	return nil
+}
+
+// Destroy removes all session data from the store and expires session cookie. Values set after Destroy are stored in
+// a new session.
+func (s *SessionState) Destroy() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.isNew {
+		s.obsolete = append(s.obsolete, s.data)
+		s.expireCookie = true
+	}
+	s.data = newSessionData()
+	s.isNew = true
+	s.modified = false
+}
+
+// csrfToken returns CSRF token of the session creating one when it does not exist. Token is stored in the context
+// with contextKey by CSRF middleware and by RegenerateID when the token is rotated.
+func (s *SessionState) csrfToken(c echo.Context, contextKey string, length uint8) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.csrf = &sessionCSRF{c: c, contextKey: contextKey, tokenLength: length}
+	if token, ok := s.data.Values[sessionCSRFKey].(string); ok && token != "" {
+		return token
+	}
+	token := randomString(length)
+	s.data.Values[sessionCSRFKey] = token
+	s.modified = true
+	return token
+}
+
+func (s *SessionState) save(c echo.Context, config SessionConfig) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.saved {
+		return nil
+	}
+	s.saved = true
+
+	for _, old := range s.obsolete {
+		if err := config.Store.Delete(old); err != nil {
+			return err
+		}
+	}
+
+	cookie := &http.Cookie{
+		Name:     config.CookieName,
+		Path:     config.CookiePath,
+		Domain:   config.CookieDomain,
+		Secure:   config.CookieSecure,
+		HttpOnly: true,
+		SameSite: config.CookieSameSite,
+	}
+	if !s.modified {
+		if s.expireCookie {
+			cookie.MaxAge = -1
+			c.SetCookie(cookie)
+		}
+		return nil
+	}
+
+	s.data.ExpiresAt = time.Now().Add(config.MaxAge)
+	value, err := config.Store.Save(s.data)
+	if err != nil {
+		return err
+	}
+	cookie.Value = value
+	cookie.Expires = s.data.ExpiresAt
+	cookie.MaxAge = int(config.MaxAge / time.Second)
+	c.SetCookie(cookie)
+	// Protect clients from caching the response
+	echo.AddVary(c.Response().Header(), echo.HeaderCookie)
+	return nil
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionCookieStore stores session data in the session cookie. Data is encrypted with AES-GCM and signed with
+// HMAC-SHA256 (see `echo.CookieKeyRing`) so clients can neither read nor modify it. As the data is not stored on the
+// server, regenerated or destroyed sessions can not be revoked and a copy of an old cookie stays valid until it expires.
+//
+// Keys are used for key rotation: first key is used to encrypt new cookies and all keys are tried when decrypting.
+// To rotate keys add new key to the beginning of the list and remove the oldest key after session MaxAge has passed.
+type SessionCookieStore struct {
+	keys *echo.CookieKeyRing
+}
+
+// sessionCookieStoreName is name the session data is signed for. Store does not know the actual cookie name.
+const sessionCookieStoreName = "echo session"
+
+// NewSessionCookieStore returns SessionCookieStore using given keys. Keys must be at least 32 bytes long.
+func NewSessionCookieStore(keys ...[]byte) *SessionCookieStore {
+	if len(keys) == 0 {
+		panic("echo: session cookie store requires at least one key")
+	}
+	for _, key := range keys {
+		if len(key) < 32 {
+			panic("echo: session cookie store key must be at least 32 bytes long")
+		}
+	}
+	ring, err := echo.NewCookieKeyRing(keys...)
+	if err != nil {
+		panic(err)
+	}
+	return &SessionCookieStore{keys: ring}
+}
+
+// Load implements SessionStore.Load
+func (s *SessionCookieStore) Load(cookieValue string) (*SessionData, error) {
+	plain, err := s.keys.Decode(sessionCookieStoreName, cookieValue, time.Now())
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	data := new(SessionData)
+	if err := gob.NewDecoder(strings.NewReader(plain)).Decode(data); err != nil {
+		return nil, ErrSessionNotFound
+	}
+	return data, nil
+}
+
+// Save implements SessionStore.Save
+func (s *SessionCookieStore) Save(data *SessionData) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return "", err
+	}
+	value, err := s.keys.Encode(sessionCookieStoreName, buf.String(), data.ExpiresAt, true)
+	if errors.Is(err, echo.ErrSecureCookieTooLarge) {
+		return "", errors.New("echo: session data is too large to be stored in a cookie")
+	}
+	return value, err
+}
+
+// Delete implements SessionStore.Delete. Cookie sessions are removed by expiring the cookie so this is no-op.
+func (s *SessionCookieStore) Delete(_ *SessionData) error {
+	return nil
+}
+
+// SessionMemoryStore stores sessions in the server memory. Session cookie contains only the session ID. Sessions are
+// lost when the server is restarted and are not shared between server instances.
+type SessionMemoryStore struct {
+	mutex       sync.Mutex
+	sessions    map[string]*SessionData
+	lastCleanup time.Time
+
+	timeNow func() time.Time
+}
+
+// sessionMemoryStoreCleanupInterval is interval of removing expired sessions from SessionMemoryStore.
+const sessionMemoryStoreCleanupInterval = time.Minute
+
+// NewSessionMemoryStore returns new SessionMemoryStore.
+func NewSessionMemoryStore() *SessionMemoryStore {
+	return &SessionMemoryStore{
+		sessions: map[string]*SessionData{},
+		timeNow:  time.Now,
+	}
+}
+
+// Load implements SessionStore.Load
+func (s *SessionMemoryStore) Load(cookieValue string) (*SessionData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	s.cleanup(now)
+	data, ok := s.sessions[cookieValue]
+	if !ok || !now.Before(data.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return copySessionData(data), nil
+}
+
+// Save implements SessionStore.Save
+func (s *SessionMemoryStore) Save(data *SessionData) (string, error) {
+	if data.ID == "" {
+		return "", errors.New("echo: session ID is empty")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sessions[data.ID] = copySessionData(data)
+	return data.ID, nil
+}
+
+// Delete implements SessionStore.Delete
+func (s *SessionMemoryStore) Delete(data *SessionData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, data.ID)
+	return nil
+}
+
+func (s *SessionMemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < sessionMemoryStoreCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+	for id, data := range s.sessions {
+		if !now.Before(data.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// copySessionData copies session so concurrent requests of the same session do not share values map.
+func copySessionData(data *SessionData) *SessionData {
+	c := *data
+	c.Values = make(map[string]interface{}, len(data.Values))
+	for k, v := range data.Values {
+		c.Values[k] = v
+	}
+	c.Flashes = append([]interface{}(nil), data.Flashes...)
+	return &c
+}
@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testSessionKey1 = []byte("0123456789abcdef0123456789abcdef")
+	testSessionKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+// sessionTestClient sends requests to echo instance and keeps cookies between them like browser does.
+type sessionTestClient struct {
+	e       *echo.Echo
+	cookies map[string]*http.Cookie
+}
+
+func newSessionTestClient(e *echo.Echo) *sessionTestClient {
+	return &sessionTestClient{e: e, cookies: map[string]*http.Cookie{}}
+}
+
+func (c *sessionTestClient) do(method string, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.e.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+			continue
+		}
+		c.cookies[cookie.Name] = cookie
+	}
+	return rec
+}
+
+func newSessionTestEcho(store SessionStore) *echo.Echo {
+	e := echo.New()
+	e.Use(Session(store))
+	e.GET("/set", func(c echo.Context) error {
+		sess := GetSession(c)
+		sess.Set("name", c.QueryParam("name"))
+		sess.AddFlash("saved")
+		return c.String(http.StatusOK, sess.ID())
+	})
+	e.GET("/get", func(c echo.Context) error {
+		sess := GetSession(c)
+		name, _ := sess.Get("name").(string)
+		flashes := sess.Flashes()
+		return c.JSON(http.StatusOK, map[string]interface{}{"name": name, "flashes": flashes, "new": sess.IsNew()})
+	})
+	e.GET("/login", func(c echo.Context) error {
+		sess := GetSession(c)
+		if err := sess.RegenerateID(); err != nil {
+			return err
+		}
+		sess.Set("user", "jon")
+		return c.String(http.StatusOK, sess.ID())
+	})
+	e.GET("/logout", func(c echo.Context) error {
+		GetSession(c).Destroy()
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/delete", func(c echo.Context) error {
+		GetSession(c).Delete("name")
+		return errors.New("handler error")
+	})
+	return e
+}
+
+func TestSession(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		givenStore func() SessionStore
+	}{
+		{name: "cookie store", givenStore: func() SessionStore { return NewSessionCookieStore(testSessionKey1) }},
+		{name: "memory store", givenStore: func() SessionStore { return NewSessionMemoryStore() }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.givenStore()
+			client := newSessionTestClient(newSessionTestEcho(store))
+
+			// session without values is not stored
+			rec := client.do(http.MethodGet, "/get", nil)
+			assert.JSONEq(t, `{"name":"","flashes":null,"new":true}`, rec.Body.String())
+			assert.Empty(t, rec.Result().Cookies())
+
+			rec = client.do(http.MethodGet, "/set?name=jon", nil)
+			id := rec.Body.String()
+			cookie := client.cookies["session"]
+			if assert.NotNil(t, cookie) {
+				assert.True(t, cookie.HttpOnly)
+				assert.Equal(t, "/", cookie.Path)
+				assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+				assert.Equal(t, 86400, cookie.MaxAge)
+			}
+			assert.Equal(t, echo.HeaderCookie, rec.Header().Get(echo.HeaderVary))
+
+			// flashes are removed after they are read
+			rec = client.do(http.MethodGet, "/get", nil)
+			assert.JSONEq(t, `{"name":"jon","flashes":["saved"],"new":false}`, rec.Body.String())
+			rec = client.do(http.MethodGet, "/get", nil)
+			assert.JSONEq(t, `{"name":"jon","flashes":null,"new":false}`, rec.Body.String())
+
+			// regenerated session keeps values but old session is no longer valid
+			oldCookie := client.cookies["session"]
+			rec = client.do(http.MethodGet, "/login", nil)
+			assert.NotEqual(t, id, rec.Body.String())
+			rec = client.do(http.MethodGet, "/get", nil)
+			assert.JSONEq(t, `{"name":"jon","flashes":null,"new":false}`, rec.Body.String())
+			if _, ok := store.(*SessionMemoryStore); ok {
+				_, err := store.Load(oldCookie.Value)
+				assert.ErrorIs(t, err, ErrSessionNotFound)
+			}
+
+			// session is saved when handler returns error and response is written by error handler
+			rec = client.do(http.MethodGet, "/delete", nil)
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+			rec = client.do(http.MethodGet, "/get", nil)
+			assert.JSONEq(t, `{"name":"","flashes":null,"new":false}`, rec.Body.String())
+
+			// destroyed session cookie is expired
+			rec = client.do(http.MethodGet, "/logout", nil)
+			assert.Contains(t, rec.Header().Get(echo.HeaderSetCookie), "Max-Age=0")
+			assert.Empty(t, client.cookies)
+		})
+	}
+}
+
+func TestSessionWithConfig_invalidCookie(t *testing.T) {
+	e := newSessionTestEcho(NewSessionCookieStore(testSessionKey1))
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "tampered"})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":"","flashes":null,"new":true}`, rec.Body.String())
+}
+
+type failingSessionStore struct {
+	SessionMemoryStore
+}
+
+func (s *failingSessionStore) Load(_ string) (*SessionData, error) {
+	return nil, errors.New("backend is down")
+}
+
+func TestSessionWithConfig_storeError(t *testing.T) {
+	e := newSessionTestEcho(&failingSessionStore{})
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "id"})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestSessionWithConfig_panics(t *testing.T) {
+	assert.PanicsWithValue(t, "echo: session middleware requires a store", func() {
+		SessionWithConfig(SessionConfig{})
+	})
+	assert.PanicsWithValue(t, "echo: session cookie store requires at least one key", func() {
+		NewSessionCookieStore()
+	})
+	assert.PanicsWithValue(t, "echo: session cookie store key must be at least 32 bytes long", func() {
+		NewSessionCookieStore([]byte("short"))
+	})
+}
+
+func TestSessionCookieStore_keyRotation(t *testing.T) {
+	data := &SessionData{ID: "id", Values: map[string]interface{}{"n": 1}, ExpiresAt: time.Now().Add(time.Hour)}
+
+	value, err := NewSessionCookieStore(testSessionKey1).Save(data)
+	assert.NoError(t, err)
+
+	// new key is first, old key is still accepted
+	rotated := NewSessionCookieStore(testSessionKey2, testSessionKey1)
+	loaded, err := rotated.Load(value)
+	assert.NoError(t, err)
+	assert.Equal(t, "id", loaded.ID)
+	assert.Equal(t, 1, loaded.Values["n"])
+
+	newValue, err := rotated.Save(loaded)
+	assert.NoError(t, err)
+	_, err = NewSessionCookieStore(testSessionKey2).Load(newValue)
+	assert.NoError(t, err)
+
+	// old key is removed
+	_, err = NewSessionCookieStore(testSessionKey2).Load(value)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// tampered value
+	b := []byte(newValue)
+	b[10] ^= 1
+	_, err = rotated.Load(string(b))
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionCookieStore_tooLarge(t *testing.T) {
+	data := &SessionData{ID: "id", Values: map[string]interface{}{"big": strings.Repeat("x", 4096)}}
+
+	_, err := NewSessionCookieStore(testSessionKey1).Save(data)
+
+	assert.EqualError(t, err, "echo: session data is too large to be stored in a cookie")
+}
+
+func TestSessionMemoryStore_expiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewSessionMemoryStore()
+	store.timeNow = func() time.Time { return now }
+
+	_, err := store.Save(&SessionData{ID: "a", ExpiresAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+	_, err = store.Save(&SessionData{ID: "b", ExpiresAt: now.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	data, err := store.Load("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", data.ID)
+
+	now = now.Add(2 * time.Minute)
+	_, err = store.Load("a")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Len(t, store.sessions, 1) // expired sessions are cleaned up
+
+	_, err = store.Save(&SessionData{})
+	assert.EqualError(t, err, "echo: session ID is empty")
+}
+
+func TestCSRFWithConfig_UseSession(t *testing.T) {
+	e := echo.New()
+	e.Use(Session(NewSessionMemoryStore()))
+	e.Use(CSRFWithConfig(CSRFConfig{UseSession: true}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("csrf").(string))
+	})
+	e.POST("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.POST("/login", func(c echo.Context) error {
+		if err := GetSession(c).RegenerateID(); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, c.Get("csrf").(string))
+	})
+	client := newSessionTestClient(e)
+
+	rec := client.do(http.MethodGet, "/", nil)
+	token := rec.Body.String()
+	assert.Len(t, token, 32)
+	assert.NotContains(t, client.cookies, "_csrf")
+	assert.Contains(t, client.cookies, "session")
+
+	// token is same for the session
+	rec = client.do(http.MethodGet, "/", nil)
+	assert.Equal(t, token, rec.Body.String())
+
+	rec = client.do(http.MethodPost, "/", http.Header{echo.HeaderXCSRFToken: {token}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = client.do(http.MethodPost, "/", http.Header{echo.HeaderXCSRFToken: {"invalid"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// token is rotated with session ID
+	rec = client.do(http.MethodPost, "/login", http.Header{echo.HeaderXCSRFToken: {token}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{echo.HeaderCookie}, rec.Header().Values(echo.HeaderVary))
+	rotated := rec.Body.String()
+	assert.Len(t, rotated, 32)
+	assert.NotEqual(t, token, rotated)
+	rec = client.do(http.MethodPost, "/", http.Header{echo.HeaderXCSRFToken: {token}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = client.do(http.MethodGet, "/", nil)
+	assert.Equal(t, rotated, rec.Body.String())
+	rec = client.do(http.MethodPost, "/", http.Header{echo.HeaderXCSRFToken: {rotated}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCSRFWithConfig_UseSessionWithoutSession(t *testing.T) {
+	e := echo.New()
+	mw := CSRFWithConfig(CSRFConfig{UseSession: true})
+
+	err := mw(echo.NotFoundHandler)(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()))
+
+	assert.Equal(t, ErrCSRFSessionMissing, err)
+}
@@ -7,11 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/bytes"
@@ -39,6 +42,18 @@ type StaticConfig struct {
 	// Optional. Default value false.
 	Browse bool `yaml:"browse"`
 
+	// BrowseTemplate is template used to render HTML directory listing. Template is executed with `DirectoryListing`
+	// as data. Clients preferring `application/json` (`Accept` header) get listing as JSON instead.
+	// Listing is sorted by `sort` query parameter (`name`, `size` or `mtime`) in `order` query parameter
+	// (`asc` or `desc`) order.
+	// Optional. Default value is built-in template.
+	BrowseTemplate *template.Template `yaml:"-"`
+
+	// BrowseHideHidden excludes hidden files and directories (names starting with `.`) from directory listing.
+	// Note: hidden files are still served when requested by name.
+	// Optional. Default value false.
+	BrowseHideHidden bool `yaml:"browseHideHidden"`
+
 	// Enable ignoring of the base of the URL path.
 	// Example: when assigning a static middleware to a non root path group,
 	// the filesystem path is not doubled
@@ -48,6 +63,11 @@ type StaticConfig struct {
 	// Filesystem provides access to the static content.
 	// Optional. Defaults to http.Dir(config.Root)
 	Filesystem http.FileSystem `yaml:"-"`
+
+	// Precompressed enables serving precompressed siblings of requested files (`app.js.br`, `app.js.gz`) to clients
+	// accepting that encoding. See `echo.DefaultPrecompressedEncodings` for looked up encodings.
+	// Optional. Default value false.
+	Precompressed bool `yaml:"precompressed"`
 }
 
 const html = `
@@ -67,6 +87,14 @@ const html = `
 			padding: 4px 16px;
 			font-size: 24px;
 		}
+		header a {
+			text-decoration: none;
+		}
+		nav {
+			padding: 4px 16px;
+			font-size: 12px;
+			color: #707070;
+		}
     ul {
 			list-style-type: none;
 			margin: 0;
@@ -103,8 +131,14 @@ const html = `
 </head>
 <body>
 	<header>
-		{{ .Name }}
+		{{ range .Breadcrumbs }}<a class="dir" href="{{ .Path }}">{{ .Name }}</a>{{ end }}
 	</header>
+	<nav>
+		sort by
+		<a href="?sort=name&order={{ if and (eq .Sort "name") (eq .Order "asc") }}desc{{ else }}asc{{ end }}">name</a>
+		<a href="?sort=size&order={{ if and (eq .Sort "size") (eq .Order "asc") }}desc{{ else }}asc{{ end }}">size</a>
+		<a href="?sort=mtime&order={{ if and (eq .Sort "mtime") (eq .Order "asc") }}desc{{ else }}asc{{ end }}">modified</a>
+	</nav>
 	<ul>
 		{{ range .Files }}
 		<li>
@@ -155,9 +189,13 @@ func StaticWithConfig(config StaticConfig) echo.MiddlewareFunc {
 	}
 
 	// Index template
-	t, tErr := template.New("index").Parse(html)
-	if tErr != nil {
-		panic(fmt.Errorf("echo: %w", tErr))
+	t := config.BrowseTemplate
+	if t == nil {
+		var tErr error
+		t, tErr = template.New("index").Parse(html)
+		if tErr != nil {
+			panic(fmt.Errorf("echo: %w", tErr))
+		}
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -219,7 +257,7 @@ func StaticWithConfig(config StaticConfig) echo.MiddlewareFunc {
 				index, err := config.Filesystem.Open(path.Join(name, config.Index))
 				if err != nil {
 					if config.Browse {
-						return listDir(t, name, file, c.Response())
+						return listDir(t, name, p, file, c, config.BrowseHideHidden)
 					}
 
 					return next(c)
@@ -232,9 +270,15 @@ func StaticWithConfig(config StaticConfig) echo.MiddlewareFunc {
 					return err
 				}
 
+				if config.Precompressed && servePrecompressed(c, config.Filesystem, path.Join(name, config.Index), info) {
+					return nil
+				}
 				return serveFile(c, index, info)
 			}
 
+			if config.Precompressed && servePrecompressed(c, config.Filesystem, name, info) {
+				return nil
+			}
 			return serveFile(c, file, info)
 		}
 	}
@@ -245,26 +289,149 @@ func serveFile(c echo.Context, file http.File, info os.FileInfo) error {
 	return nil
 }
 
-func listDir(t *template.Template, name string, dir http.File, res *echo.Response) (err error) {
+// servePrecompressed serves precompressed sibling of the file when one exists and client accepts its encoding.
+func servePrecompressed(c echo.Context, filesystem http.FileSystem, name string, info os.FileInfo) bool {
+	open := func(name string) (fs.File, error) {
+		return filesystem.Open(name)
+	}
+	return echo.ServePrecompressed(c, open, name, info)
+}
+
+// DirectoryListing is data for directory listing template of Static middleware. It is also rendered as JSON when
+// client prefers `application/json` response.
+type DirectoryListing struct {
+	// Name is file system path of the directory.
+	Name string `json:"name"`
+	// Path is URL path of the directory.
+	Path string `json:"path"`
+	// Sort is field entries are sorted by: `name`, `size` or `mtime`.
+	Sort string `json:"sort"`
+	// Order is sort order: `asc` or `desc`.
+	Order       string                       `json:"order"`
+	Breadcrumbs []DirectoryListingBreadcrumb `json:"breadcrumbs"`
+	Files       []DirectoryListingEntry      `json:"files"`
+}
+
+// DirectoryListingBreadcrumb is link to the listed directory or one of its parents.
+type DirectoryListingBreadcrumb struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// DirectoryListingEntry is file or directory in directory listing.
+type DirectoryListingEntry struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir"`
+	// Size is human-readable size of the file (`1.50KB`).
+	Size string `json:"-"`
+	// SizeBytes is size of the file in bytes.
+	SizeBytes int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+}
+
+func listDir(t *template.Template, name string, relPath string, dir http.File, c echo.Context, hideHidden bool) (err error) {
 	files, err := dir.Readdir(-1)
 	if err != nil {
 		return
 	}
 
-	// Create directory index
-	res.Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
-	data := struct {
-		Name  string
-		Files []interface{}
-	}{
-		Name: name,
+	req := c.Request()
+	query := req.URL.Query()
+	data := DirectoryListing{
+		Name:        name,
+		Path:        req.URL.Path,
+		Sort:        query.Get("sort"),
+		Order:       query.Get("order"),
+		Breadcrumbs: breadcrumbs(req.URL.Path, relPath),
+		Files:       make([]DirectoryListingEntry, 0, len(files)),
 	}
 	for _, f := range files {
-		data.Files = append(data.Files, struct {
-			Name string
-			Dir  bool
-			Size string
-		}{f.Name(), f.IsDir(), bytes.Format(f.Size())})
+		if hideHidden && strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		data.Files = append(data.Files, DirectoryListingEntry{
+			Name:      f.Name(),
+			Dir:       f.IsDir(),
+			Size:      bytes.Format(f.Size()),
+			SizeBytes: f.Size(),
+			ModTime:   f.ModTime(),
+		})
+	}
+	sortDirectoryListing(&data)
+
+	echo.AddVary(c.Response().Header(), echo.HeaderAccept)
+	if prefersJSON(req.Header.Get(echo.HeaderAccept)) {
+		return c.JSON(http.StatusOK, data)
 	}
+
+	// Create directory index
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
 	return t.Execute(res, data)
 }
+
+func sortDirectoryListing(data *DirectoryListing) {
+	if data.Sort != "size" && data.Sort != "mtime" {
+		data.Sort = "name"
+	}
+	if data.Order != "desc" {
+		data.Order = "asc"
+	}
+	compare := func(a, b DirectoryListingEntry) int {
+		switch data.Sort {
+		case "size":
+			if a.SizeBytes != b.SizeBytes {
+				if a.SizeBytes < b.SizeBytes {
+					return -1
+				}
+				return 1
+			}
+		case "mtime":
+			if c := a.ModTime.Compare(b.ModTime); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.Name, b.Name)
+	}
+	files := data.Files
+	sort.SliceStable(files, func(i, j int) bool {
+		if data.Order == "desc" {
+			return compare(files[j], files[i]) < 0
+		}
+		return compare(files[i], files[j]) < 0
+	})
+}
+
+// breadcrumbs creates links to the directory and its parents up to the root of served directory. relPath is part of
+// URL path that is relative to the served directory.
+func breadcrumbs(urlPath string, relPath string) []DirectoryListingBreadcrumb {
+	base := "/"
+	rel := strings.Trim(relPath, "/")
+	if trimmed, ok := strings.CutSuffix(strings.TrimSuffix(urlPath, "/"), strings.TrimSuffix("/"+rel, "/")); ok {
+		base = trimmed + "/"
+	}
+	result := []DirectoryListingBreadcrumb{{Name: "/", Path: base}}
+	if rel == "" {
+		return result
+	}
+	current := base
+	for _, segment := range strings.Split(rel, "/") {
+		current += url.PathEscape(segment) + "/"
+		result = append(result, DirectoryListingBreadcrumb{Name: segment + "/", Path: current})
+	}
+	return result
+}
+
+// prefersJSON checks if `Accept` header value prefers JSON over HTML.
+func prefersJSON(accept string) bool {
+	accepted := echo.ParseQualityValues(accept)
+	jsonQ, ok := accepted[echo.MIMEApplicationJSON]
+	if !ok {
+		return false
+	}
+	htmlQ, ok := accepted[echo.MIMETextHTML]
+	if !ok {
+		htmlQ = accepted["*/*"]
+	}
+	return jsonQ > 0 && jsonQ > htmlQ
+}
@@ -4,6 +4,8 @@
 package middleware
 
 import (
+	"encoding/json"
+	"html/template"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -444,4 +447,164 @@ func TestStatic_ServeStaticFile(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "219885", rec.Header().Get(echo.HeaderContentLength))
-}
\ No newline at end of file
+}
+func TestStatic_Precompressed(t *testing.T) {
+	assets := fstest.MapFS{
+		"app.js":    {Data: []byte("console.log('raw')")},
+		"app.js.gz": {Data: []byte("GZIP")},
+		"app.js.br": {Data: []byte("BR")},
+	}
+
+	var testCases = []struct {
+		name                  string
+		givenPrecompressed    bool
+		whenAcceptEncoding    string
+		expectBody            string
+		expectContentEncoding string
+	}{
+		{
+			name:                  "ok, brotli",
+			givenPrecompressed:    true,
+			whenAcceptEncoding:    "gzip, br",
+			expectBody:            "BR",
+			expectContentEncoding: "br",
+		},
+		{
+			name:                  "ok, gzip",
+			givenPrecompressed:    true,
+			whenAcceptEncoding:    "gzip",
+			expectBody:            "GZIP",
+			expectContentEncoding: "gzip",
+		},
+		{
+			name:               "ok, identity",
+			givenPrecompressed: true,
+			expectBody:         "console.log('raw')",
+		},
+		{
+			name:               "ok, disabled",
+			whenAcceptEncoding: "gzip, br",
+			expectBody:         "console.log('raw')",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(StaticWithConfig(StaticConfig{
+				Filesystem:    http.FS(assets),
+				Precompressed: tc.givenPrecompressed,
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, tc.whenAcceptEncoding)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+			assert.Equal(t, tc.expectContentEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			assert.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		})
+	}
+}
+
+func testBrowseFS() fstest.MapFS {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return fstest.MapFS{
+		"builds/b.tar":       {Data: make([]byte, 10), ModTime: now.Add(-time.Hour)},
+		"builds/a.tar":       {Data: make([]byte, 30), ModTime: now},
+		"builds/c.tar":       {Data: make([]byte, 20), ModTime: now.Add(-2 * time.Hour)},
+		"builds/.secret":     {Data: []byte("x"), ModTime: now},
+		"builds/nightly/x.z": {Data: []byte("x"), ModTime: now},
+	}
+}
+
+func TestStatic_BrowseJSON(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		givenHideHidden bool
+		whenURL         string
+		whenAccept      string
+		expectNames     []string
+	}{
+		{
+			name:        "ok, sorted by name by default",
+			whenURL:     "/builds/",
+			whenAccept:  "application/json",
+			expectNames: []string{".secret", "a.tar", "b.tar", "c.tar", "nightly"},
+		},
+		{
+			name:            "ok, hide hidden files",
+			givenHideHidden: true,
+			whenURL:         "/builds/",
+			whenAccept:      "application/json",
+			expectNames:     []string{"a.tar", "b.tar", "c.tar", "nightly"},
+		},
+		{
+			name:            "ok, sort by size descending",
+			givenHideHidden: true,
+			whenURL:         "/builds/?sort=size&order=desc",
+			whenAccept:      "text/html;q=0.5, application/json",
+			expectNames:     []string{"a.tar", "c.tar", "b.tar", "nightly"},
+		},
+		{
+			name:            "ok, sort by modification time",
+			givenHideHidden: true,
+			whenURL:         "/builds/?sort=mtime",
+			whenAccept:      "application/json",
+			expectNames:     []string{"nightly", "c.tar", "b.tar", "a.tar"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(StaticWithConfig(StaticConfig{
+				Filesystem:       http.FS(testBrowseFS()),
+				Browse:           true,
+				BrowseHideHidden: tc.givenHideHidden,
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.whenURL, nil)
+			req.Header.Set(echo.HeaderAccept, tc.whenAccept)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+
+			var listing DirectoryListing
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+			names := make([]string, 0, len(listing.Files))
+			for _, f := range listing.Files {
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, tc.expectNames, names)
+			assert.Equal(t, []DirectoryListingBreadcrumb{
+				{Name: "/", Path: "/"},
+				{Name: "builds/", Path: "/builds/"},
+			}, listing.Breadcrumbs)
+		})
+	}
+}
+
+func TestStatic_BrowseTemplate(t *testing.T) {
+	e := echo.New()
+	g := e.Group("/files")
+	g.Use(StaticWithConfig(StaticConfig{
+		Filesystem:     http.FS(testBrowseFS()),
+		Browse:         true,
+		BrowseTemplate: template.Must(template.New("list").Parse(`{{ range .Breadcrumbs }}[{{ .Path }}]{{ end }}{{ range .Files }} {{ .Name }}:{{ .SizeBytes }}{{ end }}`)),
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/files/builds/nightly/", nil)
+	req.Header.Set(echo.HeaderAccept, "text/html,application/json;q=0.9")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, "[/files/][/files/builds/][/files/builds/nightly/] x.z:1", rec.Body.String())
+}
@@ -0,0 +1,563 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/bytes"
+)
+
+// TusConfig defines the config for tus resumable upload handler.
+type TusConfig struct {
+	// Store persists uploads and their metadata.
+	// Required.
+	Store TusStore
+
+	// MaxSize is maximum allowed size of an upload, it can be specified as `4x` or `4xB`, where x is one of the
+	// multiple from K, M, G, T or P. Value is advertised to clients with `Tus-Max-Size` header.
+	// Optional. Default value "" (no limit).
+	MaxSize string `yaml:"max_size"`
+
+	// Expiration is duration after last upload activity when unfinished upload expires. Expired uploads are answered
+	// with "410 Gone". Value is advertised to clients with `Upload-Expires` header.
+	// Optional. Default value 0 (uploads do not expire).
+	Expiration time.Duration
+
+	// OnComplete is called after the last chunk of upload has been stored. Additional handlers can be subscribed with
+	// `TusHandler#OnComplete`.
+	// Optional.
+	OnComplete TusCompleteHandler
+
+	maxSize int64
+}
+
+// TusCompleteHandler is called when an upload has been completed. Returned error is sent to the client as response to
+// the last PATCH request.
+type TusCompleteHandler func(c echo.Context, upload TusUpload) error
+
+// TusUpload describes state of a single upload.
+type TusUpload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// IsComplete returns true when all bytes of upload have been received.
+func (u TusUpload) IsComplete() bool {
+	return u.Offset == u.Size
+}
+
+// TusStore is the interface to be implemented by upload storage backends.
+type TusStore interface {
+	// Create stores new empty upload.
+	Create(upload TusUpload) error
+	// Get returns upload by ID. ErrTusUploadNotFound is returned for unknown uploads.
+	Get(id string) (TusUpload, error)
+	// Append writes data at the current offset of the upload and returns updated upload state. Bytes successfully
+	// written before an error occurred must be persisted so the client can resume from the new offset.
+	Append(id string, r io.Reader, expiresAt time.Time) (TusUpload, error)
+	// Terminate removes upload and its data.
+	Terminate(id string) error
+}
+
+// TusHandler implements tus 1.0 resumable upload protocol with creation, termination and expiration extensions.
+// See: https://tus.io/protocols/resumable-upload
+type TusHandler struct {
+	config     TusConfig
+	onComplete []TusCompleteHandler
+
+	mu     sync.Mutex
+	active map[string]struct{}
+}
+
+const (
+	tusVersion        = "1.0.0"
+	tusExtensions     = "creation,creation-with-upload,expiration,termination"
+	tusContentType    = "application/offset+octet-stream"
+	tusUploadIDLength = 32
+
+	headerTusResumable      = "Tus-Resumable"
+	headerTusVersion        = "Tus-Version"
+	headerTusExtension      = "Tus-Extension"
+	headerTusMaxSize        = "Tus-Max-Size"
+	headerUploadOffset      = "Upload-Offset"
+	headerUploadLength      = "Upload-Length"
+	headerUploadMetadata    = "Upload-Metadata"
+	headerUploadExpires     = "Upload-Expires"
+	headerUploadDeferLength = "Upload-Defer-Length"
+)
+
+var (
+	// ErrTusUploadNotFound is returned by TusStore when upload does not exist.
+	ErrTusUploadNotFound = echo.NewHTTPError(http.StatusNotFound, "upload not found")
+	// ErrTusUploadExpired is returned when upload has expired.
+	ErrTusUploadExpired = echo.NewHTTPError(http.StatusGone, "upload expired")
+	// ErrTusOffsetMismatch is returned when PATCH request `Upload-Offset` does not match current offset of the upload.
+	ErrTusOffsetMismatch = echo.NewHTTPError(http.StatusConflict, "upload offset mismatch")
+	// ErrTusUploadLocked is returned when another request is already appending to the same upload.
+	ErrTusUploadLocked = echo.NewHTTPError(http.StatusLocked, "upload is locked by another request")
+)
+
+// NewTusHandler creates tus upload handler. Use `TusHandler#Register` to add its routes to Echo instance or Group.
+//
+//	tus := middleware.NewTusHandler(middleware.TusConfig{
+//		Store:   middleware.NewTusFileStore("./uploads"),
+//		MaxSize: "2G",
+//	})
+//	tus.OnComplete(func(c echo.Context, upload middleware.TusUpload) error {
+//		return process(upload.ID)
+//	})
+//	tus.Register(e.Group("/files", middleware.KeyAuth(validator)))
+func NewTusHandler(config TusConfig) *TusHandler {
+	if config.Store == nil {
+		panic("echo: tus handler requires a store")
+	}
+	if config.MaxSize != "" {
+		limit, err := bytes.Parse(config.MaxSize)
+		if err != nil {
+			panic(fmt.Errorf("echo: invalid tus max-size=%s", config.MaxSize))
+		}
+		config.maxSize = limit
+	}
+	h := &TusHandler{
+		config: config,
+		active: map[string]struct{}{},
+	}
+	if config.OnComplete != nil {
+		h.onComplete = append(h.onComplete, config.OnComplete)
+	}
+	return h
+}
+
+// OnComplete subscribes handler to upload completion events. Handlers are called in order of subscription.
+// Must be called before the server is started.
+func (h *TusHandler) OnComplete(handler TusCompleteHandler) {
+	h.onComplete = append(h.onComplete, handler)
+}
+
+// Register adds tus protocol routes to the group. Middlewares of the group apply to all of them.
+func (h *TusHandler) Register(g *echo.Group) []*echo.Route {
+	return []*echo.Route{
+		g.OPTIONS("", h.options),
+		g.POST("", h.create),
+		g.HEAD("/:id", h.head),
+		g.PATCH("/:id", h.patch),
+		g.DELETE("/:id", h.terminate),
+	}
+}
+
+func (h *TusHandler) options(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set(headerTusResumable, tusVersion)
+	header.Set(headerTusVersion, tusVersion)
+	header.Set(headerTusExtension, tusExtensions)
+	if h.config.maxSize > 0 {
+		header.Set(headerTusMaxSize, strconv.FormatInt(h.config.maxSize, 10))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TusHandler) checkVersion(c echo.Context) error {
+	c.Response().Header().Set(headerTusResumable, tusVersion)
+	if c.Request().Header.Get(headerTusResumable) != tusVersion {
+		c.Response().Header().Set(headerTusVersion, tusVersion)
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "unsupported tus version")
+	}
+	return nil
+}
+
+func (h *TusHandler) create(c echo.Context) error {
+	if err := h.checkVersion(c); err != nil {
+		return err
+	}
+	req := c.Request()
+	if req.Header.Get(headerUploadDeferLength) != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "deferred upload length is not supported")
+	}
+	size, err := strconv.ParseInt(req.Header.Get(headerUploadLength), 10, 64)
+	if err != nil || size < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Length header")
+	}
+	if h.config.maxSize > 0 && size > h.config.maxSize {
+		return echo.ErrStatusRequestEntityTooLarge
+	}
+	metadata, err := parseTusMetadata(req.Header.Get(headerUploadMetadata))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Metadata header").SetInternal(err)
+	}
+
+	upload := TusUpload{
+		ID:        randomString(tusUploadIDLength),
+		Size:      size,
+		Metadata:  metadata,
+		ExpiresAt: h.expiresAt(),
+	}
+	// upload is locked from creation so PATCH requests can not interleave with the first chunk
+	if !h.lock(upload.ID) {
+		return ErrTusUploadLocked
+	}
+	defer h.unlock(upload.ID)
+
+	if err := h.config.Store.Create(upload); err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderLocation, strings.TrimSuffix(req.URL.Path, "/")+"/"+upload.ID)
+
+	// creation-with-upload extension: request body contains first chunk of the upload
+	if req.ContentLength != 0 && req.Header.Get(echo.HeaderContentType) == tusContentType {
+		if upload, err = h.append(c, upload); err != nil {
+			return err
+		}
+		c.Response().Header().Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	}
+	h.setExpires(c, upload)
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *TusHandler) head(c echo.Context) error {
+	if err := h.checkVersion(c); err != nil {
+		return err
+	}
+	upload, err := h.get(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "no-store")
+	header.Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	header.Set(headerUploadLength, strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		header.Set(headerUploadMetadata, formatTusMetadata(upload.Metadata))
+	}
+	h.setExpires(c, upload)
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *TusHandler) patch(c echo.Context) error {
+	if err := h.checkVersion(c); err != nil {
+		return err
+	}
+	req := c.Request()
+	if req.Header.Get(echo.HeaderContentType) != tusContentType {
+		return echo.ErrUnsupportedMediaType
+	}
+	offset, err := strconv.ParseInt(req.Header.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset header")
+	}
+
+	id := c.Param("id")
+	if !h.lock(id) {
+		return ErrTusUploadLocked
+	}
+	defer h.unlock(id)
+
+	upload, err := h.get(id)
+	if err != nil {
+		return err
+	}
+	if upload.Offset != offset {
+		return ErrTusOffsetMismatch
+	}
+	if upload, err = h.append(c, upload); err != nil {
+		return err
+	}
+	c.Response().Header().Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	h.setExpires(c, upload)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TusHandler) terminate(c echo.Context) error {
+	if err := h.checkVersion(c); err != nil {
+		return err
+	}
+	id := c.Param("id")
+	if !h.lock(id) {
+		return ErrTusUploadLocked
+	}
+	defer h.unlock(id)
+
+	if _, err := h.config.Store.Get(id); err != nil {
+		return err
+	}
+	if err := h.config.Store.Terminate(id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// append stores request body to the upload and calls completion handlers when upload is finished. Body is not allowed
+// to exceed remaining length of the upload.
+func (h *TusHandler) append(c echo.Context, upload TusUpload) (TusUpload, error) {
+	req := c.Request()
+	remaining := upload.Size - upload.Offset
+	if req.ContentLength > remaining {
+		return upload, errTusChunkTooLarge
+	}
+	updated, err := h.config.Store.Append(upload.ID, &tusChunkReader{r: req.Body, remaining: remaining}, h.expiresAt())
+	if err != nil {
+		return upload, err
+	}
+	if updated.IsComplete() {
+		for _, fn := range h.onComplete {
+			if err := fn(c, updated); err != nil {
+				return updated, err
+			}
+		}
+	}
+	return updated, nil
+}
+
+// tusChunkReader reads request body limited to the remaining length of the upload. Chunked request body can be longer
+// than its announced length, so the last byte of the upload is passed on only after it is verified that the body ends
+// there. Oversized chunk results an error and never completes the upload.
+type tusChunkReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+var errTusChunkTooLarge = echo.NewHTTPError(http.StatusRequestEntityTooLarge, "chunk exceeds upload length")
+
+func (r *tusChunkReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.remaining > 1 {
+		if int64(len(p)) > r.remaining-1 {
+			p = p[:r.remaining-1]
+		}
+		n, err := r.r.Read(p)
+		r.remaining -= int64(n)
+		return n, err
+	}
+
+	buf := make([]byte, r.remaining+1)
+	n, err := io.ReadFull(r.r, buf)
+	switch {
+	case int64(n) > r.remaining:
+		return 0, errTusChunkTooLarge
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		r.remaining -= int64(n)
+		return copy(p, buf[:n]), io.EOF
+	}
+	return 0, err
+}
+
+func (h *TusHandler) get(id string) (TusUpload, error) {
+	upload, err := h.config.Store.Get(id)
+	if err != nil {
+		return upload, err
+	}
+	if !upload.ExpiresAt.IsZero() && time.Now().After(upload.ExpiresAt) {
+		if err := h.config.Store.Terminate(id); err != nil {
+			return upload, err
+		}
+		return upload, ErrTusUploadExpired
+	}
+	return upload, nil
+}
+
+func (h *TusHandler) expiresAt() time.Time {
+	if h.config.Expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(h.config.Expiration)
+}
+
+func (h *TusHandler) setExpires(c echo.Context, upload TusUpload) {
+	if !upload.ExpiresAt.IsZero() && !upload.IsComplete() {
+		c.Response().Header().Set(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (h *TusHandler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.active[id]; ok {
+		return false
+	}
+	h.active[id] = struct{}{}
+	return true
+}
+
+func (h *TusHandler) unlock(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.active, id)
+}
+
+// parseTusMetadata parses `Upload-Metadata` header value. Header consists of comma separated key-value pairs where
+// key and value are separated by space and value is base64 encoded. Value is optional.
+func parseTusMetadata(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	result := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = string(decoded)
+	}
+	return result, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// TusFileStore is TusStore implementation storing uploads in local directory. Each upload consists of data file
+// `<id>.bin` and info file `<id>.json`.
+type TusFileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewTusFileStore creates file system based TusStore. Directory is created if it does not exist.
+func NewTusFileStore(dir string) *TusFileStore {
+	return &TusFileStore{dir: dir}
+}
+
+// Create implements TusStore interface.
+func (s *TusFileStore) Create(upload TusUpload) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.DataPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(upload)
+}
+
+// Get implements TusStore interface.
+func (s *TusFileStore) Get(id string) (TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readInfo(id)
+}
+
+// Append implements TusStore interface.
+func (s *TusFileStore) Append(id string, r io.Reader, expiresAt time.Time) (TusUpload, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return upload, err
+	}
+	f, err := os.OpenFile(s.DataPath(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return upload, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return upload, err
+	}
+
+	n, copyErr := io.Copy(f, r)
+	upload.Offset += n
+	upload.ExpiresAt = expiresAt
+	if err := f.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if err := s.writeInfo(upload); err != nil {
+		return upload, err
+	}
+	return upload, copyErr
+}
+
+// Terminate implements TusStore interface.
+func (s *TusFileStore) Terminate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.infoPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.DataPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DataPath returns path to the file containing data of the upload. Useful in completion handlers to move or process
+// finished uploads.
+func (s *TusFileStore) DataPath(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".bin")
+}
+
+// DeleteExpired removes all uploads that have expired before given time.
+func (s *TusFileStore) DeleteExpired(now time.Time) error {
+	infos, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		upload, err := s.Get(strings.TrimSuffix(filepath.Base(info), ".json"))
+		if err != nil {
+			continue
+		}
+		if !upload.ExpiresAt.IsZero() && now.After(upload.ExpiresAt) {
+			if err := s.Terminate(upload.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *TusFileStore) infoPath(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".json")
+}
+
+func (s *TusFileStore) readInfo(id string) (TusUpload, error) {
+	var upload TusUpload
+	b, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return upload, ErrTusUploadNotFound
+		}
+		return upload, err
+	}
+	err = json.Unmarshal(b, &upload)
+	return upload, err
+}
+
+func (s *TusFileStore) writeInfo(upload TusUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(upload.ID))
+}
@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func tusRequest(e *echo.Echo, method, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(headerTusResumable, tusVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTusHandler(t *testing.T) {
+	store := NewTusFileStore(t.TempDir())
+	var completed []TusUpload
+
+	e := echo.New()
+	tus := NewTusHandler(TusConfig{Store: store, MaxSize: "1KB", Expiration: time.Hour})
+	tus.OnComplete(func(c echo.Context, upload TusUpload) error {
+		completed = append(completed, upload)
+		return nil
+	})
+	tus.Register(e.Group("/files"))
+
+	rec := tusRequest(e, http.MethodOptions, "/files", "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "1000", rec.Header().Get(headerTusMaxSize))
+	assert.Equal(t, tusExtensions, rec.Header().Get(headerTusExtension))
+
+	// creation
+	rec = tusRequest(e, http.MethodPost, "/files", "", map[string]string{
+		headerUploadLength:   "11",
+		headerUploadMetadata: "filename d29ybGQudHh0,is_confidential",
+	})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	location := rec.Header().Get(echo.HeaderLocation)
+	assert.True(t, strings.HasPrefix(location, "/files/"))
+	assert.NotEmpty(t, rec.Header().Get(headerUploadExpires))
+
+	// offset of new upload
+	rec = tusRequest(e, http.MethodHead, location, "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get(headerUploadOffset))
+	assert.Equal(t, "11", rec.Header().Get(headerUploadLength))
+	assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+
+	// first chunk
+	rec = tusRequest(e, http.MethodPatch, location, "hello", map[string]string{
+		echo.HeaderContentType: tusContentType,
+		headerUploadOffset:     "0",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(headerUploadOffset))
+	assert.Len(t, completed, 0)
+
+	// wrong offset
+	rec = tusRequest(e, http.MethodPatch, location, " world", map[string]string{
+		echo.HeaderContentType: tusContentType,
+		headerUploadOffset:     "0",
+	})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// last chunk
+	rec = tusRequest(e, http.MethodPatch, location, " world", map[string]string{
+		echo.HeaderContentType: tusContentType,
+		headerUploadOffset:     "5",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "11", rec.Header().Get(headerUploadOffset))
+
+	if assert.Len(t, completed, 1) {
+		assert.Equal(t, map[string]string{"filename": "world.txt", "is_confidential": ""}, completed[0].Metadata)
+		b, err := os.ReadFile(store.DataPath(completed[0].ID))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(b))
+	}
+
+	// termination
+	rec = tusRequest(e, http.MethodDelete, location, "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = tusRequest(e, http.MethodHead, location, "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTusHandler_errors(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenMethod   string
+		whenTarget   string
+		whenBody     string
+		whenHeaders  map[string]string
+		expectStatus int
+	}{
+		{
+			name:         "nok, upload larger than max size",
+			whenMethod:   http.MethodPost,
+			whenTarget:   "/files",
+			whenHeaders:  map[string]string{headerUploadLength: "2048"},
+			expectStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "nok, missing upload length",
+			whenMethod:   http.MethodPost,
+			whenTarget:   "/files",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "nok, unsupported version",
+			whenMethod:   http.MethodPost,
+			whenTarget:   "/files",
+			whenHeaders:  map[string]string{headerTusResumable: "0.2.2", headerUploadLength: "1"},
+			expectStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "nok, unknown upload",
+			whenMethod:   http.MethodPatch,
+			whenTarget:   "/files/unknown",
+			whenHeaders:  map[string]string{echo.HeaderContentType: tusContentType, headerUploadOffset: "0"},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "nok, invalid content type",
+			whenMethod:   http.MethodPatch,
+			whenTarget:   "/files/unknown",
+			whenHeaders:  map[string]string{headerUploadOffset: "0"},
+			expectStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "nok, creation-with-upload chunk exceeds length",
+			whenMethod:   http.MethodPost,
+			whenTarget:   "/files",
+			whenBody:     "too long",
+			whenHeaders:  map[string]string{echo.HeaderContentType: tusContentType, headerUploadLength: "2"},
+			expectStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			NewTusHandler(TusConfig{Store: NewTusFileStore(t.TempDir()), MaxSize: "1KB"}).Register(e.Group("/files"))
+
+			rec := tusRequest(e, tc.whenMethod, tc.whenTarget, tc.whenBody, tc.whenHeaders)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tusVersion, rec.Header().Get(headerTusResumable))
+		})
+	}
+}
+
+func TestTusHandler_expiration(t *testing.T) {
+	store := NewTusFileStore(t.TempDir())
+	e := echo.New()
+	NewTusHandler(TusConfig{Store: store, Expiration: time.Hour}).Register(e.Group("/files"))
+
+	rec := tusRequest(e, http.MethodPost, "/files", "abc", map[string]string{
+		headerUploadLength:     "10",
+		echo.HeaderContentType: tusContentType,
+	})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get(headerUploadOffset))
+	location := rec.Header().Get(echo.HeaderLocation)
+
+	assert.NoError(t, store.DeleteExpired(time.Now()))
+	rec = tusRequest(e, http.MethodHead, location, "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.NoError(t, store.DeleteExpired(time.Now().Add(2*time.Hour)))
+	rec = tusRequest(e, http.MethodHead, location, "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTusHandler_chunkedBodyExceedsLength(t *testing.T) {
+	store := NewTusFileStore(t.TempDir())
+	completed := 0
+
+	e := echo.New()
+	tus := NewTusHandler(TusConfig{Store: store})
+	tus.OnComplete(func(c echo.Context, upload TusUpload) error {
+		completed++
+		return nil
+	})
+	tus.Register(e.Group("/files"))
+
+	rec := tusRequest(e, http.MethodPost, "/files", "", map[string]string{headerUploadLength: "5"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	location := rec.Header().Get(echo.HeaderLocation)
+
+	patch := func(offset string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, location, strings.NewReader(body))
+		req.ContentLength = -1 // chunked transfer encoding
+		req.Header.Set(headerTusResumable, tusVersion)
+		req.Header.Set(echo.HeaderContentType, tusContentType)
+		req.Header.Set(headerUploadOffset, offset)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = patch("0", "abcdefg")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, 0, completed)
+
+	upload, err := store.Get(strings.TrimPrefix(location, "/files/"))
+	assert.NoError(t, err)
+	assert.False(t, upload.IsComplete())
+	assert.Equal(t, int64(4), upload.Offset)
+
+	rec = patch("4", "e")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(headerUploadOffset))
+	assert.Equal(t, 1, completed)
+
+	data, err := os.ReadFile(store.DataPath(upload.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, "abcde", string(data))
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVConfig defines the config for WebDAV handler.
+type WebDAVConfig struct {
+	// FileSystem is file system served over WebDAV. Use `webdav.Dir("/path/to/dir")` for local directory or
+	// `webdav.NewMemFS()` for in-memory file system.
+	// Required.
+	FileSystem webdav.FileSystem
+
+	// LockSystem manages WebDAV locks.
+	// Optional. Default value `webdav.NewMemLS()`.
+	LockSystem webdav.LockSystem
+}
+
+// WebDAVHandler serves WebDAV (RFC 4918) requests from a file system. Requests are routed by Echo so middlewares
+// (authentication, logging etc.) apply to all WebDAV methods.
+type WebDAVHandler struct {
+	handler webdav.Handler
+}
+
+// webDAVMethods are methods handled by WebDAV server.
+var webDAVMethods = []string{
+	http.MethodOptions,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodDelete,
+	http.MethodPut,
+	echo.PROPFIND,
+	"PROPPATCH",
+	"MKCOL",
+	"COPY",
+	"MOVE",
+	"LOCK",
+	"UNLOCK",
+}
+
+// NewWebDAVHandler creates WebDAV handler. Use `WebDAVHandler#Register` to mount it on a Group.
+//
+//	dav := middleware.NewWebDAVHandler(middleware.WebDAVConfig{FileSystem: webdav.Dir("./shared")})
+//	dav.Register(e.Group("/dav", middleware.BasicAuth(validator)))
+func NewWebDAVHandler(config WebDAVConfig) *WebDAVHandler {
+	if config.FileSystem == nil {
+		panic("echo: webdav handler requires a file system")
+	}
+	if config.LockSystem == nil {
+		config.LockSystem = webdav.NewMemLS()
+	}
+	return &WebDAVHandler{
+		handler: webdav.Handler{
+			FileSystem: config.FileSystem,
+			LockSystem: config.LockSystem,
+		},
+	}
+}
+
+// Register adds routes for all WebDAV methods to the group. Group prefix must not contain path parameters as it is
+// used as the root of WebDAV resource paths.
+func (h *WebDAVHandler) Register(g *echo.Group) []*echo.Route {
+	routes := g.Match(webDAVMethods, "", h.Handle)
+	return append(routes, g.Match(webDAVMethods, "/*", h.Handle)...)
+}
+
+// Handle serves WebDAV request. Route for the handler must end with `/*` or have no wildcard at all.
+func (h *WebDAVHandler) Handle(c echo.Context) error {
+	handler := h.handler
+	handler.Prefix = strings.TrimSuffix(strings.TrimSuffix(c.Path(), "*"), "/")
+	handler.Logger = func(r *http.Request, err error) {
+		if err != nil {
+			c.Logger().Debugf("webdav: method=%s, path=%s, err=%v", r.Method, r.URL.Path, err)
+		}
+	}
+	handler.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
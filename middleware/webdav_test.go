@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/webdav"
+)
+
+func TestWebDAVHandler(t *testing.T) {
+	e := echo.New()
+	g := e.Group("/dav", BasicAuth(func(u, p string, c echo.Context) (bool, error) {
+		return u == "joe" && p == "secret", nil
+	}))
+	NewWebDAVHandler(WebDAVConfig{FileSystem: webdav.NewMemFS()}).Register(g)
+
+	do := func(method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetBasicAuth("joe", "secret")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("MKCOL", "/dav/docs", "", nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do(http.MethodPut, "/dav/docs/a.txt", "hello", nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do(echo.PROPFIND, "/dav/docs", "", map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<D:href>/dav/docs/a.txt</D:href>")
+
+	rec = do("PROPPATCH", "/dav/docs/a.txt", `<?xml version="1.0" encoding="utf-8" ?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:Z="http://example.com/ns"><D:set><D:prop><Z:author>Joe</Z:author></D:prop></D:set></D:propertyupdate>`, nil)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	rec = do("COPY", "/dav/docs/a.txt", "", map[string]string{"Destination": "http://example.com/dav/docs/b.txt"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do("MOVE", "/dav/docs/b.txt", "", map[string]string{"Destination": "/dav/c.txt"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do(http.MethodGet, "/dav/c.txt", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+
+	rec = do("LOCK", "/dav/c.txt", `<?xml version="1.0" encoding="utf-8" ?>
+<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`, map[string]string{"Timeout": "Second-60"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	token := rec.Header().Get("Lock-Token")
+	assert.NotEmpty(t, token)
+
+	rec = do(http.MethodPut, "/dav/c.txt", "changed", nil)
+	assert.Equal(t, http.StatusLocked, rec.Code)
+
+	rec = do("UNLOCK", "/dav/c.txt", "", map[string]string{"Lock-Token": token})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = do(http.MethodDelete, "/dav/docs", "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = do(echo.PROPFIND, "/dav", "", map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "/dav/docs")
+
+	// middleware of the group applies to WebDAV methods
+	req := httptest.NewRequest(echo.PROPFIND, "/dav", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,305 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned by MultipartStream when request violates configured limits.
+var (
+	ErrMultipartFileTooLarge       = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart file size limit exceeded")
+	ErrMultipartFieldTooLarge      = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart field size limit exceeded")
+	ErrMultipartTotalTooLarge      = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart total size limit exceeded")
+	ErrMultipartTooManyFiles       = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart file count limit exceeded")
+	ErrMultipartTooManyFields      = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart field count limit exceeded")
+	ErrMultipartFieldsTooLarge     = NewHTTPError(http.StatusRequestEntityTooLarge, "multipart fields total size limit exceeded")
+	ErrMultipartContentTypeInvalid = NewHTTPError(http.StatusUnsupportedMediaType, "multipart file content type is not allowed")
+)
+
+const (
+	defaultMultipartMaxFieldSize  = 1 << 20 // 1 MB
+	defaultMultipartMaxFields     = 1000
+	defaultMultipartMaxFieldsSize = 10 << 20 // 10 MB
+)
+
+// MultipartStreamConfig defines limits for streaming `multipart/form-data` request processing.
+type MultipartStreamConfig struct {
+	// MaxFileSize is maximum size (in bytes) of single file part.
+	// Optional. Default value 0 (no limit).
+	MaxFileSize int64
+
+	// MaxTotalSize is maximum size (in bytes) of all parts contents combined.
+	// Optional. Default value 0 (no limit).
+	MaxTotalSize int64
+
+	// MaxFieldSize is maximum size (in bytes) of non-file field value. Field values are held in memory.
+	// Optional. Default value 1 MB.
+	MaxFieldSize int64
+
+	// MaxFields is maximum number of non-file fields in request. Field values are held in memory.
+	// Optional. Default value 1000.
+	MaxFields int
+
+	// MaxFieldsSize is maximum size (in bytes) of all non-file field values combined. Field values are held in memory.
+	// Optional. Default value 10 MB.
+	MaxFieldsSize int64
+
+	// MaxFiles is maximum number of file parts in request.
+	// Optional. Default value 0 (no limit).
+	MaxFiles int
+
+	// AllowedContentTypes is list of media types file parts are allowed to have. Wildcard subtypes like `image/*` are
+	// supported. File parts without `Content-Type` header are considered to be `application/octet-stream`.
+	// Optional. Default value nil (all types are allowed).
+	AllowedContentTypes []string
+}
+
+// MultipartStream iterates over parts of `multipart/form-data` request body without buffering files into memory or
+// temporary files, as `Context#MultipartForm` does. Non-file fields are collected and available with `Values` and
+// can be bound with `DefaultBinder#BindMultipartStream`.
+type MultipartStream struct {
+	reader  *multipart.Reader
+	current *MultipartPart
+	values  url.Values
+	config  MultipartStreamConfig
+	total   int64
+	files   int
+	fields  int
+	// fieldsSize is size of all non-file field values read into memory
+	fieldsSize int64
+}
+
+// MultipartPart is a single part of multipart stream. Reading from part enforces size limits of MultipartStream.
+type MultipartPart struct {
+	part   *multipart.Part
+	stream *MultipartStream
+	value  *bytes.Reader
+	read   int64
+}
+
+// MultipartSink creates destinations where file parts are stored by `MultipartPart#SaveTo`.
+type MultipartSink interface {
+	Create(part *MultipartPart) (io.WriteCloser, error)
+}
+
+// DirSink is MultipartSink storing files in the directory. File is named after the base name of the filename sent by
+// client. Existing files are never overwritten.
+type DirSink struct {
+	Dir string
+}
+
+// NewMultipartStream creates MultipartStream for the request.
+func NewMultipartStream(r *http.Request, config MultipartStreamConfig) (*MultipartStream, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return nil, ErrUnsupportedMediaType.WithInternal(err)
+		}
+		return nil, NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if config.MaxFieldSize <= 0 {
+		config.MaxFieldSize = defaultMultipartMaxFieldSize
+	}
+	if config.MaxFields <= 0 {
+		config.MaxFields = defaultMultipartMaxFields
+	}
+	if config.MaxFieldsSize <= 0 {
+		config.MaxFieldsSize = defaultMultipartMaxFieldsSize
+	}
+	return &MultipartStream{
+		reader: reader,
+		config: config,
+		values: url.Values{},
+	}, nil
+}
+
+func (c *context) MultipartStream(config MultipartStreamConfig) (*MultipartStream, error) {
+	return NewMultipartStream(c.request, config)
+}
+
+// NextPart returns next part of the stream or io.EOF when there are no more parts. Values of non-file fields are read
+// into memory and added to `Values` before the part is returned. Unread content of previous part is discarded.
+func (s *MultipartStream) NextPart() (*MultipartPart, error) {
+	if s.current != nil {
+		if _, err := io.Copy(io.Discard, s.current); err != nil {
+			return nil, err
+		}
+		s.current = nil
+	}
+
+	p, err := s.reader.NextPart()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	part := &MultipartPart{part: p, stream: s}
+	s.current = part
+
+	if !part.IsFile() {
+		s.fields++
+		if s.fields > s.config.MaxFields {
+			return nil, ErrMultipartTooManyFields
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, part); err != nil {
+			return nil, err
+		}
+		s.values.Add(p.FormName(), buf.String())
+		part.value = bytes.NewReader(buf.Bytes())
+		return part, nil
+	}
+
+	s.files++
+	if s.config.MaxFiles > 0 && s.files > s.config.MaxFiles {
+		return nil, ErrMultipartTooManyFiles
+	}
+	if !isContentTypeAllowed(part.ContentType(), s.config.AllowedContentTypes) {
+		return nil, ErrMultipartContentTypeInvalid
+	}
+	return part, nil
+}
+
+// Values returns non-file fields read so far. Note: fields sent after file parts are available only after these file
+// parts have been iterated over.
+func (s *MultipartStream) Values() url.Values {
+	return s.values
+}
+
+// FormName returns the name parameter of the part `Content-Disposition` header.
+func (p *MultipartPart) FormName() string {
+	return p.part.FormName()
+}
+
+// FileName returns the filename parameter of the part `Content-Disposition` header. Value is sent by client and must
+// not be trusted as file system path.
+func (p *MultipartPart) FileName() string {
+	return p.part.FileName()
+}
+
+// Header returns MIME headers of the part.
+func (p *MultipartPart) Header() map[string][]string {
+	return p.part.Header
+}
+
+// ContentType returns media type of the part without parameters.
+func (p *MultipartPart) ContentType() string {
+	ct := p.part.Header.Get(HeaderContentType)
+	if ct == "" {
+		return MIMEOctetStream
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ct
+	}
+	return mediaType
+}
+
+// IsFile returns true when part is a file upload (has filename parameter).
+func (p *MultipartPart) IsFile() bool {
+	return p.part.FileName() != ""
+}
+
+// Value returns value of non-file field.
+func (p *MultipartPart) Value() string {
+	values := p.stream.values[p.FormName()]
+	if p.IsFile() || len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Read reads content of the part and enforces stream size limits.
+func (p *MultipartPart) Read(b []byte) (int, error) {
+	if p.value != nil {
+		return p.value.Read(b)
+	}
+	n, err := p.part.Read(b)
+	p.read += int64(n)
+	p.stream.total += int64(n)
+
+	cfg := p.stream.config
+	if cfg.MaxTotalSize > 0 && p.stream.total > cfg.MaxTotalSize {
+		return n, ErrMultipartTotalTooLarge
+	}
+	if p.IsFile() {
+		if cfg.MaxFileSize > 0 && p.read > cfg.MaxFileSize {
+			return n, ErrMultipartFileTooLarge
+		}
+	} else {
+		p.stream.fieldsSize += int64(n)
+		if p.read > cfg.MaxFieldSize {
+			return n, ErrMultipartFieldTooLarge
+		}
+		if p.stream.fieldsSize > cfg.MaxFieldsSize {
+			return n, ErrMultipartFieldsTooLarge
+		}
+	}
+	return n, err
+}
+
+// CopyTo copies content of the part to the writer. Given hashes are updated with the same content and can be used
+// to compute checksums while streaming.
+func (p *MultipartPart) CopyTo(w io.Writer, hashes ...hash.Hash) (int64, error) {
+	if len(hashes) > 0 {
+		writers := make([]io.Writer, 0, len(hashes)+1)
+		writers = append(writers, w)
+		for _, h := range hashes {
+			writers = append(writers, h)
+		}
+		w = io.MultiWriter(writers...)
+	}
+	return io.Copy(w, p)
+}
+
+// SaveTo copies content of the part to destination created by the sink. Given hashes are updated with the same content.
+func (p *MultipartPart) SaveTo(sink MultipartSink, hashes ...hash.Hash) (int64, error) {
+	w, err := sink.Create(p)
+	if err != nil {
+		return 0, err
+	}
+	n, err := p.CopyTo(w, hashes...)
+	if cErr := w.Close(); err == nil {
+		err = cErr
+	}
+	return n, err
+}
+
+// Create implements MultipartSink interface.
+func (s DirSink) Create(part *MultipartPart) (io.WriteCloser, error) {
+	name := filepath.Base(filepath.Clean("/" + filepath.FromSlash(part.FileName())))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		return nil, fmt.Errorf("invalid multipart file name: %q", part.FileName())
+	}
+	return os.OpenFile(filepath.Join(s.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+}
+
+func isContentTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, contentType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && len(contentType) > len(prefix) &&
+			strings.EqualFold(contentType[:len(prefix)+1], prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMultipartPart struct {
+	name        string
+	fileName    string
+	contentType string
+	content     string
+}
+
+func newMultipartRequest(t *testing.T, parts []testMultipartPart) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for _, p := range parts {
+		h := make(textproto.MIMEHeader)
+		if p.fileName != "" {
+			h.Set(HeaderContentDisposition, `form-data; name="`+p.name+`"; filename="`+p.fileName+`"`)
+		} else {
+			h.Set(HeaderContentDisposition, `form-data; name="`+p.name+`"`)
+		}
+		if p.contentType != "" {
+			h.Set(HeaderContentType, p.contentType)
+		}
+		w, err := mw.CreatePart(h)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(p.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set(HeaderContentType, mw.FormDataContentType())
+	return req
+}
+
+func TestContext_MultipartStream(t *testing.T) {
+	e := New()
+	req := newMultipartRequest(t, []testMultipartPart{
+		{name: "title", content: "holiday"},
+		{name: "file", fileName: "a.txt", contentType: "text/plain", content: "hello"},
+		{name: "tags", content: "sea"},
+		{name: "file", fileName: "b.txt", contentType: "text/plain", content: "world"},
+		{name: "tags", content: "sun"},
+	})
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	stream, err := c.MultipartStream(MultipartStreamConfig{})
+	assert.NoError(t, err)
+
+	var files []string
+	var fields []string
+	for {
+		part, err := stream.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !part.IsFile() {
+			fields = append(fields, part.FormName()+"="+part.Value())
+			continue
+		}
+		sum := sha256.New()
+		buf := new(bytes.Buffer)
+		n, err := part.CopyTo(buf, sum)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		assert.Equal(t, "text/plain", part.ContentType())
+
+		expectSum := sha256.Sum256(buf.Bytes())
+		assert.Equal(t, hex.EncodeToString(expectSum[:]), hex.EncodeToString(sum.Sum(nil)))
+		files = append(files, part.FileName()+":"+buf.String())
+	}
+
+	assert.Equal(t, []string{"a.txt:hello", "b.txt:world"}, files)
+	assert.Equal(t, []string{"title=holiday", "tags=sea", "tags=sun"}, fields)
+	assert.Equal(t, []string{"sea", "sun"}, stream.Values()["tags"])
+
+	var target struct {
+		Title string   `form:"title"`
+		Tags  []string `form:"tags"`
+	}
+	err = new(DefaultBinder).BindMultipartStream(stream, &target)
+	assert.NoError(t, err)
+	assert.Equal(t, "holiday", target.Title)
+	assert.Equal(t, []string{"sea", "sun"}, target.Tags)
+}
+
+func TestMultipartStream_Limits(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig MultipartStreamConfig
+		whenParts   []testMultipartPart
+		expectErr   error
+	}{
+		{
+			name:        "ok, within limits",
+			givenConfig: MultipartStreamConfig{MaxFileSize: 5, MaxTotalSize: 10, MaxFiles: 1, AllowedContentTypes: []string{"image/*"}},
+			whenParts: []testMultipartPart{
+				{name: "id", content: "1"},
+				{name: "file", fileName: "a.png", contentType: "image/png", content: "12345"},
+			},
+		},
+		{
+			name:        "nok, file too large",
+			givenConfig: MultipartStreamConfig{MaxFileSize: 4},
+			whenParts: []testMultipartPart{
+				{name: "file", fileName: "a.bin", content: "12345"},
+			},
+			expectErr: ErrMultipartFileTooLarge,
+		},
+		{
+			name:        "nok, total too large",
+			givenConfig: MultipartStreamConfig{MaxTotalSize: 8},
+			whenParts: []testMultipartPart{
+				{name: "file", fileName: "a.bin", content: "12345"},
+				{name: "file", fileName: "b.bin", content: "12345"},
+			},
+			expectErr: ErrMultipartTotalTooLarge,
+		},
+		{
+			name:        "nok, field too large",
+			givenConfig: MultipartStreamConfig{MaxFieldSize: 2},
+			whenParts: []testMultipartPart{
+				{name: "id", content: "123"},
+			},
+			expectErr: ErrMultipartFieldTooLarge,
+		},
+		{
+			name:        "nok, too many fields",
+			givenConfig: MultipartStreamConfig{MaxFields: 2},
+			whenParts: []testMultipartPart{
+				{name: "a", content: "1"},
+				{name: "b", content: "2"},
+				{name: "c", content: "3"},
+			},
+			expectErr: ErrMultipartTooManyFields,
+		},
+		{
+			name:        "nok, fields total size too large",
+			givenConfig: MultipartStreamConfig{MaxFieldsSize: 5},
+			whenParts: []testMultipartPart{
+				{name: "a", content: "123"},
+				{name: "b", content: "456"},
+			},
+			expectErr: ErrMultipartFieldsTooLarge,
+		},
+		{
+			name: "nok, default field count limit",
+			whenParts: func() []testMultipartPart {
+				parts := make([]testMultipartPart, defaultMultipartMaxFields+1)
+				for i := range parts {
+					parts[i] = testMultipartPart{name: "f", content: "1"}
+				}
+				return parts
+			}(),
+			expectErr: ErrMultipartTooManyFields,
+		},
+		{
+			name:        "nok, too many files",
+			givenConfig: MultipartStreamConfig{MaxFiles: 1},
+			whenParts: []testMultipartPart{
+				{name: "file", fileName: "a.bin", content: "1"},
+				{name: "file", fileName: "b.bin", content: "2"},
+			},
+			expectErr: ErrMultipartTooManyFiles,
+		},
+		{
+			name:        "nok, content type not allowed",
+			givenConfig: MultipartStreamConfig{AllowedContentTypes: []string{"image/png"}},
+			whenParts: []testMultipartPart{
+				{name: "file", fileName: "a.bin", content: "1"},
+			},
+			expectErr: ErrMultipartContentTypeInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := NewMultipartStream(newMultipartRequest(t, tc.whenParts), tc.givenConfig)
+			assert.NoError(t, err)
+
+			for {
+				var part *MultipartPart
+				part, err = stream.NextPart()
+				if err != nil {
+					break
+				}
+				if _, err = part.CopyTo(io.Discard); err != nil {
+					break
+				}
+			}
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+			} else {
+				assert.Equal(t, io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestNewMultipartStream_notMultipart(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+
+	_, err := NewMultipartStream(req, MultipartStreamConfig{})
+
+	var he *HTTPError
+	assert.True(t, errors.As(err, &he))
+	assert.Equal(t, http.StatusUnsupportedMediaType, he.Code)
+}
+
+func TestMultipartPart_SaveTo(t *testing.T) {
+	dir := t.TempDir()
+	req := newMultipartRequest(t, []testMultipartPart{
+		{name: "file", fileName: "../../evil.txt", content: "hello"},
+	})
+	stream, err := NewMultipartStream(req, MultipartStreamConfig{})
+	assert.NoError(t, err)
+
+	part, err := stream.NextPart()
+	assert.NoError(t, err)
+	n, err := part.SaveTo(DirSink{Dir: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	b, err := os.ReadFile(filepath.Join(dir, "evil.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
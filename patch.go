@@ -0,0 +1,469 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/**
+Following helpers implement JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7386) for PATCH endpoints.
+
+Example:
+	e.PATCH("/users/:id", func(c echo.Context) error {
+		u, err := loadUser(c.Param("id"))
+		if err != nil {
+			return err
+		}
+		// applies `application/json-patch+json` or `application/merge-patch+json` request body to u
+		if err := echo.BindPatch(c, u); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, u)
+	})
+
+Errors are returned as HTTPError:
+  - 400 when patch document is malformed (invalid JSON, unknown op, invalid pointer)
+  - 409 when patch can not be applied to the current state of the document (i.e. path does not exist)
+  - 415 when request content type is not one of the supported patch media types
+  - 422 when `test` operation fails or patched document can not be unmarshalled back to the target
+*/
+
+// JSONPatchOperation is single operation in JSON Patch document.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is JSON Patch document as described in RFC 6902. Bind it from `application/json-patch+json` request body.
+type JSONPatch []JSONPatchOperation
+
+// JSONMergePatch is JSON Merge Patch document as described in RFC 7386. Bind it from `application/merge-patch+json`
+// request body.
+type JSONMergePatch []byte
+
+// ErrJSONPatchTestFailed is returned (as internal error of HTTPError) when `test` operation of JSON Patch fails.
+var ErrJSONPatchTestFailed = errors.New("json patch test operation failed")
+
+// BindPatch applies request body as JSON Patch or JSON Merge Patch, depending on the request content type, to the
+// target. Target must be a pointer to a value that can be marshalled to and unmarshalled from JSON.
+func BindPatch(c Context, target interface{}) error {
+	base, _, _ := strings.Cut(c.Request().Header.Get(HeaderContentType), ";")
+	switch strings.TrimSpace(base) {
+	case MIMEApplicationJSONPatch:
+		var patch JSONPatch
+		if err := c.Bind(&patch); err != nil {
+			return err
+		}
+		return patch.ApplyTo(target)
+	case MIMEApplicationMergePatch:
+		var patch JSONMergePatch
+		if err := c.Bind(&patch); err != nil {
+			return err
+		}
+		return patch.ApplyTo(target)
+	}
+	return ErrUnsupportedMediaType
+}
+
+// Apply applies patch operations to JSON document and returns patched document. Operations are applied in order and
+// patching stops on first failed operation.
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	node, err := decodePatchDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if node, err = op.apply(node); err != nil {
+			return nil, wrapJSONPatchError(err, i, op)
+		}
+	}
+	return json.Marshal(node)
+}
+
+// ApplyTo applies patch to the target. Target is marshalled to JSON, patched and unmarshalled back to the target.
+// Target must be a non-nil pointer.
+func (p JSONPatch) ApplyTo(target interface{}) error {
+	return applyPatchTo(target, p.Apply)
+}
+
+func (op JSONPatchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, newJSONPatchError(http.StatusBadRequest, "missing value")
+		}
+		value, err := decodePatchDocument(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return jsonPointerAdd(doc, path, value)
+		case "replace":
+			return jsonPointerReplace(doc, path, value)
+		}
+		current, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonValuesEqual(current, value) {
+			return nil, &HTTPError{Code: http.StatusUnprocessableEntity, Message: "test operation failed", Internal: ErrJSONPatchTestFailed}
+		}
+		return doc, nil
+	case "remove":
+		return jsonPointerRemove(doc, path)
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return jsonPointerAdd(doc, path, copyJSONValue(value))
+		}
+		if isJSONPointerPrefix(from, path) {
+			return nil, newJSONPatchError(http.StatusBadRequest, "can not move value into one of its children")
+		}
+		if doc, err = jsonPointerRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, value)
+	}
+	return nil, newJSONPatchError(http.StatusBadRequest, fmt.Sprintf("unknown operation %q", op.Op))
+}
+
+// UnmarshalJSON stores raw merge patch document.
+func (p *JSONMergePatch) UnmarshalJSON(b []byte) error {
+	*p = append((*p)[:0], b...)
+	return nil
+}
+
+// MarshalJSON returns raw merge patch document.
+func (p JSONMergePatch) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// Apply merges patch into JSON document and returns patched document.
+func (p JSONMergePatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decodePatchDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := decodePatchDocument(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, patch))
+}
+
+// ApplyTo merges patch into the target. Target is marshalled to JSON, patched and unmarshalled back to the target.
+// Target must be a non-nil pointer.
+func (p JSONMergePatch) ApplyTo(target interface{}) error {
+	return applyPatchTo(target, p.Apply)
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for k, v := range patchObject {
+		if v == nil {
+			delete(targetObject, k)
+			continue
+		}
+		targetObject[k] = mergePatch(targetObject[k], v)
+	}
+	return targetObject
+}
+
+func applyPatchTo(target interface{}, apply func(doc []byte) ([]byte, error)) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("echo: patch target must be a non-nil pointer")
+	}
+	doc, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	patched, err := apply(doc)
+	if err != nil {
+		return err
+	}
+	// target is reset so fields removed by the patch do not keep their old values
+	elem := rv.Elem()
+	backup := reflect.New(elem.Type()).Elem()
+	backup.Set(elem)
+	elem.Set(reflect.Zero(elem.Type()))
+	if err := json.Unmarshal(patched, target); err != nil {
+		elem.Set(backup)
+		return &HTTPError{
+			Code:     http.StatusUnprocessableEntity,
+			Message:  fmt.Sprintf("patched document is not valid for target: %v", err),
+			Internal: err,
+		}
+	}
+	return nil
+}
+
+func decodePatchDocument(doc []byte) (interface{}, error) {
+	var node interface{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber() // numbers are kept as they are so large integers do not lose precision
+	if err := dec.Decode(&node); err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid JSON document: %v", err), Internal: err}
+	}
+	return node, nil
+}
+
+func newJSONPatchError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+func wrapJSONPatchError(err error, index int, op JSONPatchOperation) error {
+	he, ok := err.(*HTTPError)
+	if !ok {
+		return err
+	}
+	return &HTTPError{
+		Code:     he.Code,
+		Message:  fmt.Sprintf("json patch operation %d (%s %s) failed: %v", index, op.Op, op.Path, he.Message),
+		Internal: he.Internal,
+	}
+}
+
+// parseJSONPointer parses JSON Pointer (RFC 6901) into reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, newJSONPatchError(http.StatusBadRequest, fmt.Sprintf("invalid JSON pointer %q", pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isJSONPointerPrefix(prefix []string, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, newJSONPatchError(http.StatusConflict, fmt.Sprintf("path member %q does not exist", token))
+			}
+			doc = v
+		case []interface{}:
+			i, err := jsonPointerIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, newJSONPatchError(http.StatusConflict, fmt.Sprintf("path member %q does not exist", token))
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerModify walks to the parent of the location referenced by path and replaces the parent with the result of fn.
+func jsonPointerModify(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := jsonPointerGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = jsonPointerModify(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		i, _ := jsonPointerIndex(path[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func jsonPointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonPointerModify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := jsonPointerIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, newJSONPatchError(http.StatusConflict, fmt.Sprintf("parent of path member %q is not an object or an array", token))
+	})
+}
+
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, newJSONPatchError(http.StatusBadRequest, "can not remove document root")
+	}
+	return jsonPointerModify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, newJSONPatchError(http.StatusConflict, fmt.Sprintf("path member %q does not exist", token))
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := jsonPointerIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, newJSONPatchError(http.StatusConflict, fmt.Sprintf("path member %q does not exist", token))
+	})
+}
+
+func jsonPointerReplace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	if _, err := jsonPointerGet(doc, path); err != nil {
+		return nil, err
+	}
+	return jsonPointerModify(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+		case []interface{}:
+			i, _ := jsonPointerIndex(token, len(node)-1)
+			node[i] = value
+		}
+		return parent, nil
+	})
+}
+
+// jsonPointerIndex parses array index token. Leading zeros are not allowed by RFC 6901.
+func jsonPointerIndex(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, newJSONPatchError(http.StatusBadRequest, fmt.Sprintf("invalid array index %q", token))
+	}
+	if i > last {
+		return 0, newJSONPatchError(http.StatusConflict, fmt.Sprintf("array index %q is out of bounds", token))
+	}
+	return i, nil
+}
+
+func copyJSONValue(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, v := range node {
+			c[k] = copyJSONValue(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, v := range node {
+			c[i] = copyJSONValue(v)
+		}
+		return c
+	}
+	return v
+}
+
+// jsonValuesEqual compares JSON values as required by `test` operation. Numbers are equal when their values are
+// numerically equal.
+func jsonValuesEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonValuesEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonValuesEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		xf, errX := x.Float64()
+		yf, errY := y.Float64()
+		return errX == nil && errY == nil && xf == yf
+	}
+	return a == b
+}
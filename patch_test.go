@@ -0,0 +1,323 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPatch_Apply(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenDoc    string
+		whenPatch   string
+		expect      string
+		expectCode  int
+		expectError string
+	}{
+		{
+			name:      "ok, add object member",
+			givenDoc:  `{"foo":"bar"}`,
+			whenPatch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			expect:    `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:      "ok, add array element",
+			givenDoc:  `{"foo":["bar","baz"]}`,
+			whenPatch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			expect:    `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:      "ok, add to the end of array",
+			givenDoc:  `{"foo":["bar"]}`,
+			whenPatch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			expect:    `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:      "ok, add null value",
+			givenDoc:  `{"foo":"bar"}`,
+			whenPatch: `[{"op":"add","path":"/baz","value":null}]`,
+			expect:    `{"baz":null,"foo":"bar"}`,
+		},
+		{
+			name:      "ok, remove",
+			givenDoc:  `{"baz":"qux","foo":["bar","qux","baz"]}`,
+			whenPatch: `[{"op":"remove","path":"/baz"},{"op":"remove","path":"/foo/1"}]`,
+			expect:    `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:      "ok, replace",
+			givenDoc:  `{"baz":"qux","foo":"bar"}`,
+			whenPatch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			expect:    `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:      "ok, replace root",
+			givenDoc:  `{"foo":"bar"}`,
+			whenPatch: `[{"op":"replace","path":"","value":[1]}]`,
+			expect:    `[1]`,
+		},
+		{
+			name:      "ok, move",
+			givenDoc:  `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			whenPatch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			expect:    `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:      "ok, move array element",
+			givenDoc:  `{"foo":["all","grass","cows","eat"]}`,
+			whenPatch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			expect:    `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:      "ok, copy is not shared with source",
+			givenDoc:  `{"a":{"b":1}}`,
+			whenPatch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			expect:    `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:      "ok, test with escaped pointer and equal numbers",
+			givenDoc:  `{"/":9,"~1":10,"n":1,"big":12345678901234567890}`,
+			whenPatch: `[{"op":"test","path":"/~01","value":10},{"op":"test","path":"/~1","value":9},{"op":"test","path":"/n","value":1.0},{"op":"test","path":"/big","value":12345678901234567890}]`,
+			expect:    `{"/":9,"big":12345678901234567890,"n":1,"~1":10}`,
+		},
+		{
+			name:        "nok, test failed",
+			givenDoc:    `{"baz":"qux","foo":["a",2,"c"]}`,
+			whenPatch:   `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":"2"}]`,
+			expectCode:  http.StatusUnprocessableEntity,
+			expectError: "code=422, message=json patch operation 1 (test /foo/1) failed: test operation failed, internal=json patch test operation failed",
+		},
+		{
+			name:        "nok, remove missing member",
+			givenDoc:    `{"foo":"bar"}`,
+			whenPatch:   `[{"op":"remove","path":"/baz"}]`,
+			expectCode:  http.StatusConflict,
+			expectError: `code=409, message=json patch operation 0 (remove /baz) failed: path member "baz" does not exist`,
+		},
+		{
+			name:        "nok, add to missing parent",
+			givenDoc:    `{"foo":"bar"}`,
+			whenPatch:   `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			expectCode:  http.StatusConflict,
+			expectError: `code=409, message=json patch operation 0 (add /baz/bat) failed: path member "baz" does not exist`,
+		},
+		{
+			name:        "nok, array index out of bounds",
+			givenDoc:    `{"foo":["bar"]}`,
+			whenPatch:   `[{"op":"add","path":"/foo/2","value":"qux"}]`,
+			expectCode:  http.StatusConflict,
+			expectError: `code=409, message=json patch operation 0 (add /foo/2) failed: array index "2" is out of bounds`,
+		},
+		{
+			name:        "nok, array index with leading zero",
+			givenDoc:    `{"foo":["bar","baz"]}`,
+			whenPatch:   `[{"op":"remove","path":"/foo/01"}]`,
+			expectCode:  http.StatusBadRequest,
+			expectError: `code=400, message=json patch operation 0 (remove /foo/01) failed: invalid array index "01"`,
+		},
+		{
+			name:        "nok, move into own child",
+			givenDoc:    `{"foo":{"bar":1}}`,
+			whenPatch:   `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			expectCode:  http.StatusBadRequest,
+			expectError: `code=400, message=json patch operation 0 (move /foo/bar/baz) failed: can not move value into one of its children`,
+		},
+		{
+			name:        "nok, missing value",
+			givenDoc:    `{}`,
+			whenPatch:   `[{"op":"add","path":"/foo"}]`,
+			expectCode:  http.StatusBadRequest,
+			expectError: `code=400, message=json patch operation 0 (add /foo) failed: missing value`,
+		},
+		{
+			name:        "nok, unknown operation",
+			givenDoc:    `{}`,
+			whenPatch:   `[{"op":"merge","path":"/foo"}]`,
+			expectCode:  http.StatusBadRequest,
+			expectError: `code=400, message=json patch operation 0 (merge /foo) failed: unknown operation "merge"`,
+		},
+		{
+			name:        "nok, invalid pointer",
+			givenDoc:    `{}`,
+			whenPatch:   `[{"op":"remove","path":"foo"}]`,
+			expectCode:  http.StatusBadRequest,
+			expectError: `code=400, message=json patch operation 0 (remove foo) failed: invalid JSON pointer "foo"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var patch JSONPatch
+			assert.NoError(t, json.Unmarshal([]byte(tc.whenPatch), &patch))
+
+			result, err := patch.Apply([]byte(tc.givenDoc))
+
+			if tc.expectError != "" {
+				var he *HTTPError
+				if assert.ErrorAs(t, err, &he) {
+					assert.Equal(t, tc.expectCode, he.Code)
+				}
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(result))
+		})
+	}
+}
+
+func TestJSONPatch_ApplyTestFailedIs(t *testing.T) {
+	patch := JSONPatch{{Op: "test", Path: "/id", Value: json.RawMessage(`2`)}}
+
+	u := user{ID: 1, Name: "Jon Snow"}
+	err := patch.ApplyTo(&u)
+
+	assert.True(t, errors.Is(err, ErrJSONPatchTestFailed))
+	assert.Equal(t, user{ID: 1, Name: "Jon Snow"}, u)
+}
+
+func TestJSONMergePatch_Apply(t *testing.T) {
+	// cases from RFC 7386 Appendix A
+	var testCases = []struct {
+		givenDoc  string
+		whenPatch string
+		expect    string
+	}{
+		{givenDoc: `{"a":"b"}`, whenPatch: `{"a":"c"}`, expect: `{"a":"c"}`},
+		{givenDoc: `{"a":"b"}`, whenPatch: `{"b":"c"}`, expect: `{"a":"b","b":"c"}`},
+		{givenDoc: `{"a":"b"}`, whenPatch: `{"a":null}`, expect: `{}`},
+		{givenDoc: `{"a":"b","b":"c"}`, whenPatch: `{"a":null}`, expect: `{"b":"c"}`},
+		{givenDoc: `{"a":["b"]}`, whenPatch: `{"a":"c"}`, expect: `{"a":"c"}`},
+		{givenDoc: `{"a":"c"}`, whenPatch: `{"a":["b"]}`, expect: `{"a":["b"]}`},
+		{givenDoc: `{"a":{"b":"c"}}`, whenPatch: `{"a":{"b":"d","c":null}}`, expect: `{"a":{"b":"d"}}`},
+		{givenDoc: `{"a":[{"b":"c"}]}`, whenPatch: `{"a":[1]}`, expect: `{"a":[1]}`},
+		{givenDoc: `["a","b"]`, whenPatch: `["c","d"]`, expect: `["c","d"]`},
+		{givenDoc: `{"a":"b"}`, whenPatch: `["c"]`, expect: `["c"]`},
+		{givenDoc: `{"a":"foo"}`, whenPatch: `null`, expect: `null`},
+		{givenDoc: `{"a":"foo"}`, whenPatch: `"bar"`, expect: `"bar"`},
+		{givenDoc: `{"e":null}`, whenPatch: `{"a":1}`, expect: `{"e":null,"a":1}`},
+		{givenDoc: `[1,2]`, whenPatch: `{"a":"b","c":null}`, expect: `{"a":"b"}`},
+		{givenDoc: `{}`, whenPatch: `{"a":{"bb":{"ccc":null}}}`, expect: `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.whenPatch, func(t *testing.T) {
+			result, err := JSONMergePatch(tc.whenPatch).Apply([]byte(tc.givenDoc))
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(result))
+		})
+	}
+}
+
+func TestJSONMergePatch_ApplyToInvalidTarget(t *testing.T) {
+	u := user{ID: 1, Name: "Jon Snow"}
+
+	err := JSONMergePatch(`{"id":"one"}`).ApplyTo(&u)
+
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Equal(t, http.StatusUnprocessableEntity, he.Code)
+	}
+	assert.Equal(t, user{ID: 1, Name: "Jon Snow"}, u)
+
+	assert.EqualError(t, JSONMergePatch(`{}`).ApplyTo(u), "echo: patch target must be a non-nil pointer")
+}
+
+func TestBindPatch(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type profile struct {
+		Name    string   `json:"name"`
+		Tags    []string `json:"tags"`
+		Address *address `json:"address,omitempty"`
+	}
+
+	var testCases = []struct {
+		name        string
+		whenType    string
+		whenBody    string
+		expect      profile
+		expectError string
+	}{
+		{
+			name:     "ok, json patch",
+			whenType: MIMEApplicationJSONPatch,
+			whenBody: `[{"op":"test","path":"/name","value":"Jon"},{"op":"replace","path":"/name","value":"Arya"},{"op":"add","path":"/tags/0","value":"new"},{"op":"remove","path":"/address/zip"}]`,
+			expect:   profile{Name: "Arya", Tags: []string{"new", "a", "b"}, Address: &address{City: "Winterfell"}},
+		},
+		{
+			name:     "ok, merge patch with charset",
+			whenType: MIMEApplicationMergePatch + "; charset=UTF-8",
+			whenBody: `{"name":"Arya","address":{"zip":null},"tags":null}`,
+			expect:   profile{Name: "Arya", Address: &address{City: "Winterfell"}},
+		},
+		{
+			name:        "nok, test failed",
+			whenType:    MIMEApplicationJSONPatch,
+			whenBody:    `[{"op":"test","path":"/name","value":"Arya"},{"op":"replace","path":"/name","value":"Sansa"}]`,
+			expect:      profile{Name: "Jon", Tags: []string{"a", "b"}, Address: &address{City: "Winterfell", Zip: "00001"}},
+			expectError: "code=422, message=json patch operation 0 (test /name) failed: test operation failed, internal=json patch test operation failed",
+		},
+		{
+			name:        "nok, invalid patch document",
+			whenType:    MIMEApplicationJSONPatch,
+			whenBody:    `{"op":"remove"}`,
+			expect:      profile{Name: "Jon", Tags: []string{"a", "b"}, Address: &address{City: "Winterfell", Zip: "00001"}},
+			expectError: "code=400, message=Unmarshal type error: expected=echo.JSONPatch, got=object, field=, offset=1, internal=json: cannot unmarshal object into Go value of type echo.JSONPatch",
+		},
+		{
+			name:        "nok, unsupported media type",
+			whenType:    MIMEApplicationJSON,
+			whenBody:    `{"name":"Arya"}`,
+			expect:      profile{Name: "Jon", Tags: []string{"a", "b"}, Address: &address{City: "Winterfell", Zip: "00001"}},
+			expectError: "code=415, message=Unsupported Media Type",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tc.whenBody))
+			req.Header.Set(HeaderContentType, tc.whenType)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			p := profile{Name: "Jon", Tags: []string{"a", "b"}, Address: &address{City: "Winterfell", Zip: "00001"}}
+			err := BindPatch(c, &p)
+
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expect, p)
+		})
+	}
+}
+
+func TestDefaultBinder_BindBodyPatchTypes(t *testing.T) {
+	e := New()
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`[{"op":"remove","path":"/a"}]`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSONPatch)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var patch JSONPatch
+	assert.NoError(t, c.Bind(&patch))
+	assert.Equal(t, JSONPatch{{Op: "remove", Path: "/a"}}, patch)
+
+	req = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"id":2}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationMergePatch)
+	c = e.NewContext(req, httptest.NewRecorder())
+
+	u := user{ID: 1, Name: "Jon Snow"}
+	assert.NoError(t, c.Bind(&u))
+	assert.Equal(t, user{ID: 2, Name: "Jon Snow"}, u)
+}
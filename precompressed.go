@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PrecompressedEncoding maps content coding to file name extension of precompressed file.
+type PrecompressedEncoding struct {
+	Encoding  string
+	Extension string
+}
+
+// DefaultPrecompressedEncodings are encodings looked up by PrecompressedFS in order of server preference.
+var DefaultPrecompressedEncodings = []PrecompressedEncoding{
+	{Encoding: "br", Extension: ".br"},
+	{Encoding: "gzip", Extension: ".gz"},
+}
+
+type precompressedFS struct {
+	fs.FS
+	encodings []PrecompressedEncoding
+}
+
+// PrecompressedFS wraps file system so that Static handlers and `Context#FileFS` serve precompressed siblings of
+// requested files (`app.js.br`, `app.js.gz`) to clients accepting that encoding. `Content-Encoding` and
+// `Vary: Accept-Encoding` headers are set and `Content-Type` is determined from the name of requested file.
+// Range requests apply to the encoded content.
+//
+// When encodings are not given DefaultPrecompressedEncodings are used.
+//
+//	e.StaticFS("/assets", echo.PrecompressedFS(echo.MustSubFS(assets, "dist")))
+func PrecompressedFS(filesystem fs.FS, encodings ...PrecompressedEncoding) fs.FS {
+	if len(encodings) == 0 {
+		encodings = DefaultPrecompressedEncodings
+	}
+	return &precompressedFS{FS: filesystem, encodings: encodings}
+}
+
+// Sub implements fs.SubFS interface so that sub file systems (ala `MustSubFS`) still serve precompressed files.
+func (p *precompressedFS) Sub(dir string) (fs.FS, error) {
+	sub, err := subFS(p.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &precompressedFS{FS: sub, encodings: p.encodings}, nil
+}
+
+// ServePrecompressed serves precompressed sibling of the file with given name (`app.js.br`, `app.js.gz`) when one
+// exists and client accepts its encoding. Siblings are opened with open function and fi is FileInfo of the original
+// file. `Content-Encoding` and `Vary: Accept-Encoding` headers are set and `Content-Type` is determined from the name
+// of the original file. Returns false when the original file should be served instead.
+//
+// When encodings are not given DefaultPrecompressedEncodings are used.
+func ServePrecompressed(c Context, open func(name string) (fs.File, error), name string, fi fs.FileInfo, encodings ...PrecompressedEncoding) bool {
+	if len(encodings) == 0 {
+		encodings = DefaultPrecompressedEncodings
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(fi.Name()))
+	if contentType == "" {
+		return false // without known type we would sniff content type from compressed bytes
+	}
+
+	offers := make([]string, 0, len(encodings))
+	extensions := make(map[string]string, len(encodings))
+	for _, e := range encodings {
+		f, err := open(name + e.Extension)
+		if err != nil {
+			continue
+		}
+		sfi, err := f.Stat()
+		f.Close()
+		if err != nil || sfi.IsDir() {
+			continue
+		}
+		offers = append(offers, e.Encoding)
+		extensions[e.Encoding] = e.Extension
+	}
+	if len(offers) == 0 {
+		return false
+	}
+
+	res := c.Response()
+	AddVary(res.Header(), HeaderAcceptEncoding)
+
+	encoding := NegotiateEncoding(c.Request().Header.Get(HeaderAcceptEncoding), offers...)
+	if encoding == "" {
+		return false
+	}
+	f, err := open(name + extensions[encoding])
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	header := res.Header()
+	header.Set(HeaderContentType, contentType)
+	header.Set(HeaderContentEncoding, encoding)
+	if etag := header.Get(HeaderETag); etag != "" && strings.HasSuffix(etag, `"`) {
+		// different representations of the same resource must have different entity tags
+		header.Set(HeaderETag, etag[:len(etag)-1]+"-"+encoding+`"`)
+	}
+	http.ServeContent(res, c.Request(), fi.Name(), fi.ModTime(), rs)
+	return true
+}
+
+// NegotiateEncoding selects the best content coding from offers for given `Accept-Encoding` header value using
+// q-values. Offers are given in order of server preference which is used to break ties. Empty string is returned
+// when none of the offers is acceptable, in which case response should not be encoded.
+// See RFC 9110 section 12.5.3: https://www.rfc-editor.org/rfc/rfc9110#section-12.5.3
+func NegotiateEncoding(acceptEncoding string, offers ...string) string {
+	if acceptEncoding == "" || len(offers) == 0 {
+		return ""
+	}
+	accepted := ParseAcceptEncoding(acceptEncoding)
+
+	best := ""
+	bestQ := 0.0
+	for _, offer := range offers {
+		q, ok := accepted[strings.ToLower(offer)]
+		if !ok {
+			if q, ok = accepted["*"]; !ok {
+				continue
+			}
+		}
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// ParseAcceptEncoding parses `Accept-Encoding` header value into map of lower cased content codings and their
+// q-values. Invalid q-values are treated as 0.
+func ParseAcceptEncoding(acceptEncoding string) map[string]float64 {
+	return ParseQualityValues(acceptEncoding)
+}
+
+// ParseQualityValues parses value of header with weighted elements (`Accept`, `Accept-Encoding`, `Accept-Language`)
+// into map of lower cased elements and their q-values. Parameters other than q are dropped. Invalid q-values are
+// treated as 0.
+func ParseQualityValues(header string) map[string]float64 {
+	result := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(k), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		result[coding] = q
+	}
+	return result
+}
+
+// AddVary adds value to `Vary` header unless it is already present.
+func AddVary(header http.Header, value string) {
+	for _, v := range header.Values(HeaderVary) {
+		for _, existing := range strings.Split(v, ",") {
+			existing = strings.TrimSpace(existing)
+			if existing == "*" || strings.EqualFold(existing, value) {
+				return
+			}
+		}
+	}
+	header.Add(HeaderVary, value)
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecompressedFS(t *testing.T) {
+	assets := fstest.MapFS{
+		"dist/app.js":         {Data: []byte("console.log('raw')")},
+		"dist/app.js.br":      {Data: []byte("BR")},
+		"dist/app.js.gz":      {Data: []byte("GZIP")},
+		"dist/style.css":      {Data: []byte("body{}")},
+		"dist/data.xyz123":    {Data: []byte("raw-bin")},
+		"dist/data.xyz123.gz": {Data: []byte("GZIP-bin")},
+	}
+
+	var testCases = []struct {
+		name                  string
+		whenURL               string
+		whenAcceptEncoding    string
+		whenRange             string
+		expectBody            string
+		expectStatus          int
+		expectContentEncoding string
+		expectContentType     string
+		expectVary            string
+	}{
+		{
+			name:                  "ok, brotli is preferred",
+			whenURL:               "/assets/app.js",
+			whenAcceptEncoding:    "gzip, deflate, br",
+			expectStatus:          http.StatusOK,
+			expectBody:            "BR",
+			expectContentEncoding: "br",
+			expectContentType:     "text/javascript; charset=utf-8",
+			expectVary:            HeaderAcceptEncoding,
+		},
+		{
+			name:                  "ok, q-values are respected",
+			whenURL:               "/assets/app.js",
+			whenAcceptEncoding:    "br;q=0.5, gzip",
+			expectStatus:          http.StatusOK,
+			expectBody:            "GZIP",
+			expectContentEncoding: "gzip",
+			expectContentType:     "text/javascript; charset=utf-8",
+			expectVary:            HeaderAcceptEncoding,
+		},
+		{
+			name:               "ok, raw file when encodings are not accepted",
+			whenURL:            "/assets/app.js",
+			whenAcceptEncoding: "br;q=0, gzip;q=0",
+			expectStatus:       http.StatusOK,
+			expectBody:         "console.log('raw')",
+			expectContentType:  "text/javascript; charset=utf-8",
+			expectVary:         HeaderAcceptEncoding,
+		},
+		{
+			name:               "ok, raw file without siblings",
+			whenURL:            "/assets/style.css",
+			whenAcceptEncoding: "gzip",
+			expectStatus:       http.StatusOK,
+			expectBody:         "body{}",
+			expectContentType:  "text/css; charset=utf-8",
+		},
+		{
+			name:                  "ok, range applies to encoded content",
+			whenURL:               "/assets/app.js",
+			whenAcceptEncoding:    "gzip",
+			whenRange:             "bytes=1-2",
+			expectStatus:          http.StatusPartialContent,
+			expectBody:            "ZI",
+			expectContentEncoding: "gzip",
+			expectContentType:     "text/javascript; charset=utf-8",
+			expectVary:            HeaderAcceptEncoding,
+		},
+		{
+			name:               "ok, unknown content type is served raw",
+			whenURL:            "/assets/data.xyz123",
+			whenAcceptEncoding: "gzip",
+			expectStatus:       http.StatusOK,
+			expectBody:         "raw-bin",
+			expectContentType:  "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New()
+			e.StaticFS("/assets", MustSubFS(PrecompressedFS(assets), "dist"))
+
+			req := httptest.NewRequest(http.MethodGet, tc.whenURL, nil)
+			req.Header.Set(HeaderAcceptEncoding, tc.whenAcceptEncoding)
+			if tc.whenRange != "" {
+				req.Header.Set(HeaderRange, tc.whenRange)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+			assert.Equal(t, tc.expectContentEncoding, rec.Header().Get(HeaderContentEncoding))
+			assert.Equal(t, tc.expectContentType, rec.Header().Get(HeaderContentType))
+			assert.Equal(t, tc.expectVary, rec.Header().Get(HeaderVary))
+		})
+	}
+}
+
+func TestServePrecompressed(t *testing.T) {
+	filesystem := fstest.MapFS{
+		"app.js":    {Data: []byte("plain")},
+		"app.js.gz": {Data: []byte("gzipped")},
+		"app.js.br": {Data: []byte("brotli")},
+	}
+	fi, err := fs.Stat(filesystem, "app.js")
+	assert.NoError(t, err)
+
+	e := New()
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set(HeaderAcceptEncoding, "gzip, br;q=0.5")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.True(t, ServePrecompressed(c, filesystem.Open, "app.js", fi, PrecompressedEncoding{Encoding: "gzip", Extension: ".gz"}))
+	assert.Equal(t, "gzip", rec.Header().Get(HeaderContentEncoding))
+	assert.Equal(t, HeaderAcceptEncoding, rec.Header().Get(HeaderVary))
+	assert.Equal(t, "gzipped", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set(HeaderAcceptEncoding, "identity")
+	c = e.NewContext(req, httptest.NewRecorder())
+	assert.False(t, ServePrecompressed(c, filesystem.Open, "app.js", fi))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		acceptEncoding string
+		offers         []string
+		expect         string
+	}{
+		{name: "empty header", acceptEncoding: "", offers: []string{"gzip"}, expect: ""},
+		{name: "server preference on tie", acceptEncoding: "gzip, br", offers: []string{"br", "gzip"}, expect: "br"},
+		{name: "highest q wins", acceptEncoding: "gzip;q=1.0, br;q=0.8", offers: []string{"br", "gzip"}, expect: "gzip"},
+		{name: "q=0 excludes", acceptEncoding: "gzip;q=0", offers: []string{"gzip"}, expect: ""},
+		{name: "wildcard", acceptEncoding: "*;q=0.1", offers: []string{"deflate"}, expect: "deflate"},
+		{name: "wildcard does not override explicit", acceptEncoding: "*, gzip;q=0", offers: []string{"gzip"}, expect: ""},
+		{name: "case insensitive", acceptEncoding: "GZIP", offers: []string{"gzip"}, expect: "gzip"},
+		{name: "invalid q", acceptEncoding: "gzip;q=abc", offers: []string{"gzip"}, expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, NegotiateEncoding(tc.acceptEncoding, tc.offers...))
+		})
+	}
+}
+
+func TestParseQualityValues(t *testing.T) {
+	assert.Equal(t, map[string]float64{
+		"text/html":        1,
+		"application/json": 0.9,
+		"*/*":              0,
+	}, ParseQualityValues("text/html;level=1, Application/JSON;q=0.9, */*;q=abc"))
+}
+
+func TestAddVary(t *testing.T) {
+	h := http.Header{}
+	AddVary(h, HeaderAcceptEncoding)
+	AddVary(h, "accept-encoding")
+	AddVary(h, HeaderOrigin)
+	assert.Equal(t, []string{HeaderAcceptEncoding, HeaderOrigin}, h.Values(HeaderVary))
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// secureCookieMaxSize is maximum size of the cookie value browsers are required to support (RFC 6265 section 6.1).
+const secureCookieMaxSize = 4096
+
+const (
+	secureCookieSigned    byte = 's'
+	secureCookieEncrypted byte = 'e'
+	// secureCookieHeaderSize is size of the mode byte and expiry timestamp preceding the cookie value
+	secureCookieHeaderSize = 1 + 8
+)
+
+var (
+	// ErrSecureCookieKeysNotSet is returned by secure cookie methods of the Context when `Echo.CookieKeys` is nil.
+	ErrSecureCookieKeysNotSet = errors.New("echo: secure cookie keys are not set")
+	// ErrSecureCookieExpired is returned when expiry time embedded in the secure cookie value has passed.
+	ErrSecureCookieExpired = errors.New("echo: secure cookie has expired")
+	// ErrSecureCookieTooLarge is returned when encoded secure cookie value exceeds 4096 bytes.
+	ErrSecureCookieTooLarge = errors.New("echo: secure cookie value is too large")
+)
+
+// InvalidCookieError is returned when secure cookie value is malformed, was modified by the client or is signed with
+// a key that is not in the key ring anymore.
+type InvalidCookieError struct {
+	Name string
+}
+
+// Error returns error message.
+func (e *InvalidCookieError) Error() string {
+	return fmt.Sprintf("echo: secure cookie %q is invalid or has been tampered with", e.Name)
+}
+
+// SecureCookieOptions are attributes of the cookie set with `Context#SetSecureCookie`.
+type SecureCookieOptions struct {
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+
+	// MaxAge is cookie lifetime in seconds. When MaxAge is positive the expiry is also embedded into the signed cookie
+	// value so that clients can not extend cookie lifetime. MaxAge takes precedence over Expires.
+	MaxAge int
+	// Expires is cookie expiry time. When set the expiry is also embedded into the signed cookie value.
+	Expires time.Time
+
+	// Encrypt encrypts cookie value with AES-GCM so clients can not read it. Value is always signed.
+	Encrypt bool
+}
+
+// CookieKeyRing holds keys used to sign and encrypt secure cookies.
+//
+// Keys are used for key rotation: first key is used for new cookies and all keys are tried when cookie is verified.
+// To rotate keys add new key to the beginning of the list and remove the oldest key after cookies signed with it
+// have expired.
+type CookieKeyRing struct {
+	keys []cookieKey
+}
+
+type cookieKey struct {
+	aead    cipher.AEAD
+	signKey []byte
+}
+
+// NewCookieKeyRing returns CookieKeyRing using given keys. At least one key is required and keys must be at least
+// 32 bytes long.
+func NewCookieKeyRing(keys ...[]byte) (*CookieKeyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("echo: cookie key ring requires at least one key")
+	}
+	ring := &CookieKeyRing{keys: make([]cookieKey, 0, len(keys))}
+	for _, key := range keys {
+		if len(key) < 32 {
+			return nil, errors.New("echo: cookie key must be at least 32 bytes long")
+		}
+		block, err := aes.NewCipher(deriveCookieKey(key, "encryption"))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys = append(ring.keys, cookieKey{aead: aead, signKey: deriveCookieKey(key, "signature")})
+	}
+	return ring, nil
+}
+
+// deriveCookieKey derives separate keys for encryption and signing from the same secret.
+func deriveCookieKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("echo cookie " + purpose))
+	return mac.Sum(nil)
+}
+
+// Encode signs and optionally encrypts the value of the named cookie with the first key of the key ring. Cookie name
+// is part of the signature so value of one cookie can not be used as value of another cookie. Zero expiresAt means
+// that the value does not expire.
+func (r *CookieKeyRing) Encode(name string, value string, expiresAt time.Time, encrypt bool) (string, error) {
+	key := r.keys[0]
+
+	payload := make([]byte, secureCookieHeaderSize, secureCookieHeaderSize+len(value)+key.aead.NonceSize()+key.aead.Overhead())
+	payload[0] = secureCookieSigned
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(payload[1:secureCookieHeaderSize], uint64(expiresAt.Unix()))
+	}
+	if encrypt {
+		payload[0] = secureCookieEncrypted
+		nonce := make([]byte, key.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		payload = append(payload, nonce...)
+		// header is authenticated by the signature, name binds ciphertext to the cookie
+		payload = key.aead.Seal(payload, nonce, []byte(value), []byte(name))
+	} else {
+		payload = append(payload, value...)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(cookieSignature(key.signKey, name, payload, payload))
+	if len(encoded) > secureCookieMaxSize {
+		return "", ErrSecureCookieTooLarge
+	}
+	return encoded, nil
+}
+
+// Decode verifies the encoded value of the named cookie with all keys of the key ring and returns the original value.
+// Returns `*InvalidCookieError` when value is not valid and `ErrSecureCookieExpired` when embedded expiry has passed.
+func (r *CookieKeyRing) Decode(name string, encoded string, now time.Time) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(b) < secureCookieHeaderSize+sha256.Size {
+		return "", &InvalidCookieError{Name: name}
+	}
+	payload, signature := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	for _, key := range r.keys {
+		if !hmac.Equal(signature, cookieSignature(key.signKey, name, payload, nil)) {
+			continue
+		}
+		if expires := int64(binary.BigEndian.Uint64(payload[1:secureCookieHeaderSize])); expires != 0 && now.Unix() >= expires {
+			return "", ErrSecureCookieExpired
+		}
+		value := payload[secureCookieHeaderSize:]
+		switch payload[0] {
+		case secureCookieSigned:
+			return string(value), nil
+		case secureCookieEncrypted:
+			nonceSize := key.aead.NonceSize()
+			if len(value) < nonceSize {
+				break
+			}
+			plain, err := key.aead.Open(nil, value[:nonceSize], value[nonceSize:], []byte(name))
+			if err != nil {
+				break
+			}
+			return string(plain), nil
+		}
+		return "", &InvalidCookieError{Name: name}
+	}
+	return "", &InvalidCookieError{Name: name}
+}
+
+// cookieSignature appends HMAC-SHA256 of cookie name and payload to dst.
+func cookieSignature(signKey []byte, name string, payload []byte, dst []byte) []byte {
+	mac := hmac.New(sha256.New, signKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(dst)
+}
+
+func (c *context) SetSecureCookie(name string, value string, opts SecureCookieOptions) error {
+	if c.echo.CookieKeys == nil {
+		return ErrSecureCookieKeysNotSet
+	}
+	expiresAt := opts.Expires
+	if opts.MaxAge > 0 {
+		expiresAt = time.Now().Add(time.Duration(opts.MaxAge) * time.Second)
+	}
+	encoded, err := c.echo.CookieKeys.Encode(name, value, expiresAt, opts.Encrypt)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     name,
+		Value:    encoded,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Expires:  opts.Expires,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	})
+	return nil
+}
+
+func (c *context) SecureCookie(name string) (*http.Cookie, error) {
+	if c.echo.CookieKeys == nil {
+		return nil, ErrSecureCookieKeysNotSet
+	}
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := c.echo.CookieKeys.Decode(name, cookie.Value, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	cookie.Value = value
+	return cookie, nil
+}
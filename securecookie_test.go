@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testCookieKey1 = []byte("0123456789abcdef0123456789abcdef")
+	testCookieKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func mustCookieKeyRing(t *testing.T, keys ...[]byte) *CookieKeyRing {
+	ring, err := NewCookieKeyRing(keys...)
+	assert.NoError(t, err)
+	return ring
+}
+
+// secureCookieRoundTrip sets secure cookie with one echo instance and reads it with another
+func secureCookieRoundTrip(t *testing.T, setKeys *CookieKeyRing, getKeys *CookieKeyRing, opts SecureCookieOptions, tamper func(string) string) (*http.Cookie, error) {
+	e := New()
+	e.CookieKeys = setKeys
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	assert.NoError(t, c.SetSecureCookie("user", "jon=admin; 1", opts))
+
+	cookies := rec.Result().Cookies()
+	if !assert.Len(t, cookies, 1) {
+		return nil, nil
+	}
+	sent := cookies[0]
+	if tamper != nil {
+		sent.Value = tamper(sent.Value)
+	}
+
+	e.CookieKeys = getKeys
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sent.Name, Value: sent.Value})
+	c = e.NewContext(req, httptest.NewRecorder())
+	return c.SecureCookie("user")
+}
+
+func TestContext_SecureCookie(t *testing.T) {
+	ring1 := mustCookieKeyRing(t, testCookieKey1)
+
+	var testCases = []struct {
+		name        string
+		givenOpts   SecureCookieOptions
+		givenGet    *CookieKeyRing
+		givenTamper func(string) string
+		expectErr   string
+	}{
+		{
+			name:      "ok, signed",
+			givenOpts: SecureCookieOptions{Path: "/", HttpOnly: true},
+		},
+		{
+			name:      "ok, encrypted with max age",
+			givenOpts: SecureCookieOptions{Encrypt: true, MaxAge: 3600},
+		},
+		{
+			name:     "ok, old key is still accepted after rotation",
+			givenGet: mustCookieKeyRing(t, testCookieKey2, testCookieKey1),
+		},
+		{
+			name:      "nok, key removed from ring",
+			givenOpts: SecureCookieOptions{Encrypt: true},
+			givenGet:  mustCookieKeyRing(t, testCookieKey2),
+			expectErr: `echo: secure cookie "user" is invalid or has been tampered with`,
+		},
+		{
+			name:      "nok, expired",
+			givenOpts: SecureCookieOptions{Expires: time.Now().Add(-time.Minute)},
+			expectErr: "echo: secure cookie has expired",
+		},
+		{
+			name: "nok, tampered signed value",
+			givenTamper: func(v string) string {
+				b := []byte(v)
+				b[15] ^= 1
+				return string(b)
+			},
+			expectErr: `echo: secure cookie "user" is invalid or has been tampered with`,
+		},
+		{
+			name:      "nok, tampered encrypted value",
+			givenOpts: SecureCookieOptions{Encrypt: true},
+			givenTamper: func(v string) string {
+				return v[:len(v)-4] + "AAAA"
+			},
+			expectErr: `echo: secure cookie "user" is invalid or has been tampered with`,
+		},
+		{
+			name:        "nok, not base64",
+			givenTamper: func(v string) string { return "%%%" },
+			expectErr:   `echo: secure cookie "user" is invalid or has been tampered with`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			getKeys := ring1
+			if tc.givenGet != nil {
+				getKeys = tc.givenGet
+			}
+
+			cookie, err := secureCookieRoundTrip(t, ring1, getKeys, tc.givenOpts, tc.givenTamper)
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "jon=admin; 1", cookie.Value)
+		})
+	}
+}
+
+func TestContext_SetSecureCookie(t *testing.T) {
+	e := New()
+	e.CookieKeys = mustCookieKeyRing(t, testCookieKey1)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	err := c.SetSecureCookie("user", "jon", SecureCookieOptions{
+		Path:     "/admin",
+		Domain:   "example.com",
+		MaxAge:   60,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Encrypt:  true,
+	})
+
+	assert.NoError(t, err)
+	cookie := rec.Result().Cookies()[0]
+	assert.Equal(t, "user", cookie.Name)
+	assert.NotContains(t, cookie.Value, "jon")
+	assert.Equal(t, "/admin", cookie.Path)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, 60, cookie.MaxAge)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+
+	err = c.SetSecureCookie("big", strings.Repeat("x", 4096), SecureCookieOptions{})
+	assert.ErrorIs(t, err, ErrSecureCookieTooLarge)
+}
+
+func TestContext_SecureCookieErrors(t *testing.T) {
+	e := New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.ErrorIs(t, c.SetSecureCookie("user", "jon", SecureCookieOptions{}), ErrSecureCookieKeysNotSet)
+	_, err := c.SecureCookie("user")
+	assert.ErrorIs(t, err, ErrSecureCookieKeysNotSet)
+
+	e.CookieKeys = mustCookieKeyRing(t, testCookieKey1)
+	_, err = c.SecureCookie("user")
+	assert.ErrorIs(t, err, http.ErrNoCookie)
+}
+
+func TestCookieKeyRing_Decode(t *testing.T) {
+	ring := mustCookieKeyRing(t, testCookieKey1)
+	now := time.Now()
+
+	encoded, err := ring.Encode("a", "value", now.Add(time.Minute), false)
+	assert.NoError(t, err)
+
+	// value of one cookie can not be used as value of another cookie
+	_, err = ring.Decode("b", encoded, now)
+	var invalidErr *InvalidCookieError
+	if assert.True(t, errors.As(err, &invalidErr)) {
+		assert.Equal(t, "b", invalidErr.Name)
+	}
+
+	value, err := ring.Decode("a", encoded, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = ring.Decode("a", encoded, now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrSecureCookieExpired)
+}
+
+func TestNewCookieKeyRing(t *testing.T) {
+	_, err := NewCookieKeyRing()
+	assert.EqualError(t, err, "echo: cookie key ring requires at least one key")
+
+	_, err = NewCookieKeyRing(testCookieKey1, []byte("short"))
+	assert.EqualError(t, err, "echo: cookie key must be at least 32 bytes long")
+}
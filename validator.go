@@ -0,0 +1,659 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationField is field value passed to validation rule.
+type ValidationField struct {
+	// Value is value of the field. Pointers are dereferenced.
+	Value reflect.Value
+	// Param is parameter of the rule. i.e. `min=1` has parameter `1`.
+	Param string
+	// Parent is struct containing the field. Used by cross-field rules.
+	Parent reflect.Value
+}
+
+// ValidationRule checks field value. Returns false when value is invalid or can not be checked with the rule
+// parameter. Rules are called during request handling so they should not panic.
+type ValidationRule func(field ValidationField) bool
+
+// ValidationError describes field that failed validation.
+type ValidationError struct {
+	// Field is path of the field using JSON field names. i.e. `items[0].qty`
+	Field string `json:"field"`
+	// Rule is name of the failed rule.
+	Rule string `json:"rule"`
+	// Param is parameter of the failed rule.
+	Param string `json:"param,omitempty"`
+	// Reason describes why validation failed.
+	Reason string `json:"reason"`
+}
+
+// ValidationErrors is returned by DefaultValidator when one or more fields are invalid.
+type ValidationErrors struct {
+	*HTTPError
+	// Errors are field errors in order of struct fields.
+	Errors []ValidationError `json:"errors"`
+}
+
+// Error returns error message
+func (ve *ValidationErrors) Error() string {
+	fields := make([]string, len(ve.Errors))
+	for i, fe := range ve.Errors {
+		fields[i] = fe.Field + ":" + fe.Rule
+	}
+	return fmt.Sprintf("%s, fields=%s", ve.HTTPError.Error(), strings.Join(fields, ","))
+}
+
+// DefaultValidator is Validator implementation driven by `validate` struct tags.
+//
+//	type User struct {
+//		Name     string    `json:"name" validate:"required,max=64"`
+//		Email    string    `json:"email" validate:"required,email"`
+//		Role     string    `json:"role" validate:"omitempty,oneof=admin user"`
+//		Password string    `json:"password" validate:"min=8"`
+//		Confirm  string    `json:"confirm" validate:"eqfield=Password"`
+//		Tags     []string  `json:"tags" validate:"max=5,dive,required"`
+//		Address  *Address  `json:"address" validate:"required"`
+//	}
+//
+//	e.Validator = echo.NewValidator()
+//
+// Rules are separated by comma and are checked in order until the first failing rule. Supported rules:
+//   - `required` - value must not be zero value (empty string, nil pointer, empty slice etc.)
+//   - `omitempty` - skips following rules when value is zero value
+//   - `min=n`, `max=n`, `len=n` - numbers are compared by value, strings by number of characters and slices/maps by
+//     number of elements
+//   - `email` - valid email address
+//   - `url` - absolute URL with scheme and host
+//   - `oneof=a b c` - value must be one of space separated values
+//   - `eqfield=F`, `nefield=F`, `gtfield=F`, `gtefield=F`, `ltfield=F`, `ltefield=F` - compares value to other
+//     exported field (Go field name) of the same struct
+//   - `dive` - following rules are applied to each element of slice, array or map
+//
+// Nested structs (also in slices and maps) are validated recursively. Use `validate:"-"` to skip the field. Fields
+// of embedded structs without `json` name and `validate` tag are validated as fields of the parent struct, like
+// `encoding/json` flattens them.
+// Errors use JSON field names (`json` tag) and are returned as ValidationErrors which is rendered by
+// `DefaultHTTPErrorHandler` with list of invalid fields. Invalid rules and rule parameters (`min=abc`) are returned
+// by Validate as errors.
+type DefaultValidator struct {
+	rules map[string]ValidationRule
+	// paramChecks check parameters of built-in rules against struct and field type when struct tags are parsed
+	paramChecks map[string]func(parent reflect.Type, typ reflect.Type, param string) error
+	cache       sync.Map // reflect.Type -> []validatedField
+}
+
+type validationRuleRef struct {
+	name  string
+	param string
+}
+
+type validatedField struct {
+	index     []int
+	name      string
+	rules     []validationRuleRef
+	diveRules []validationRuleRef
+	dive      bool
+	omitEmpty bool
+}
+
+// NewValidator creates DefaultValidator with built-in rules.
+func NewValidator() *DefaultValidator {
+	v := &DefaultValidator{
+		rules:       map[string]ValidationRule{},
+		paramChecks: map[string]func(parent reflect.Type, typ reflect.Type, param string) error{},
+	}
+	for name, rule := range builtinValidationRules {
+		v.rules[name] = rule
+	}
+	for _, name := range []string{"min", "max", "len"} {
+		v.paramChecks[name] = checkCompareParam
+	}
+	for _, name := range []string{"eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield"} {
+		v.paramChecks[name] = checkFieldParam
+	}
+	return v
+}
+
+// RegisterRule adds custom validation rule or replaces existing rule with the same name. Rules must be registered
+// before validator is used.
+//
+//	v.RegisterRule("even", func(f echo.ValidationField) bool {
+//		return f.Value.Int()%2 == 0
+//	})
+func (v *DefaultValidator) RegisterRule(name string, rule ValidationRule) {
+	v.rules[name] = rule
+	delete(v.paramChecks, name)
+}
+
+// Validate implements Validator interface. Values that are not struct or pointer to struct are not validated.
+func (v *DefaultValidator) Validate(i interface{}) error {
+	val := reflect.ValueOf(i)
+	for val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []ValidationError
+	if err := v.validateStruct(val, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationErrors{
+			HTTPError: &HTTPError{Code: http.StatusBadRequest, Message: "validation failed"},
+			Errors:    errs,
+		}
+	}
+	return nil
+}
+
+func (v *DefaultValidator) validateStruct(val reflect.Value, path string, errs *[]ValidationError) error {
+	fields, err := v.structFields(val.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+		field := fieldByIndex(val, f.index)
+		if f.omitEmpty && isZeroValue(field) {
+			continue
+		}
+		if err := v.validateValue(field, val, fieldPath, f.rules, errs); err != nil {
+			return err
+		}
+		fieldVal := indirectValue(field)
+		if f.dive {
+			if err := v.validateElements(fieldVal, val, fieldPath, f.diveRules, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := v.validateNested(fieldVal, fieldPath, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValue checks rules for value and adds error for the first failed rule.
+func (v *DefaultValidator) validateValue(val reflect.Value, parent reflect.Value, path string, rules []validationRuleRef, errs *[]ValidationError) error {
+	for _, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if isZeroValue(val) {
+				return nil
+			}
+			continue
+		case "required":
+			if isZeroValue(val) {
+				*errs = append(*errs, newValidationError(path, r))
+				return nil
+			}
+			continue
+		}
+
+		rule, ok := v.rules[r.name]
+		if !ok {
+			return fmt.Errorf("echo: unknown validation rule: %s", r.name)
+		}
+		indirect := indirectValue(val)
+		if !indirect.IsValid() {
+			return nil // nil pointer, only `required` can fail
+		}
+		if !rule(ValidationField{Value: indirect, Param: r.param, Parent: parent}) {
+			*errs = append(*errs, newValidationError(path, r))
+			return nil
+		}
+	}
+	return nil
+}
+
+func (v *DefaultValidator) validateElements(val reflect.Value, parent reflect.Value, path string, rules []validationRuleRef, errs *[]ValidationError) error {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			if err := v.validateValue(val.Index(i), parent, elemPath, rules, errs); err != nil {
+				return err
+			}
+			if err := v.validateNested(indirectValue(val.Index(i)), elemPath, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			elem := val.MapIndex(key)
+			elemPath := path + "[" + fmt.Sprint(key.Interface()) + "]"
+			if err := v.validateValue(elem, parent, elemPath, rules, errs); err != nil {
+				return err
+			}
+			if err := v.validateNested(indirectValue(elem), elemPath, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateNested validates structs and structs in slices/maps without `dive` rules.
+func (v *DefaultValidator) validateNested(val reflect.Value, path string, errs *[]ValidationError) error {
+	switch val.Kind() {
+	case reflect.Struct:
+		return v.validateStruct(val, path, errs)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		elemType := val.Type().Elem()
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct {
+			return v.validateElements(val, reflect.Value{}, path, nil, errs)
+		}
+	}
+	return nil
+}
+
+func (v *DefaultValidator) structFields(typ reflect.Type) ([]validatedField, error) {
+	if cached, ok := v.cache.Load(typ); ok {
+		return cached.([]validatedField), nil
+	}
+	fields, err := v.appendStructFields(nil, typ, typ, nil, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	fields = dominantFields(fields)
+	v.cache.Store(typ, fields)
+	return fields, nil
+}
+
+// appendStructFields parses validation rules of struct fields. Fields of embedded structs are added as fields of the
+// root struct.
+func (v *DefaultValidator) appendStructFields(fields []validatedField, root reflect.Type, typ reflect.Type, index []int, visited map[reflect.Type]bool) ([]validatedField, error) {
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if sf.Anonymous && tag == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if embedded.Kind() == reflect.Struct && jsonName == "" {
+				if visited[embedded] {
+					continue
+				}
+				var err error
+				if fields, err = v.appendStructFields(fields, root, embedded, fieldIndex, visited); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := validatedField{index: fieldIndex, name: jsonFieldName(sf)}
+		if tag != "" {
+			for _, part := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+				if name == "" {
+					continue
+				}
+				if name == "dive" {
+					f.dive = true
+					continue
+				}
+				if name != "omitempty" && name != "required" {
+					if _, ok := v.rules[name]; !ok {
+						return nil, fmt.Errorf("echo: unknown validation rule %q for field %s.%s", name, root.Name(), sf.Name)
+					}
+				}
+				ruleType := sf.Type
+				if f.dive {
+					ruleType = elemType(ruleType)
+				}
+				if check, ok := v.paramChecks[name]; ok {
+					if err := check(root, ruleType, param); err != nil {
+						return nil, fmt.Errorf("echo: invalid parameter of validation rule %q for field %s.%s: %w", name, root.Name(), sf.Name, err)
+					}
+				}
+				ref := validationRuleRef{name: name, param: param}
+				if f.dive {
+					f.diveRules = append(f.diveRules, ref)
+				} else {
+					f.omitEmpty = f.omitEmpty || name == "omitempty"
+					f.rules = append(f.rules, ref)
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// dominantFields removes fields hidden by fields with the same name at shallower depth of embedding. Fields with the
+// same name at the same depth are all removed as `encoding/json` does not use them either.
+func dominantFields(fields []validatedField) []validatedField {
+	depths := make(map[string]int, len(fields))
+	counts := make(map[string]int, len(fields))
+	for _, f := range fields {
+		d, ok := depths[f.name]
+		if !ok || len(f.index) < d {
+			depths[f.name] = len(f.index)
+			counts[f.name] = 1
+		} else if len(f.index) == d {
+			counts[f.name]++
+		}
+	}
+	result := fields[:0]
+	for _, f := range fields {
+		if len(f.index) == depths[f.name] && counts[f.name] == 1 {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// elemType returns type of elements of slice, array or map type. Returns nil for other types.
+func elemType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return typ.Elem()
+	}
+	return nil
+}
+
+// fieldByIndex returns nested field by index. Returns invalid value when field is in nil embedded struct pointer.
+func fieldByIndex(val reflect.Value, index []int) reflect.Value {
+	field, err := val.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return field
+}
+
+func jsonFieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func newValidationError(path string, r validationRuleRef) ValidationError {
+	var reason string
+	switch r.name {
+	case "required":
+		reason = "is required"
+	case "min":
+		reason = "must be at least " + r.param
+	case "max":
+		reason = "must be at most " + r.param
+	case "len":
+		reason = "must have length " + r.param
+	case "email":
+		reason = "must be a valid email address"
+	case "url":
+		reason = "must be a valid URL"
+	case "oneof":
+		reason = "must be one of: " + r.param
+	case "eqfield":
+		reason = "must be equal to " + r.param
+	case "nefield":
+		reason = "must not be equal to " + r.param
+	case "gtfield":
+		reason = "must be greater than " + r.param
+	case "gtefield":
+		reason = "must be greater than or equal to " + r.param
+	case "ltfield":
+		reason = "must be less than " + r.param
+	case "ltefield":
+		reason = "must be less than or equal to " + r.param
+	default:
+		reason = "failed " + r.name + " validation"
+	}
+	return ValidationError{Field: path, Rule: r.name, Param: r.param, Reason: reason}
+}
+
+func indirectValue(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+func isZeroValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Map:
+		return val.Len() == 0
+	case reflect.Invalid:
+		return true
+	}
+	return val.IsZero()
+}
+
+var builtinValidationRules = map[string]ValidationRule{
+	"min": func(f ValidationField) bool {
+		c, ok := compareToParam(f.Value, f.Param)
+		return ok && c >= 0
+	},
+	"max": func(f ValidationField) bool {
+		c, ok := compareToParam(f.Value, f.Param)
+		return ok && c <= 0
+	},
+	"len": func(f ValidationField) bool {
+		c, ok := compareToParam(f.Value, f.Param)
+		return ok && c == 0
+	},
+	"email": func(f ValidationField) bool {
+		if f.Value.Kind() != reflect.String {
+			return false
+		}
+		addr, err := mail.ParseAddress(f.Value.String())
+		return err == nil && addr.Address == f.Value.String()
+	},
+	"url": func(f ValidationField) bool {
+		if f.Value.Kind() != reflect.String {
+			return false
+		}
+		u, err := url.Parse(f.Value.String())
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"oneof": func(f ValidationField) bool {
+		value := fmt.Sprint(f.Value.Interface())
+		for _, option := range strings.Fields(f.Param) {
+			if value == option {
+				return true
+			}
+		}
+		return false
+	},
+	"eqfield": func(f ValidationField) bool {
+		other := otherField(f)
+		return other.IsValid() && reflect.DeepEqual(f.Value.Interface(), other.Interface())
+	},
+	"nefield": func(f ValidationField) bool {
+		other := otherField(f)
+		return !other.IsValid() || !reflect.DeepEqual(f.Value.Interface(), other.Interface())
+	},
+	"gtfield": func(f ValidationField) bool {
+		c, ok := compareValues(f.Value, otherField(f))
+		return ok && c > 0
+	},
+	"gtefield": func(f ValidationField) bool {
+		c, ok := compareValues(f.Value, otherField(f))
+		return ok && c >= 0
+	},
+	"ltfield": func(f ValidationField) bool {
+		c, ok := compareValues(f.Value, otherField(f))
+		return ok && c < 0
+	},
+	"ltefield": func(f ValidationField) bool {
+		c, ok := compareValues(f.Value, otherField(f))
+		return ok && c <= 0
+	},
+}
+
+func otherField(f ValidationField) reflect.Value {
+	if !f.Parent.IsValid() || f.Parent.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	sf, ok := f.Parent.Type().FieldByName(f.Param)
+	if !ok || !sf.IsExported() {
+		return reflect.Value{}
+	}
+	return indirectValue(fieldByIndex(f.Parent, sf.Index))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// compareToParam compares numbers by value and strings, slices, maps and arrays by length to the rule parameter.
+// Returns false when value can not be compared or parameter is invalid for the value.
+func compareToParam(val reflect.Value, param string) (int, bool) {
+	p, err := parseCompareParam(val.Type(), param)
+	if err != nil {
+		return 0, false
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return compareNumbers(float64(utf8.RuneCountInString(val.String())), p), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return compareNumbers(float64(val.Len()), p), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareNumbers(float64(val.Int()), p), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareNumbers(float64(val.Uint()), p), true
+	case reflect.Float32, reflect.Float64:
+		return compareNumbers(val.Float(), p), true
+	}
+	return 0, false
+}
+
+// parseCompareParam parses parameter of `min`, `max` and `len` rules for the value type. Lengths are integers,
+// time.Duration values are compared to durations (`min=1s`) and other numbers to numbers.
+func parseCompareParam(typ reflect.Type, param string) (float64, error) {
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.Atoi(param)
+		return float64(n), err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if typ == durationType {
+			d, err := time.ParseDuration(param)
+			return float64(d), err
+		}
+		return strconv.ParseFloat(param, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(param, 64)
+	}
+	return 0, nil
+}
+
+// checkCompareParam checks parameter of `min`, `max` and `len` rules for the field type.
+func checkCompareParam(_ reflect.Type, typ reflect.Type, param string) error {
+	if typ == nil {
+		return nil // type of elements is not known, i.e. `dive` on interface field
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	_, err := parseCompareParam(typ, param)
+	return err
+}
+
+// checkFieldParam checks that parameter of cross-field rules (`eqfield` etc.) names exported field of the struct.
+func checkFieldParam(parent reflect.Type, _ reflect.Type, param string) error {
+	sf, ok := parent.FieldByName(param)
+	if !ok {
+		return fmt.Errorf("field %s does not exist", param)
+	}
+	if !sf.IsExported() {
+		return fmt.Errorf("field %s is not exported", param)
+	}
+	return nil
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareValues compares numbers, strings and time.Time values of the same kind.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, false
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			return ta.Compare(tb), true
+		}
+		return 0, false
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch b.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return compareNumbers(float64(a.Int()), float64(b.Int())), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch b.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return compareNumbers(float64(a.Uint()), float64(b.Uint())), true
+		}
+	case reflect.Float32, reflect.Float64:
+		switch b.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return compareNumbers(a.Float(), b.Float()), true
+		}
+	case reflect.String:
+		if b.Kind() == reflect.String {
+			return strings.Compare(a.String(), b.String()), true
+		}
+	}
+	return 0, false
+}
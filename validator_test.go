@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2015 LabStack LLC and Echo contributors
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=5"`
+}
+
+type validatedItem struct {
+	SKU string `json:"sku" validate:"required"`
+	Qty int    `json:"qty" validate:"min=1,max=100"`
+}
+
+type validatedUser struct {
+	Name     string             `json:"name" validate:"required,max=8"`
+	Email    string             `json:"email,omitempty" validate:"required,email"`
+	Role     string             `json:"role" validate:"omitempty,oneof=admin user"`
+	Website  string             `json:"website" validate:"omitempty,url"`
+	Password string             `json:"password" validate:"min=8"`
+	Confirm  string             `json:"confirm" validate:"eqfield=Password"`
+	Age      *int               `json:"age" validate:"omitempty,min=18"`
+	Tags     []string           `json:"tags" validate:"max=3,dive,required,max=5"`
+	Address  *validatedAddress  `json:"address" validate:"required"`
+	Billing  validatedAddress   `json:"billing" validate:"omitempty"`
+	Items    []validatedItem    `json:"items"`
+	Labels   map[string]string  `json:"labels" validate:"dive,min=2"`
+	Ignored  string             `json:"ignored" validate:"-"`
+	NoJSON   int                `validate:"max=1"`
+	Start    time.Time          `json:"start"`
+	End      time.Time          `json:"end" validate:"gtfield=Start"`
+	Extra    map[string]*string `json:"extra"`
+}
+
+func validUser() validatedUser {
+	age := 20
+	return validatedUser{
+		Name:     "jon",
+		Email:    "jon@example.com",
+		Role:     "admin",
+		Website:  "https://example.com",
+		Password: "secret123",
+		Confirm:  "secret123",
+		Age:      &age,
+		Tags:     []string{"a", "b"},
+		Address:  &validatedAddress{City: "Tallinn", Zip: "10111"},
+		Items:    []validatedItem{{SKU: "x", Qty: 1}},
+		Labels:   map[string]string{"env": "prod"},
+		Start:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDefaultValidator_Validate(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		givenUser    func(u *validatedUser)
+		expectErrors []ValidationError
+	}{
+		{
+			name:      "ok, valid",
+			givenUser: func(u *validatedUser) {},
+		},
+		{
+			name: "ok, omitempty skips empty values and nested structs",
+			givenUser: func(u *validatedUser) {
+				u.Role = ""
+				u.Website = ""
+				u.Age = nil
+				u.Billing = validatedAddress{}
+			},
+		},
+		{
+			name: "nok, field rules",
+			givenUser: func(u *validatedUser) {
+				u.Name = "jonathan snow"
+				u.Email = "not an email"
+				u.Role = "root"
+				u.Website = "example.com"
+				u.Confirm = "other"
+				age := 17
+				u.Age = &age
+				u.NoJSON = 2
+			},
+			expectErrors: []ValidationError{
+				{Field: "name", Rule: "max", Param: "8", Reason: "must be at most 8"},
+				{Field: "email", Rule: "email", Reason: "must be a valid email address"},
+				{Field: "role", Rule: "oneof", Param: "admin user", Reason: "must be one of: admin user"},
+				{Field: "website", Rule: "url", Reason: "must be a valid URL"},
+				{Field: "confirm", Rule: "eqfield", Param: "Password", Reason: "must be equal to Password"},
+				{Field: "age", Rule: "min", Param: "18", Reason: "must be at least 18"},
+				{Field: "NoJSON", Rule: "max", Param: "1", Reason: "must be at most 1"},
+			},
+		},
+		{
+			name: "nok, required",
+			givenUser: func(u *validatedUser) {
+				u.Name = ""
+				u.Email = ""
+				u.Address = nil
+			},
+			expectErrors: []ValidationError{
+				{Field: "name", Rule: "required", Reason: "is required"},
+				{Field: "email", Rule: "required", Reason: "is required"},
+				{Field: "address", Rule: "required", Reason: "is required"},
+			},
+		},
+		{
+			name: "nok, nested structs, dive and cross-field time comparison",
+			givenUser: func(u *validatedUser) {
+				u.Tags = []string{"a", "", "toolong"}
+				u.Address.City = ""
+				u.Billing = validatedAddress{City: "x", Zip: "1"}
+				u.Items = append(u.Items, validatedItem{Qty: 101})
+				u.Labels = map[string]string{"b": "x", "a": "y"}
+				u.End = u.Start
+			},
+			expectErrors: []ValidationError{
+				{Field: "tags[1]", Rule: "required", Reason: "is required"},
+				{Field: "tags[2]", Rule: "max", Param: "5", Reason: "must be at most 5"},
+				{Field: "address.city", Rule: "required", Reason: "is required"},
+				{Field: "billing.zip", Rule: "len", Param: "5", Reason: "must have length 5"},
+				{Field: "items[1].sku", Rule: "required", Reason: "is required"},
+				{Field: "items[1].qty", Rule: "max", Param: "100", Reason: "must be at most 100"},
+				{Field: "labels[a]", Rule: "min", Param: "2", Reason: "must be at least 2"},
+				{Field: "labels[b]", Rule: "min", Param: "2", Reason: "must be at least 2"},
+				{Field: "end", Rule: "gtfield", Param: "Start", Reason: "must be greater than Start"},
+			},
+		},
+		{
+			name: "nok, slice length before dive",
+			givenUser: func(u *validatedUser) {
+				u.Tags = []string{"a", "b", "c", "d"}
+			},
+			expectErrors: []ValidationError{
+				{Field: "tags", Rule: "max", Param: "3", Reason: "must be at most 3"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := validUser()
+			tc.givenUser(&u)
+
+			err := NewValidator().Validate(&u)
+
+			if tc.expectErrors == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var ve *ValidationErrors
+			if assert.True(t, errors.As(err, &ve)) {
+				assert.Equal(t, http.StatusBadRequest, ve.Code)
+				assert.Equal(t, tc.expectErrors, ve.Errors)
+			}
+		})
+	}
+}
+
+func TestDefaultValidator_RegisterRule(t *testing.T) {
+	v := NewValidator()
+	v.RegisterRule("even", func(f ValidationField) bool {
+		return f.Value.Int()%2 == 0
+	})
+	v.RegisterRule("prefix", func(f ValidationField) bool {
+		return strings.HasPrefix(f.Value.String(), f.Param)
+	})
+
+	type request struct {
+		Count int    `json:"count" validate:"even"`
+		Code  string `json:"code" validate:"prefix=EC-"`
+	}
+
+	assert.NoError(t, v.Validate(request{Count: 2, Code: "EC-1"}))
+
+	err := v.Validate(&request{Count: 3, Code: "X-1"})
+	var ve *ValidationErrors
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, []ValidationError{
+			{Field: "count", Rule: "even", Reason: "failed even validation"},
+			{Field: "code", Rule: "prefix", Param: "EC-", Reason: "failed prefix validation"},
+		}, ve.Errors)
+	}
+}
+
+func TestDefaultValidator_UnknownRule(t *testing.T) {
+	type request struct {
+		Name string `validate:"required,unknown"`
+	}
+	err := NewValidator().Validate(&request{})
+	assert.EqualError(t, err, `echo: unknown validation rule "unknown" for field request.Name`)
+}
+
+func TestDefaultValidator_InvalidRuleParam(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		whenValue   interface{}
+		expectError string
+	}{
+		{
+			name: "nok, string length",
+			whenValue: &struct {
+				Name string `validate:"min=abc"`
+			}{Name: "x"},
+			expectError: `echo: invalid parameter of validation rule "min" for field .Name: strconv.Atoi: parsing "abc": invalid syntax`,
+		},
+		{
+			name: "nok, number",
+			whenValue: &struct {
+				Age *int `validate:"omitempty,max=1x"`
+			}{},
+			expectError: `echo: invalid parameter of validation rule "max" for field .Age: strconv.ParseFloat: parsing "1x": invalid syntax`,
+		},
+		{
+			name: "nok, duration",
+			whenValue: &struct {
+				Timeout time.Duration `validate:"min=10"`
+			}{},
+			expectError: `echo: invalid parameter of validation rule "min" for field .Timeout: time: missing unit in duration "10"`,
+		},
+		{
+			name: "nok, dive element",
+			whenValue: &struct {
+				Tags []string `validate:"dive,len=x"`
+			}{},
+			expectError: `echo: invalid parameter of validation rule "len" for field .Tags: strconv.Atoi: parsing "x": invalid syntax`,
+		},
+		{
+			name: "nok, cross-field rule with missing field",
+			whenValue: &struct {
+				Confirm string `validate:"eqfield=Passwrd"`
+			}{},
+			expectError: `echo: invalid parameter of validation rule "eqfield" for field .Confirm: field Passwrd does not exist`,
+		},
+		{
+			name: "nok, cross-field rule with unexported field",
+			whenValue: &struct {
+				password string
+				Confirm  string `validate:"eqfield=password"`
+			}{password: "x", Confirm: "x"},
+			expectError: `echo: invalid parameter of validation rule "eqfield" for field .Confirm: field password is not exported`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			assert.NotPanics(t, func() {
+				err = NewValidator().Validate(tc.whenValue)
+			})
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestDefaultValidator_InvalidRuleParamInterfaceField(t *testing.T) {
+	type request struct {
+		Value interface{} `validate:"min=abc"`
+	}
+	err := NewValidator().Validate(&request{Value: "x"})
+
+	var ve *ValidationErrors
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, "Value", ve.Errors[0].Field)
+	}
+}
+
+func TestDefaultValidator_EmbeddedStruct(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `json:"created_by" validate:"required"`
+		Note      string `json:"note" validate:"max=3"`
+	}
+	type base struct {
+		ID int `json:"id" validate:"min=1"`
+	}
+	type request struct {
+		base
+		*Audit
+		Note  string `json:"note" validate:"required"`
+		Named Audit  `json:"named"`
+		Other *Audit `json:"other,omitempty"`
+		Start int    `json:"start"`
+		End   int    `json:"end" validate:"gtfield=ID"`
+	}
+
+	err := NewValidator().Validate(&request{base: base{ID: 5}, Audit: &Audit{Note: "too long"}, Named: Audit{CreatedBy: "a"}, End: 1})
+
+	var ve *ValidationErrors
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, []ValidationError{
+			{Field: "created_by", Rule: "required", Reason: "is required"},
+			{Field: "note", Rule: "required", Reason: "is required"},
+			{Field: "end", Rule: "gtfield", Param: "ID", Reason: "must be greater than ID"},
+		}, ve.Errors)
+	}
+
+	// fields of nil embedded pointer are validated as zero values
+	err = NewValidator().Validate(&request{base: base{ID: 1}, Note: "n", Named: Audit{CreatedBy: "a"}, End: 2})
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, []ValidationError{
+			{Field: "created_by", Rule: "required", Reason: "is required"},
+		}, ve.Errors)
+	}
+}
+
+func TestDefaultValidator_NotStruct(t *testing.T) {
+	v := NewValidator()
+	assert.NoError(t, v.Validate(nil))
+	assert.NoError(t, v.Validate("string"))
+	assert.NoError(t, v.Validate((*validatedUser)(nil)))
+}
+
+func TestDefaultValidator_ErrorHandler(t *testing.T) {
+	e := New()
+	e.Validator = NewValidator()
+	e.POST("/", func(c Context) error {
+		var req validatedItem
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		return c.Validate(&req)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"qty":0}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, `{"errors":[{"field":"sku","rule":"required","reason":"is required"},{"field":"qty","rule":"min","param":"1","reason":"must be at least 1"}],"message":"validation failed"}`+"\n", rec.Body.String())
+}